@@ -6,7 +6,6 @@ package completions
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
@@ -111,56 +110,34 @@ func AliasNames(cmd *cobra.Command, args []string, toComplete string) ([]string,
 // resource type passed as the first positional arg. Falls back to scanning
 // every resource directory when no resource is known yet.
 func TemplateNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-	rootPath, err := utils.ResolveTemplatePath(cmd)
-	if err != nil {
-		return nil, cobra.ShellCompDirectiveNoFileComp
-	}
 	resource := ""
 	if len(args) >= 1 {
 		resource = strings.ToLower(utils.ResolveAliasString(args[0]))
 	}
 
-	seen := map[string]struct{}{}
-	var out []string
-
-	entries, err := os.ReadDir(rootPath)
+	files, err := utils.ResolveTemplateSearchPath(cmd, nil).Glob("*")
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
-	for _, e := range entries {
-		if !e.IsDir() {
-			continue
-		}
-		if e.Name() == "_shared" {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, f := range files {
+		if f.Dir == "_shared" {
 			continue
 		}
-		if resource != "" && !strings.HasPrefix(strings.ToLower(e.Name()), resource+"-") &&
-			!strings.HasPrefix(strings.ToLower(e.Name()), resource+"s-") &&
-			strings.ToLower(e.Name()) != resource+"--v1" {
+		dir := strings.ToLower(f.Dir)
+		if resource != "" && !strings.HasPrefix(dir, resource+"-") &&
+			!strings.HasPrefix(dir, resource+"s-") &&
+			dir != resource+"--v1" {
 			// Loose match — resource dirs are `<plural>-<group>-<version>`, so we
 			// accept anything that starts with the resource singular or plural.
 			continue
 		}
-		files, err := os.ReadDir(filepath.Join(rootPath, e.Name()))
-		if err != nil {
+		if _, ok := seen[f.Name]; ok {
 			continue
 		}
-		for _, f := range files {
-			name := f.Name()
-			switch {
-			case strings.HasSuffix(name, ".yaml"):
-				name = strings.TrimSuffix(name, ".yaml")
-			case strings.HasSuffix(name, ".tpl"):
-				name = strings.TrimSuffix(name, ".tpl")
-			default:
-				continue
-			}
-			if _, ok := seen[name]; ok {
-				continue
-			}
-			seen[name] = struct{}{}
-			out = append(out, name)
-		}
+		seen[f.Name] = struct{}{}
+		out = append(out, f.Name)
 	}
 	sort.Strings(out)
 	return filterPrefix(out, toComplete), cobra.ShellCompDirectiveNoFileComp
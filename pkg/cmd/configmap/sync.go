@@ -107,9 +107,13 @@ Use --force to always overwrite regardless of priority.`,
 				if err := os.MkdirAll(localDir, 0755); err != nil {
 					return fmt.Errorf("failed to create directory %s: %w", localDir, err)
 				}
-				if err := os.WriteFile(localPath, []byte(value), 0644); err != nil {
+				historyErr, err := utils.WriteTemplateVersioned(absPath, resourceDir, templateName, ".yaml", []byte(value), "sync")
+				if err != nil {
 					return fmt.Errorf("failed to write %s: %w", localPath, err)
 				}
+				if historyErr != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "WARN %s: failed to keep its history: %v\n", localPath, historyErr)
+				}
 				synced++
 			}
 
@@ -135,12 +139,11 @@ func shouldOverwrite(sources []string, localExists bool, force bool) bool {
 		return true
 	}
 	for _, s := range sources {
-		switch s {
-		case "configmap":
-			return true
-		case "local":
-			return false
+		local, _, err := utils.ParseTemplateSource(s)
+		if err != nil {
+			continue
 		}
+		return !local
 	}
 	return false
 }
@@ -0,0 +1,110 @@
+package get
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// defaultErrorPlaceholder is rendered in place of a cell whose template
+	// or JSONPath evaluation failed (non-strict mode).
+	defaultErrorPlaceholder = "<err>"
+	// timeoutPlaceholder is rendered for a cell that exceeded --cell-timeout
+	// or ran past --render-timeout.
+	timeoutPlaceholder = "<timeout>"
+	// maxCellErrorExamples caps how many offending objects are listed per
+	// distinct error in the stderr summary.
+	maxCellErrorExamples = 3
+)
+
+// CellErrors accumulates per-cell evaluation failures so that a single bad
+// object (e.g. `index` out of range on a pending pod) doesn't abort the whole
+// listing. Errors are grouped by column and message; the first few offending
+// objects are kept as examples for the summary.
+type CellErrors struct {
+	order   []string
+	entries map[string]*cellErrorEntry
+	total   int
+}
+
+type cellErrorEntry struct {
+	Column   string
+	Message  string
+	Count    int
+	Examples []string
+}
+
+// Record notes that the given column failed to render for obj.
+func (c *CellErrors) Record(column string, obj runtime.Object, err error) {
+	if c.entries == nil {
+		c.entries = make(map[string]*cellErrorEntry)
+	}
+	key := column + "\x00" + err.Error()
+	e, ok := c.entries[key]
+	if !ok {
+		e = &cellErrorEntry{Column: column, Message: err.Error()}
+		c.entries[key] = e
+		c.order = append(c.order, key)
+	}
+	e.Count++
+	if len(e.Examples) < maxCellErrorExamples {
+		e.Examples = append(e.Examples, objectRef(obj))
+	}
+	c.total++
+}
+
+// Len returns the total number of failed cells recorded.
+func (c *CellErrors) Len() int {
+	if c == nil {
+		return 0
+	}
+	return c.total
+}
+
+// WriteSummary prints one entry per distinct error, in first-seen order,
+// with a handful of example objects. Nothing is written when no errors were
+// recorded.
+func (c *CellErrors) WriteSummary(w io.Writer) {
+	if c.Len() == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%d cell(s) failed to render (%d distinct error(s)):\n", c.total, len(c.order))
+	for _, key := range c.order {
+		e := c.entries[key]
+		fmt.Fprintf(w, "  [%dx] column %q: %s\n", e.Count, e.Column, e.Message)
+		examples := strings.Join(e.Examples, ", ")
+		if e.Count > len(e.Examples) {
+			examples += ", ..."
+		}
+		fmt.Fprintf(w, "        e.g. %s\n", examples)
+	}
+}
+
+// objectRef renders a short "namespace/name" (or "name") identity for obj.
+// Watch events are unwrapped to the object they carry.
+func objectRef(obj runtime.Object) string {
+	acc, err := meta.Accessor(unwrapEvent(obj))
+	if err != nil || acc.GetName() == "" {
+		return "<unknown>"
+	}
+	if ns := acc.GetNamespace(); ns != "" {
+		return ns + "/" + acc.GetName()
+	}
+	return acc.GetName()
+}
+
+// unwrapEvent returns the object carried by a rendered watch event (see
+// printOneObject), or obj itself.
+func unwrapEvent(obj runtime.Object) runtime.Object {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		if inner, ok := u.Object["object"].(map[string]interface{}); ok && u.GetName() == "" {
+			return &unstructured.Unstructured{Object: inner}
+		}
+	}
+	return obj
+}
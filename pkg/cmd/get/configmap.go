@@ -0,0 +1,48 @@
+package get
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+// configMapTimeout bounds how long get waits for a template ConfigMap
+// before falling back to its cache.
+const configMapTimeout = 5 * time.Second
+
+// configMapLayer returns the layer of the templates in ConfigMap src,
+// refreshing its on-disk cache if the ConfigMap changed. Errors are
+// warnings: a stale cache, or no layer at all, is better than no output.
+func (o *GetOptions) configMapLayer(ctx context.Context, src utils.ConfigMapSource) (utils.TemplateLayer, bool) {
+	restConfig, err := o.factory.ToRESTConfig()
+	if err != nil {
+		fmt.Fprintf(o.ErrOut, "Warning: skipping templates in ConfigMap %s: %v\n", src, err)
+		return utils.TemplateLayer{}, false
+	}
+	dir, err := utils.ConfigMapCacheDir(restConfig.Host, src)
+	if err != nil {
+		fmt.Fprintf(o.ErrOut, "Warning: skipping templates in ConfigMap %s: %v\n", src, err)
+		return utils.TemplateLayer{}, false
+	}
+	reader, err := utils.NewConfigMapReader(restConfig)
+	if err != nil {
+		fmt.Fprintf(o.ErrOut, "Warning: skipping templates in ConfigMap %s: %v\n", src, err)
+		return utils.TemplateLayer{}, false
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, configMapTimeout)
+	defer cancel()
+	layer, ok, err := utils.CachedConfigMapLayer(ctx, reader, dir, src)
+	if err != nil {
+		if ok {
+			fmt.Fprintf(o.ErrOut, "Warning: %v; using the cached templates\n", err)
+		} else {
+			fmt.Fprintf(o.ErrOut, "Warning: skipping templates: %v\n", err)
+		}
+	}
+	return layer, ok
+}
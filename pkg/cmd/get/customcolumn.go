@@ -6,18 +6,20 @@ package get
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/liggitt/tabwriter"
-	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -31,6 +33,7 @@ import (
 	"github.com/kubectl-cwide/pkg/common"
 	"github.com/kubectl-cwide/pkg/models"
 	"github.com/kubectl-cwide/pkg/parser"
+	"github.com/kubectl-cwide/pkg/parser/funcs"
 	"github.com/kubectl-cwide/pkg/utils"
 )
 
@@ -71,26 +74,46 @@ func RelaxedJSONPathExpression(pathExpression string) (string, error) {
 //	NAME               API_VERSION
 //	foo                bar
 func NewCustomColumnsPrinterFromSpec(spec string, decoder runtime.Decoder, noHeaders bool) (*CustomColumnsPrinter, error) {
+	specColumns, err := ParseCustomColumnsSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]Column, len(specColumns))
+	headers := make([]string, len(specColumns))
+	for ix, c := range specColumns {
+		spec, err := RelaxedJSONPathExpression(c.FieldSpec)
+		if err != nil {
+			return nil, err
+		}
+		columns[ix] = Column{Header: c.Header, FieldSpec: spec}
+		headers[ix] = c.Header
+	}
+
+	generator := utils.NewTableGenerator().With(printersinternal.AddHandlers)
+
+	return &CustomColumnsPrinter{Columns: columns, Decoder: decoder, NoHeaders: noHeaders, DefaultTableGenerator: generator, Headers: headers, scope: parser.NewScope(context.Background())}, nil
+}
+
+// ParseCustomColumnsSpec parses a kubectl custom-columns spec, a comma
+// separated list of <header>:<jsonpath-field-spec> pairs, into columns. The
+// field specs are checked but kept as written.
+func ParseCustomColumnsSpec(spec string) ([]models.YAMLColumn, error) {
 	if len(spec) == 0 {
 		return nil, fmt.Errorf("custom-columns format specified but no custom columns given")
 	}
 	parts := strings.Split(spec, ",")
-	columns := make([]Column, len(parts))
+	columns := make([]models.YAMLColumn, len(parts))
 	for ix := range parts {
 		colSpec := strings.SplitN(parts[ix], ":", 2)
 		if len(colSpec) != 2 {
 			return nil, fmt.Errorf("unexpected custom-columns spec: %s, expected <header>:<json-path-expr>", parts[ix])
 		}
-		spec, err := RelaxedJSONPathExpression(colSpec[1])
-		if err != nil {
+		if _, err := RelaxedJSONPathExpression(colSpec[1]); err != nil {
 			return nil, err
 		}
-		columns[ix] = Column{Header: colSpec[0], FieldSpec: spec}
+		columns[ix] = models.YAMLColumn{Header: colSpec[0], FieldSpec: colSpec[1]}
 	}
-
-	generator := utils.NewTableGenerator().With(printersinternal.AddHandlers)
-
-	return &CustomColumnsPrinter{Columns: columns, Decoder: decoder, NoHeaders: noHeaders, DefaultTableGenerator: generator}, nil
+	return columns, nil
 }
 
 // NewCustomColumnsPrinterFromTemplate creates a custom columns printer from a template stream.  The template is expected
@@ -103,13 +126,13 @@ func NewCustomColumnsPrinterFromTemplate(templateReader io.Reader, decoder runti
 	if !scanner.Scan() {
 		return nil, fmt.Errorf("invalid template, missing header line. Expected format is one line of space separated headers, one line of space separated column specs.")
 	}
-	headers := splitIgnoringTemplateSpaces(scanner.Text())
+	headers := SplitIgnoringTemplateSpaces(scanner.Text())
 
 	if !scanner.Scan() {
 		return nil, fmt.Errorf("invalid template, missing spec line. Expected format is one line of space separated headers, one line of space separated column specs.")
 	}
 
-	specs := splitIgnoringTemplateSpaces(scanner.Text())
+	specs := SplitIgnoringTemplateSpaces(scanner.Text())
 
 	if len(headers) != len(specs) {
 		return nil, fmt.Errorf("number of headers (%d) and field specifications (%d) don't match", len(headers), len(specs))
@@ -119,8 +142,9 @@ func NewCustomColumnsPrinterFromTemplate(templateReader io.Reader, decoder runti
 	for scanner.Scan() {
 		templateText += scanner.Text() + "\n"
 	}
+	scope := parser.NewScope(context.Background())
 	localTemplate := template.New("local")
-	localTemplate.Funcs(parser.GetFuncMap(restConfig))
+	localTemplate.Funcs(parser.GetFuncMap(scope, restConfig))
 
 	_, err := localTemplate.Parse(templateText)
 	if err != nil {
@@ -150,22 +174,39 @@ func NewCustomColumnsPrinterFromTemplate(templateReader io.Reader, decoder runti
 
 	generator := utils.NewTableGenerator().With(printersinternal.AddHandlers)
 
-	return &CustomColumnsPrinter{Columns: columns, Decoder: decoder, NoHeaders: false, Config: restConfig, localTemplate: localTemplate, DefaultTableGenerator: generator, Headers: headers}, nil
+	return &CustomColumnsPrinter{Columns: columns, Decoder: decoder, NoHeaders: false, Config: restConfig, localTemplate: localTemplate, DefaultTableGenerator: generator, Headers: headers, scope: scope}, nil
 }
 
 // NewCustomColumnsPrinterFromYAML creates a custom columns printer from a YAML template.
 func NewCustomColumnsPrinterFromYAML(data []byte, decoder runtime.Decoder, restConfig *rest.Config) (*CustomColumnsPrinter, error) {
-	var tmpl models.YAMLTemplate
-	if err := yaml.Unmarshal(data, &tmpl); err != nil {
+	return NewCustomColumnsPrinterFromYAMLFile("", data, "", decoder, restConfig)
+}
+
+// NewCustomColumnsPrinterFromYAMLFile creates a custom columns printer from
+// the YAML template read from path. Errors in its templates, when they are
+// parsed and when cells are rendered, are reported at their path:line:col.
+// sharedHelpers are the helpers of a template without its own.
+func NewCustomColumnsPrinterFromYAMLFile(path string, data []byte, sharedHelpers string, decoder runtime.Decoder, restConfig *rest.Config) (*CustomColumnsPrinter, error) {
+	tmpl, pos, err := models.ParseYAMLTemplate(path, data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse YAML template: %v", err)
 	}
+	sources := models.TemplateSources{"local": pos.Helpers}
+	for name, p := range pos.Funcs {
+		sources["__func_"+name] = p
+	}
+	if tmpl.Helpers == "" {
+		tmpl.Helpers = sharedHelpers
+		delete(sources, "local")
+	}
 
 	if len(tmpl.Columns) == 0 {
 		return nil, fmt.Errorf("YAML template must define at least one column")
 	}
 
+	scope := parser.NewScope(context.Background())
 	localTemplate := template.New("local")
-	localTemplate.Funcs(parser.GetFuncMap(restConfig))
+	localTemplate.Funcs(parser.GetFuncMap(scope, restConfig))
 
 	// Register user-defined custom funcs (two-pass approach).
 	// Pass 1: register stub functions so the parser recognizes their names.
@@ -179,14 +220,14 @@ func NewCustomColumnsPrinterFromYAML(data []byte, decoder runtime.Decoder, restC
 
 	if tmpl.Helpers != "" {
 		if _, err := localTemplate.Parse(tmpl.Helpers); err != nil {
-			return nil, fmt.Errorf("failed to parse helpers template: %v", err)
+			return nil, sources.Wrap(err, "failed to parse helpers template")
 		}
 	}
 
 	// Parse each custom func body as a named sub-template.
 	for name, body := range tmpl.Funcs {
 		if _, err := localTemplate.New("__func_" + name).Parse(body); err != nil {
-			return nil, fmt.Errorf("custom func %q: %v", name, err)
+			return nil, sources.Wrap(err, fmt.Sprintf("custom func %q", name))
 		}
 	}
 
@@ -213,40 +254,75 @@ func NewCustomColumnsPrinterFromYAML(data []byte, decoder runtime.Decoder, restC
 	columns := make([]Column, len(tmpl.Columns))
 	headers := make([]string, len(tmpl.Columns))
 	for ix, col := range tmpl.Columns {
+		var colPos models.YAMLColumnPositions
+		if ix < len(pos.Columns) {
+			colPos = pos.Columns[ix]
+		}
+		// columnError reports err at the column's entry in the file.
+		columnError := func(err error) error {
+			if !colPos.Item.IsKnown() {
+				return err
+			}
+			return &models.SourceError{Pos: colPos.Item, Msg: err.Error(), Err: err}
+		}
 		if col.Header == "" {
-			return nil, fmt.Errorf("column %d is missing a header", ix)
+			return nil, columnError(fmt.Errorf("column %d is missing a header", ix))
 		}
 		headers[ix] = col.Header
 
 		var spec string
+		var source models.ScalarPos
 		isTemplate := false
 		if col.Template != "" {
 			spec = col.Template
+			source = colPos.Template
 			isTemplate = true
+			// Parse now, so that a broken template fails with its position
+			// before anything is fetched.
+			name := fmt.Sprintf("column%d", ix)
+			if _, err := localTemplate.New(name).Parse(spec); err != nil {
+				return nil, models.TemplateSources{name: source}.Wrap(err, fmt.Sprintf("column %q", col.Header))
+			}
 		} else if col.FieldSpec != "" {
+			source = colPos.FieldSpec
 			if col.FieldSpec == common.DefaultPrinterField {
 				spec = fmt.Sprintf("{.%s}", common.DefaultPrinterField)
 			} else {
 				var err error
 				spec, err = RelaxedJSONPathExpression(col.FieldSpec)
 				if err != nil {
-					return nil, fmt.Errorf("column %q: %v", col.Header, err)
+					return nil, columnError(fmt.Errorf("column %q: %v", col.Header, err))
 				}
 			}
 		} else {
-			return nil, fmt.Errorf("column %q must have either fieldSpec or template", col.Header)
+			return nil, columnError(fmt.Errorf("column %q must have either fieldSpec or template", col.Header))
+		}
+		if err := parser.ValidateFormat(col.Format); err != nil {
+			return nil, columnError(fmt.Errorf("column %q: %v", col.Header, err))
+		}
+		list := parser.ListOptions{Default: col.Default, Separator: col.Separator, MaxItems: col.MaxItems, Unique: col.Unique}
+		if err := list.Validate(); err != nil {
+			return nil, columnError(fmt.Errorf("column %q: %v", col.Header, err))
+		}
+		if err := parser.ValidateType(col.Type); err != nil {
+			return nil, columnError(fmt.Errorf("column %q: %v", col.Header, err))
 		}
 
 		columns[ix] = Column{
 			Header:     col.Header,
 			FieldSpec:  spec,
 			IsTemplate: isTemplate,
+			Format:     col.Format,
+			List:       list,
+			Type:       col.Type,
+			Volatile:   col.Volatile,
+			Source:     source,
 		}
 	}
 
 	generator := utils.NewTableGenerator().With(printersinternal.AddHandlers)
 
-	return &CustomColumnsPrinter{Columns: columns, Decoder: decoder, NoHeaders: false, Config: restConfig, localTemplate: localTemplate, DefaultTableGenerator: generator, Headers: headers}, nil
+	return &CustomColumnsPrinter{Columns: columns, Decoder: decoder, NoHeaders: false, Config: restConfig, localTemplate: localTemplate, DefaultTableGenerator: generator, Headers: headers, scope: scope, sources: sources}, nil
 }
 
 // Column represents a user specified column
@@ -259,6 +335,33 @@ type Column struct {
 	// IsTemplate marks this column's FieldSpec as a Go template expression,
 	// bypassing the IsTemplate() heuristic check.
 	IsTemplate bool
+	// Format renders the value as a timestamp or duration (see
+	// funcs.TimeFormats). Empty means raw, except that a column headed AGE
+	// defaults to "age".
+	Format string
+	// List controls the missing-value placeholder and how multiple values
+	// are joined.
+	List parser.ListOptions
+	// Type is the declared type of the column in structured output (see
+	// parser.ColumnTypes). Empty means inferred.
+	Type string
+	// Volatile marks a value that changes without the object changing;
+	// --timeline ignores it.
+	Volatile bool
+	// Source is where FieldSpec was read from in the template file, to
+	// report template errors at their position. Zero when unknown.
+	Source models.ScalarPos
+}
+
+// Row is one rendered object as passed to CustomColumnsPrinter.RowSink.
+type Row struct {
+	// Object is the object the row was rendered from.
+	Object runtime.Object
+	// Cells holds the rendered text of each column.
+	Cells []string
+	// Values holds the typed value behind each cell, for sorting, filtering
+	// and structured output.
+	Values []parser.CellValue
 }
 
 // CustomColumnPrinter is a printer that knows how to print arbitrary columns
@@ -275,9 +378,66 @@ type CustomColumnsPrinter struct {
 	*utils.DefaultTableGenerator
 	Headers     []string // - Headers is used to store the headers for the custom columns
 	CustomTable table.Writer
-	// RowSink, when non-nil, captures each row's column values instead of
-	// writing them to the tabwriter. Used by structured output formats.
-	RowSink func(cols []string)
+	// RowSink, when non-nil, captures each row instead of writing it to the
+	// tabwriter. Used by structured output formats, sort and filter.
+	RowSink func(row Row)
+	// ShowKind prefixes the NAME column with the object's kind, e.g.
+	// pod/web.
+	ShowKind bool
+	// TimeFormat, when set, overrides the format of every timestamp column
+	// (see funcs.IsTimestampFormat). Duration columns are unaffected.
+	TimeFormat string
+	// Strict restores fail-fast behaviour: the first cell that fails to
+	// render aborts printing. When false, the cell shows ErrorPlaceholder
+	// and the failure is recorded in Errors.
+	Strict bool
+	// ErrorPlaceholder is rendered for failed cells in non-strict mode.
+	// Empty means defaultErrorPlaceholder.
+	ErrorPlaceholder string
+	// Errors collects per-cell failures seen in non-strict mode.
+	Errors CellErrors
+	// CellTimeout bounds each template cell; a cell that exceeds it renders
+	// as "<timeout>". Zero means no per-cell limit.
+	CellTimeout time.Duration
+	// scope carries the context network-touching template functions run
+	// under; see SetContext.
+	scope *parser.Scope
+	// sources are the positions of the helpers and custom funcs in the
+	// template file, and cellSources those and the columns' of the current
+	// PrintObj, to report render errors at their position.
+	sources     models.TemplateSources
+	cellSources models.TemplateSources
+}
+
+// SetContext sets the context template functions run under, typically
+// cmd.Context() optionally bounded by --render-timeout.
+func (s *CustomColumnsPrinter) SetContext(ctx context.Context) {
+	if s.scope == nil {
+		s.scope = parser.NewScope(ctx)
+		return
+	}
+	s.scope.SetBase(ctx)
+}
+
+// SetClock sets the clock ages are computed from; see parser.Scope.SetClock.
+func (s *CustomColumnsPrinter) SetClock(now func() time.Time) {
+	if s.scope == nil {
+		s.scope = parser.NewScope(context.Background())
+	}
+	s.scope.SetClock(now)
+}
+
+// SetFuncs replaces template functions, e.g. lookup and probeCheck with
+// stubs in template tests. Only templates sharing the printer's template
+// set (.yaml and .tpl templates) see the replacements.
+func (s *CustomColumnsPrinter) SetFuncs(funcs template.FuncMap) {
+	if s.localTemplate != nil {
+		s.localTemplate.Funcs(funcs)
+	}
+	if s.scope == nil {
+		s.scope = parser.NewScope(context.Background())
+	}
+	s.scope.Override(funcs)
 }
 
 // SelectColumns filters the printer's Columns/Headers to the named subset,
@@ -316,6 +476,51 @@ func (s *CustomColumnsPrinter) SelectColumns(names []string) error {
 	return nil
 }
 
+// InsertNamespaceColumn prepends a NAMESPACE column, as kubectl does with
+// --all-namespaces, unless the template already has one.
+func (s *CustomColumnsPrinter) InsertNamespaceColumn() {
+	for _, c := range s.Columns {
+		if strings.EqualFold(c.Header, "NAMESPACE") {
+			return
+		}
+	}
+	col := Column{Header: "NAMESPACE", FieldSpec: "{.metadata.namespace}"}
+	s.Columns = append([]Column{col}, s.Columns...)
+	s.Headers = append([]string{col.Header}, s.Headers...)
+}
+
+// AddLabelColumns appends one column per label key, like kubectl's -L. The
+// header is the last path segment of the key in upper case; objects without
+// the label show an empty cell.
+func (s *CustomColumnsPrinter) AddLabelColumns(keys []string) {
+	for _, key := range keys {
+		header := strings.ToUpper(key[strings.LastIndex(key, "/")+1:])
+		s.addColumn(Column{
+			Header:     header,
+			FieldSpec:  fmt.Sprintf(`{{ dig "metadata" "labels" %s "" . }}`, strconv.Quote(key)),
+			IsTemplate: true,
+			Type:       parser.TypeString,
+		})
+	}
+}
+
+// AddLabelsColumn appends a LABELS column listing every label as
+// key=value, like kubectl's --show-labels.
+func (s *CustomColumnsPrinter) AddLabelsColumn() {
+	s.addColumn(Column{
+		Header: "LABELS",
+		FieldSpec: `{{ $l := dig "metadata" "labels" dict . }}{{ if not $l }}<none>{{ end }}` +
+			`{{ range $i, $k := keys $l | sortAlpha }}{{ if $i }},{{ end }}{{ $k }}={{ index $l $k }}{{ end }}`,
+		IsTemplate: true,
+		Type:       parser.TypeString,
+	})
+}
+
+func (s *CustomColumnsPrinter) addColumn(col Column) {
+	s.Columns = append(s.Columns, col)
+	s.Headers = append(s.Headers, col.Header)
+}
+
 func (s *CustomColumnsPrinter) WithCustomTable() *CustomColumnsPrinter {
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
@@ -336,8 +541,10 @@ func (s *CustomColumnsPrinter) PrintObj(obj runtime.Object, out io.Writer) error
 		return errors.New(printers.InternalObjectPrinterErr)
 	}
 
-	if _, found := out.(*tabwriter.Writer); !found {
-		w := printers.GetNewTabWriter(out)
+	switch out.(type) {
+	case *utils.TabWriter, *tabwriter.Writer:
+	default:
+		w := utils.NewTabWriter(out)
 		out = w
 		defer w.Flush()
 	}
@@ -355,12 +562,22 @@ func (s *CustomColumnsPrinter) PrintObj(obj runtime.Object, out io.Writer) error
 		}
 	}
 
+	s.cellSources = make(models.TemplateSources, len(s.sources)+len(s.Columns))
+	for name, src := range s.sources {
+		s.cellSources[name] = src
+	}
 	parsers := make([]parser.Parser, len(s.Columns))
 	for ix, col := range s.Columns {
+		s.cellSources[fmt.Sprintf("column%d", ix)] = col.Source
 		p := parser.NewFieldParser()
 		p.Header = col.Header
-		p.IsAGE = col.Header == "AGE"
+		p.Format = s.columnFormat(col)
+		p.List = col.List
+		p.Type = col.Type
 		p.IsDefaultPrinterField = col.FieldSpec == fmt.Sprintf("{.%s}", common.DefaultPrinterField)
+		p.Config = s.Config
+		p.Scope = s.scope
+		p.Timeout = s.CellTimeout
 
 		if col.IsTemplate || parser.IsTemplate(col.FieldSpec) {
 			var tParser *template.Template
@@ -368,7 +585,7 @@ func (s *CustomColumnsPrinter) PrintObj(obj runtime.Object, out io.Writer) error
 				tParser = s.localTemplate.New(fmt.Sprintf("column%d", ix)).Option("missingkey=zero")
 			} else {
 				tParser = template.New(fmt.Sprintf("column%d", ix)).Option("missingkey=zero")
-				tParser.Funcs(parser.GetFuncMap(s.Config))
+				tParser.Funcs(parser.GetFuncMap(s.scope, s.Config))
 			}
 
 			tParser, err := tParser.Parse(col.FieldSpec)
@@ -409,6 +626,7 @@ func (s *CustomColumnsPrinter) PrintObj(obj runtime.Object, out io.Writer) error
 
 func (s *CustomColumnsPrinter) printOneObject(obj runtime.Object, parsers []parser.Parser, out io.Writer) error {
 	columns := make([]string, len(parsers))
+	values := make([]parser.CellValue, len(parsers))
 	switch u := obj.(type) {
 	case *metav1.WatchEvent:
 		if printers.InternalObjectPreventer.IsForbidden(reflect.Indirect(reflect.ValueOf(u.Object.Object)).Type().PkgPath()) {
@@ -437,27 +655,40 @@ func (s *CustomColumnsPrinter) printOneObject(obj runtime.Object, parsers []pars
 	t, _ := s.GenerateTable(obj, k8sprinters.GenerateOptions{NoHeaders: s.NoHeaders, Wide: true})
 
 	for ix := range parsers {
-		parser := parsers[ix]
-
-		col, err := parser.Parse(obj, t)
-		if err != nil {
+		var (
+			col string
+			err error
+		)
+		if cp, ok := parsers[ix].(parser.CellParser); ok {
+			col, values[ix], err = cp.ParseCell(obj, t)
+		} else {
+			col, err = parsers[ix].Parse(obj, t)
+			values[ix].Data = col
+		}
+		if errors.Is(err, context.Canceled) {
 			return err
 		}
+		if err != nil {
+			err = s.cellSources.Wrap(err, "")
+			if s.Strict {
+				return fmt.Errorf("column %q of %s: %w", s.Columns[ix].Header, objectRef(obj), err)
+			}
+			s.Errors.Record(s.Columns[ix].Header, obj, err)
+			col = s.errorPlaceholder()
+			if errors.Is(err, parser.ErrCellTimeout) {
+				col = timeoutPlaceholder
+			}
+			values[ix] = parser.CellValue{Data: col}
+		}
 
 		columns[ix] = col
 	}
-
-	var multiLinesColumns [][]string
-	// if the column has multiple lines (e.g. a template that outputs multiple lines), we need to split it
-	var maxLen int
-	for _, col := range columns {
-		lines := strings.Split(col, "\n")
-		maxLen = max(maxLen, len(lines))
-		multiLinesColumns = append(multiLinesColumns, lines)
+	if s.ShowKind {
+		s.prefixKind(obj, columns, values)
 	}
 
 	if s.RowSink != nil {
-		s.RowSink(append([]string(nil), columns...))
+		s.RowSink(Row{Object: obj, Cells: append([]string(nil), columns...), Values: values})
 	} else if s.CustomTable != nil {
 		var row table.Row
 		for idx := range columns {
@@ -465,23 +696,139 @@ func (s *CustomColumnsPrinter) printOneObject(obj runtime.Object, parsers []pars
 		}
 		s.CustomTable.AppendRow(row)
 	} else {
-		for i := 0; i < maxLen; i++ {
-			var lineColumns []string
-			for _, multiLinesCol := range multiLinesColumns {
-				if i < len(multiLinesCol) {
-					lineColumns = append(lineColumns, multiLinesCol[i])
-				} else {
-					lineColumns = append(lineColumns, "")
-				}
+		writeTabRow(out, columns)
+	}
+	return nil
+}
+
+// writeTabRow writes one row of tab-separated cells. A cell with several
+// lines (e.g. a template that outputs multiple lines) spreads the row over
+// as many lines, leaving the other columns blank.
+func writeTabRow(out io.Writer, columns []string) {
+	var multiLinesColumns [][]string
+	var maxLen int
+	for _, col := range columns {
+		lines := strings.Split(col, "\n")
+		maxLen = max(maxLen, len(lines))
+		multiLinesColumns = append(multiLinesColumns, lines)
+	}
+	for i := 0; i < maxLen; i++ {
+		var lineColumns []string
+		for _, multiLinesCol := range multiLinesColumns {
+			if i < len(multiLinesCol) {
+				lineColumns = append(lineColumns, multiLinesCol[i])
+			} else {
+				lineColumns = append(lineColumns, "")
 			}
-			fmt.Fprintln(out, strings.Join(lineColumns, "\t"))
 		}
+		fmt.Fprintln(out, strings.Join(lineColumns, "\t"))
 	}
-	return nil
+}
+
+// prefixKind rewrites the first NAME cell as kind/name (kind.group/name for
+// grouped resources), like kubectl's --show-kind.
+func (s *CustomColumnsPrinter) prefixKind(obj runtime.Object, columns []string, values []parser.CellValue) {
+	gvk := unwrapEvent(obj).GetObjectKind().GroupVersionKind()
+	if gvk.Kind == "" {
+		return
+	}
+	kind := strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		kind += "." + gvk.Group
+	}
+	for ix, col := range s.Columns {
+		if strings.EqualFold(col.Header, "NAME") {
+			columns[ix] = kind + "/" + columns[ix]
+			values[ix] = parser.CellValue{Data: columns[ix]}
+			return
+		}
+	}
+}
+
+// columnFormat resolves the format a column renders with: its own Format,
+// "age" for a column headed AGE, and TimeFormat in place of either when the
+// column renders a timestamp.
+func (s *CustomColumnsPrinter) columnFormat(col Column) string {
+	format := col.Format
+	if format == "" && col.Header == "AGE" {
+		format = funcs.TimeFormatAge
+	}
+	if s.TimeFormat != "" && funcs.IsTimestampFormat(format) {
+		format = s.TimeFormat
+	}
+	return format
+}
+
+func (s *CustomColumnsPrinter) errorPlaceholder() string {
+	if s.ErrorPlaceholder == "" {
+		return defaultErrorPlaceholder
+	}
+	return s.ErrorPlaceholder
+}
+
+// probeTypeRegexp finds the probe types a template passes to probeCheck as
+// string literals, so they can be prefetched.
+var probeTypeRegexp = regexp.MustCompile(`probeCheck\s+\S+\s+"(readiness|liveness|startup)"`)
+
+// ConfigureProbes applies the probeCheck settings: whether exec probes may
+// run, how many checks may run at once across rows, and how long each may
+// take (the cell timeout; zero keeps the checker's default).
+func (s *CustomColumnsPrinter) ConfigureProbes(allowExec bool, concurrency int, timeout time.Duration) {
+	if probes := s.scope.Probes(); probes != nil {
+		probes.AllowExec = allowExec
+		probes.Concurrency = concurrency
+		probes.Timeout = timeout
+	}
+}
+
+// PrefetchProbes starts, in the background, the probe checks that the
+// template will ask for on objs, so rows render without waiting on each
+// other's checks. It's a no-op when the template doesn't call probeCheck
+// with a literal probe type.
+func (s *CustomColumnsPrinter) PrefetchProbes(objs []runtime.Object) {
+	probes := s.scope.Probes()
+	if probes == nil {
+		return
+	}
+
+	var text strings.Builder
+	for _, col := range s.Columns {
+		text.WriteString(col.FieldSpec)
+		text.WriteString("\n")
+	}
+	if s.localTemplate != nil {
+		for _, t := range s.localTemplate.Templates() {
+			if t.Tree != nil && t.Tree.Root != nil {
+				text.WriteString(t.Tree.Root.String())
+				text.WriteString("\n")
+			}
+		}
+	}
+	seen := map[string]bool{}
+	var probeTypes []string
+	for _, m := range probeTypeRegexp.FindAllStringSubmatch(text.String(), -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			probeTypes = append(probeTypes, m[1])
+		}
+	}
+	if len(probeTypes) == 0 {
+		return
+	}
+
+	var pods []map[string]interface{}
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok || u.GetKind() != "Pod" {
+			continue
+		}
+		pods = append(pods, u.Object)
+	}
+	probes.Prefetch(s.scope.Context(), pods, probeTypes)
 }
 
 // SplitIgnoringTemplateSpaces splits a string by spaces but ignores spaces inside `{{}}`
-func splitIgnoringTemplateSpaces(input string) []string {
+func SplitIgnoringTemplateSpaces(input string) []string {
 	// Regex to match `{{ ... }}` patterns
 	templateRegex := regexp.MustCompile(`{{[^}]*}}`)
 
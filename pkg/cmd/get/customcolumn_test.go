@@ -2,13 +2,21 @@ package get
 
 import (
 	"bytes"
+	"io"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
+
+	"github.com/kubectl-cwide/pkg/parser"
+	"github.com/kubectl-cwide/pkg/parser/funcs"
+	"github.com/kubectl-cwide/pkg/utils"
+	"github.com/kubectl-cwide/pkg/utils/golden"
 )
 
 // testDecoder returns a minimal runtime.Decoder for tests.
@@ -249,3 +257,449 @@ columns:
 		t.Errorf("expected 'test', got: %s", output)
 	}
 }
+
+func TestCellErrors_PlaceholderAndSummary(t *testing.T) {
+	yamlTmpl := []byte(`
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: FIRST_IMAGE
+    template: '{{ (index .spec.containers 0).image }}'
+`)
+
+	printer, err := NewCustomColumnsPrinterFromYAML(yamlTmpl, testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	good := testObj(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "good", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"image": "nginx"}},
+		},
+	})
+	bad := testObj(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "pending", "namespace": "default"},
+		"spec":       map[string]interface{}{"containers": []interface{}{}},
+	})
+
+	var buf bytes.Buffer
+	for _, obj := range []runtime.Object{good, bad} {
+		if err := printer.PrintObj(obj, &buf); err != nil {
+			t.Fatalf("PrintObj error: %v", err)
+		}
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, "nginx") {
+		t.Errorf("expected healthy row to render, got: %s", output)
+	}
+	if !strings.Contains(output, "<err>") {
+		t.Errorf("expected placeholder for failed cell, got: %s", output)
+	}
+	if printer.Errors.Len() != 1 {
+		t.Fatalf("expected 1 recorded error, got %d", printer.Errors.Len())
+	}
+
+	var summary bytes.Buffer
+	printer.Errors.WriteSummary(&summary)
+	if !strings.Contains(summary.String(), `column "FIRST_IMAGE"`) || !strings.Contains(summary.String(), "default/pending") {
+		t.Errorf("summary missing column or example object: %s", summary.String())
+	}
+}
+
+func TestCellErrors_Strict(t *testing.T) {
+	yamlTmpl := []byte(`
+columns:
+  - header: FIRST_IMAGE
+    template: '{{ (index .spec.containers 0).image }}'
+`)
+
+	printer, err := NewCustomColumnsPrinterFromYAML(yamlTmpl, testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	printer.Strict = true
+
+	obj := testObj(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "pending"},
+		"spec":       map[string]interface{}{"containers": []interface{}{}},
+	})
+
+	var buf bytes.Buffer
+	if err := printer.PrintObj(obj, &buf); err == nil {
+		t.Fatal("expected strict mode to fail on the first bad cell")
+	}
+}
+
+func TestColumnFormat(t *testing.T) {
+	yamlTmpl := []byte(`
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: CREATED
+    fieldSpec: .metadata.creationTimestamp
+    format: rfc3339
+  - header: EXPIRES
+    fieldSpec: .status.notAfter
+    format: age
+  - header: TIMEOUT
+    fieldSpec: .spec.timeoutSeconds
+    format: duration
+`)
+	printer, err := NewCustomColumnsPrinterFromYAML(yamlTmpl, testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	future := time.Now().Add(73 * time.Hour).UTC().Format(time.RFC3339)
+	obj := testObj(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "cert", "creationTimestamp": "2024-03-01T10:00:00+02:00"},
+		"spec":       map[string]interface{}{"timeoutSeconds": int64(5400)},
+		"status":     map[string]interface{}{"notAfter": future},
+	})
+
+	var cells []string
+	var values []parser.CellValue
+	printer.RowSink = func(row Row) { cells, values = row.Cells, row.Values }
+	if err := printer.PrintObj(obj, io.Discard); err != nil {
+		t.Fatalf("PrintObj error: %v", err)
+	}
+	want := []string{"cert", "2024-03-01T08:00:00Z", "in 3d", "90m"}
+	if strings.Join(cells, "|") != strings.Join(want, "|") {
+		t.Errorf("cells = %q, want %q", cells, want)
+	}
+	if values[1].Kind != parser.ValueTime || values[2].Kind != parser.ValueAge || values[3].Duration != 90*time.Minute {
+		t.Errorf("values = %+v", values)
+	}
+
+	printer.TimeFormat = "unix"
+	if err := printer.PrintObj(obj, io.Discard); err != nil {
+		t.Fatalf("PrintObj error: %v", err)
+	}
+	if cells[1] != "1709280000" || cells[3] != "90m" {
+		t.Errorf("--time-format=unix: cells = %q", cells)
+	}
+}
+
+func TestColumnFormat_Unknown(t *testing.T) {
+	yamlTmpl := []byte(`
+columns:
+  - header: CREATED
+    fieldSpec: .metadata.creationTimestamp
+    format: fortnights
+`)
+	if _, err := NewCustomColumnsPrinterFromYAML(yamlTmpl, testDecoder(), nil); err == nil || !strings.Contains(err.Error(), "fortnights") {
+		t.Fatalf("want unknown format error, got %v", err)
+	}
+}
+
+func TestColumnListOptions(t *testing.T) {
+	yamlTmpl := []byte(`
+columns:
+  - header: IMAGES
+    fieldSpec: .spec.containers[*].image
+    unique: true
+    maxItems: 2
+  - header: NODE
+    fieldSpec: .spec.nodeName
+    default: "-"
+  - header: PORTS
+    template: '{{ list 80 443 8080 | toJson }}'
+    separator: " "
+  - header: NAMES
+    template: '{{ range .spec.containers }}{{ .name }}{{ "\n" }}{{ end }}'
+    separator: "/"
+`)
+	printer, err := NewCustomColumnsPrinterFromYAML(yamlTmpl, testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	container := func(name, image string) interface{} {
+		return map[string]interface{}{"name": name, "image": image}
+	}
+	obj := testObj(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "web"},
+		"spec": map[string]interface{}{"containers": []interface{}{
+			container("app", "nginx"), container("proxy", "envoy"), container("copy", "nginx"), container("log", "fluentd"),
+		}},
+	})
+
+	var cells []string
+	printer.RowSink = func(row Row) { cells = row.Cells }
+	if err := printer.PrintObj(obj, io.Discard); err != nil {
+		t.Fatalf("PrintObj error: %v", err)
+	}
+	want := []string{"nginx,envoy,+1 more", "-", "80 443 8080", "app/proxy/copy/log"}
+	if strings.Join(cells, "|") != strings.Join(want, "|") {
+		t.Errorf("cells = %q, want %q", cells, want)
+	}
+}
+
+func TestColumnListOptions_NegativeMaxItems(t *testing.T) {
+	yamlTmpl := []byte(`
+columns:
+  - header: IMAGES
+    fieldSpec: .spec.containers[*].image
+    maxItems: -1
+`)
+	if _, err := NewCustomColumnsPrinterFromYAML(yamlTmpl, testDecoder(), nil); err == nil {
+		t.Fatal("want error for negative maxItems")
+	}
+}
+
+// withColor enables colorIf output for the duration of the test.
+func withColor(t *testing.T) {
+	t.Helper()
+	if v, ok := os.LookupEnv("NO_COLOR"); ok {
+		os.Unsetenv("NO_COLOR")
+		t.Cleanup(func() { os.Setenv("NO_COLOR", v) })
+	}
+	funcs.SetColorDisabled(false)
+}
+
+func alignmentPods() []runtime.Object {
+	pod := func(name, phase, team string) runtime.Object {
+		return testObj(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata":   map[string]interface{}{"name": name, "labels": map[string]interface{}{"team": team}},
+			"status":     map[string]interface{}{"phase": phase},
+		})
+	}
+	return []runtime.Object{
+		pod("web", "Running", "platform"),
+		pod("数据库-0", "CrashLoopBackOff", "数据团队"),
+		pod("cache-✅", "Pending", "sre"),
+	}
+}
+
+const alignmentTemplate = `
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: STATUS
+    template: '{{ colorIf (eq .status.phase "Running") "green" (colorIf (ne .status.phase "Running") "red" .status.phase) }}'
+  - header: TEAM
+    fieldSpec: .metadata.labels.team
+  - header: END
+    template: '{{ "|" }}'
+`
+
+func TestAlignment_TabWriterGolden(t *testing.T) {
+	withColor(t)
+	printer, err := NewCustomColumnsPrinterFromYAML([]byte(alignmentTemplate), testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	w := utils.NewTabWriter(&buf)
+	for _, obj := range alignmentPods() {
+		if err := printer.PrintObj(obj, w); err != nil {
+			t.Fatalf("PrintObj error: %v", err)
+		}
+	}
+	w.Flush()
+	golden.Assert(t, "align_tabwriter", buf.Bytes())
+}
+
+func TestAlignment_RenderRowsGolden(t *testing.T) {
+	withColor(t)
+	printer, err := NewCustomColumnsPrinterFromYAML([]byte(alignmentTemplate), testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var rows []Row
+	printer.RowSink = func(row Row) { rows = append(rows, row) }
+	for _, obj := range alignmentPods() {
+		if err := printer.PrintObj(obj, io.Discard); err != nil {
+			t.Fatalf("PrintObj error: %v", err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := renderTypedRows(&buf, "table", printer.Headers, rows); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	golden.Assert(t, "align_render_rows", buf.Bytes())
+}
+
+func TestAlignment_CustomTableGolden(t *testing.T) {
+	withColor(t)
+	printer, err := NewCustomColumnsPrinterFromYAML([]byte(alignmentTemplate), testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	printer.WithCustomTable()
+	for _, obj := range alignmentPods() {
+		if err := printer.PrintObj(obj, io.Discard); err != nil {
+			t.Fatalf("PrintObj error: %v", err)
+		}
+	}
+	printer.CustomTable.SetOutputMirror(nil)
+	golden.Assert(t, "align_ctable", []byte(printer.CustomTable.Render()+"\n"))
+}
+
+func TestFlagColumns(t *testing.T) {
+	printer, err := NewCustomColumnsPrinterFromYAML([]byte(`
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+`), testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	printer.InsertNamespaceColumn()
+	printer.InsertNamespaceColumn()
+	printer.AddLabelColumns([]string{"app.kubernetes.io/name", "tier"})
+	printer.AddLabelsColumn()
+	printer.ShowKind = true
+
+	var rows []Row
+	printer.RowSink = func(row Row) { rows = append(rows, row) }
+	objs := []runtime.Object{
+		testObj(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name": "web", "namespace": "prod",
+				"labels": map[string]interface{}{"tier": "1", "app.kubernetes.io/name": "shop"},
+			},
+		}),
+		testObj(map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "db", "namespace": "dev"},
+		}),
+	}
+	for _, obj := range objs {
+		if err := printer.PrintObj(obj, io.Discard); err != nil {
+			t.Fatalf("PrintObj: %v", err)
+		}
+	}
+
+	if got := strings.Join(printer.Headers, ","); got != "NAMESPACE,NAME,NAME,TIER,LABELS" {
+		t.Fatalf("headers = %s", got)
+	}
+	want := [][]string{
+		{"prod", "pod/web", "shop", "1", "app.kubernetes.io/name=shop,tier=1"},
+		{"dev", "pod/db", "", "", "<none>"},
+	}
+	for i, row := range rows {
+		if strings.Join(row.Cells, "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, row.Cells, want[i])
+		}
+	}
+	// A label value that looks like a number stays a string.
+	if v := rows[0].Values[3].Data; v != "1" {
+		t.Errorf("TIER value = %#v, want \"1\"", v)
+	}
+}
+
+func TestYAMLFileErrorPositions(t *testing.T) {
+	cases := map[string]string{
+		// A literal block: lines and columns are those of the file.
+		`columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: READY
+    template: |
+      {{- with .status }}
+        {{ .phase | nosuch }}
+      {{- end }}
+`: `pod.yaml:7:7: column "READY": function "nosuch" not defined`,
+		// A quoted scalar starts after the quote.
+		`columns:
+  - header: NAME
+    template: '{{ .metadata.name }'
+`: `pod.yaml:3:16: column "NAME": unexpected "}" in operand`,
+		`helpers: |
+  {{ define "x" }}
+    {{ .foo | nosuch }}
+  {{ end }}
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+`: `pod.yaml:3:3: failed to parse helpers template: function "nosuch" not defined`,
+		`columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+    format: fortnights
+`: `pod.yaml:2:5: column "NAME": unknown format "fortnights"`,
+		`columns:
+  - header: NAME
+    fieldSpec: .metadata.name: x
+`: `pod.yaml:3: mapping values are not allowed in this context`,
+	}
+	for body, want := range cases {
+		_, err := NewCustomColumnsPrinterFromYAMLFile("pod.yaml", []byte(body), "", testDecoder(), nil)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got %v, want %q", err, want)
+		}
+	}
+}
+
+func TestYAMLFileExecErrorPosition(t *testing.T) {
+	printer, err := NewCustomColumnsPrinterFromYAMLFile("pod.yaml", []byte(`columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: IMAGE
+    template: "{{ index .spec.containers 5 }}"
+`), "", testDecoder(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := testObj(map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web", "namespace": "shop"},
+		"spec":     map[string]interface{}{"containers": []interface{}{}},
+	})
+	if err := printer.PrintObj(obj, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	var summary bytes.Buffer
+	printer.Errors.WriteSummary(&summary)
+	// The offset of the failing action within the string.
+	if want := `column "IMAGE": pod.yaml:5:19: executing "column1" at <index .spec.containers 5>`; !strings.Contains(summary.String(), want) {
+		t.Errorf("summary lacks %q:\n%s", want, summary.String())
+	}
+
+	printer.Strict = true
+	if err := printer.PrintObj(obj, io.Discard); err == nil || !strings.Contains(err.Error(), "pod.yaml:5:19: ") {
+		t.Errorf("strict: got %v", err)
+	}
+}
+
+func TestCustomColumnsSpec(t *testing.T) {
+	printer, err := NewCustomColumnsPrinterFromSpec("NAME:metadata.name,PHASE:{.status.phase}", testDecoder(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	obj := testObj(map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web"},
+		"status":   map[string]interface{}{"phase": "Running"},
+	})
+	if err := Render(&out, printer, []runtime.Object{obj}, "table"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Fields(out.String()); strings.Join(got, " ") != "NAME PHASE web Running" {
+		t.Errorf("got %q", out.String())
+	}
+
+	columns, err := ParseCustomColumnsSpec("NAME:metadata.name,PHASE:{.status.phase}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(columns) != 2 || columns[0].FieldSpec != "metadata.name" || columns[1].FieldSpec != "{.status.phase}" {
+		t.Errorf("field specs are not kept as written: %+v", columns)
+	}
+	if _, err := ParseCustomColumnsSpec("NAME"); err == nil {
+		t.Error("a spec without a field spec should fail")
+	}
+}
@@ -4,29 +4,26 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/kubectl-cwide/pkg/clients"
 	"github.com/kubectl-cwide/pkg/cmd/completions"
+	"github.com/kubectl-cwide/pkg/models"
+	"github.com/kubectl-cwide/pkg/parser/funcs"
 	"github.com/kubectl-cwide/pkg/utils"
 	"github.com/spf13/cobra"
-	"k8s.io/apimachinery/pkg/api/meta"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
-	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/cli-runtime/pkg/resource"
 	"k8s.io/client-go/rest"
-	watchtools "k8s.io/client-go/tools/watch"
-	"k8s.io/utils/ptr"
 
 	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 	"k8s.io/kubectl/pkg/scheme"
-	"k8s.io/kubectl/pkg/util/i18n"
-	"k8s.io/kubectl/pkg/util/interrupt"
 )
 
 type GetOptions struct {
@@ -36,6 +33,7 @@ type GetOptions struct {
 	ChunkSize int64
 
 	OutputWatchEvents bool
+	Timeline          bool
 
 	LabelSelector     string
 	FieldSelector     string
@@ -50,6 +48,9 @@ type GetOptions struct {
 
 	NoHeaders      bool
 	IgnoreNotFound bool
+	ShowLabels     bool
+	ShowKind       bool
+	LabelColumns   []string
 
 	genericiooptions.IOStreams
 
@@ -61,41 +62,68 @@ type GetOptions struct {
 	Output            string
 	SortColumn        string
 	FilterExprs       []string
+	Strict            bool
+	ErrorPlaceholder  string
+	CellTimeout       time.Duration
+	RenderTimeout     time.Duration
+	ProbeExec         bool
+	ProbeConcurrency  int
+	TimeFormat        string
 
 	factory cmdutil.Factory
 	args    []string
+	// templateRules holds the config whose templateRules choose the
+	// template per object; nil when --template was given.
+	templateRules *models.Config
+	// templateLayers is the template search path, earliest layer first.
+	templateLayers utils.TemplateSearchPath
+	// customColumns and customColumnsFile are the columns of -o
+	// custom-columns=SPEC and -o custom-columns-file=FILE, used instead of
+	// a template.
+	customColumns     string
+	customColumnsFile string
 }
 
 // NewGetOptions returns a GetOptions with default chunk size 500.
 func NewGetOptions(streams genericiooptions.IOStreams) *GetOptions {
 	return &GetOptions{
 		IOStreams:   streams,
-		ChunkSize:  cmdutil.DefaultChunkSize,
+		ChunkSize:   cmdutil.DefaultChunkSize,
 		ServerPrint: true,
 	}
 }
 
-// resolveTemplatePrinter finds the template file (.yaml first, then .tpl) and creates the appropriate printer.
-// Shared helpers under <rootPath>/_shared/*.tpl are concatenated at the top of .tpl template bodies before parsing,
-// and merged into the `helpers` field of .yaml templates.
-func resolveTemplatePrinter(rootPath, crdTemplateDir, templateName string, decoder runtime.Decoder, restConfig *rest.Config) (*CustomColumnsPrinter, error) {
-	dir := filepath.Join(rootPath, crdTemplateDir)
-	sharedHelpers, _ := loadSharedHelpers(rootPath)
-
-	// Try .yaml first
-	yamlPath := filepath.Join(dir, templateName+".yaml")
-	if data, err := os.ReadFile(yamlPath); err == nil {
-		if sharedHelpers != "" {
-			data = injectYAMLHelpers(data, sharedHelpers)
+// ResolveTemplatePrinter finds the template for kind gvk in the first layer of the search path that has it (.yaml
+// first, then .tpl) and creates the appropriate printer. If only another version of the kind has the template, that
+// one is used and a warning naming its version is written to errOut. Shared helpers under _shared/*.tpl of every
+// layer are concatenated at the top of .tpl template bodies before parsing, and merged into the `helpers` field of
+// .yaml templates.
+func ResolveTemplatePrinter(layers utils.TemplateSearchPath, gvk schema.GroupVersionKind, templateName string, decoder runtime.Decoder, restConfig *rest.Config, errOut io.Writer) (*CustomColumnsPrinter, error) {
+	dir := utils.GenerateDirNameByGVK(gvk)
+	file, ok := layers.Resolve(gvk, templateName)
+	if !ok {
+		return nil, fmt.Errorf("template not found (tried %s.yaml and %s.tpl in %s, %s and other versions of every template layer)", templateName, templateName, dir, utils.GenerateVersionlessDirName(gvk.GroupKind()))
+	}
+	if file.Dir != dir && errOut != nil {
+		used := "the version-less template in " + file.Dir
+		if v := file.Version(); v != "" {
+			used = "the template for " + v
 		}
-		return NewCustomColumnsPrinterFromYAML(data, decoder, restConfig)
+		fmt.Fprintf(errOut, "Warning: no %q template for %s %s; using %s\n", templateName, gvk.GroupVersion(), gvk.Kind, used)
 	}
+	return NewCustomColumnsPrinterFromFile(layers, file, decoder, restConfig)
+}
 
-	// Fall back to .tpl
-	tplPath := filepath.Join(dir, templateName+".tpl")
-	data, err := os.ReadFile(tplPath)
+// NewCustomColumnsPrinterFromFile creates the printer of a template file, with the shared helpers of layers.
+func NewCustomColumnsPrinterFromFile(layers utils.TemplateSearchPath, file utils.TemplateFile, decoder runtime.Decoder, restConfig *rest.Config) (*CustomColumnsPrinter, error) {
+	data, err := file.Read()
 	if err != nil {
-		return nil, fmt.Errorf("template not found (tried %s.yaml and %s.tpl in %s)", templateName, templateName, dir)
+		return nil, fmt.Errorf("failed to read template %s: %w", file.Path(), err)
+	}
+	sharedHelpers := layers.SharedHelpers()
+
+	if file.Ext == ".yaml" {
+		return NewCustomColumnsPrinterFromYAMLFile(file.Path(), data, sharedHelpers, decoder, restConfig)
 	}
 
 	if sharedHelpers != "" {
@@ -104,60 +132,20 @@ func resolveTemplatePrinter(rootPath, crdTemplateDir, templateName string, decod
 	return NewCustomColumnsPrinterFromTemplate(strings.NewReader(string(data)), decoder, restConfig)
 }
 
-// loadSharedHelpers concatenates every *.tpl file under <rootPath>/_shared/
-// so `{{ template "name" . }}` calls from templates can resolve helpers
-// defined once and reused everywhere.
-func loadSharedHelpers(rootPath string) (string, error) {
-	sharedDir := filepath.Join(rootPath, "_shared")
-	entries, err := os.ReadDir(sharedDir)
-	if err != nil {
-		return "", err // no shared/ dir is fine, caller ignores the error
-	}
-	var b strings.Builder
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		if !strings.HasSuffix(e.Name(), ".tpl") {
-			continue
-		}
-		data, err := os.ReadFile(filepath.Join(sharedDir, e.Name()))
-		if err != nil {
-			continue
-		}
-		b.Write(data)
-		b.WriteString("\n")
-	}
-	return b.String(), nil
-}
-
-// injectYAMLHelpers appends sharedHelpers to the YAML template's `helpers`
-// field. If the field does not exist, it's created.
-func injectYAMLHelpers(data []byte, sharedHelpers string) []byte {
-	// Cheap textual merge: if the file already has a `helpers: |` block,
-	// leave that alone (the YAML parser can only handle one). Otherwise
-	// prepend a new one.
-	if strings.Contains(string(data), "\nhelpers:") || strings.HasPrefix(string(data), "helpers:") {
-		return data
-	}
-	// indent each helper line by 2 spaces for the block scalar
-	indented := "  " + strings.ReplaceAll(sharedHelpers, "\n", "\n  ")
-	block := "helpers: |\n" + indented + "\n"
-	return append([]byte(block), data...)
-}
-
 // Complete resolves flags and sets up the factory.
 func (o *GetOptions) Complete(cmd *cobra.Command, args []string) error {
 	o.args = utils.ResolveAlias(args)
 
-	rootPath, err := utils.ResolveTemplatePath(cmd)
-	if err != nil {
-		return fmt.Errorf("failed to resolve template path: %w", err)
-	}
-	o.TemplateRootPath = rootPath
-
 	o.factory = clients.FactoryFromCmd(cmd, o.Context)
 
+	// The user root is optional: the project, team, ConfigMap and built-in
+	// layers may still have the template.
+	o.TemplateRootPath, _ = utils.ResolveTemplatePath(cmd)
+	o.templateLayers = utils.ResolveTemplateSearchPath(cmd, func(src utils.ConfigMapSource) (utils.TemplateLayer, bool) {
+		return o.configMapLayer(cmd.Context(), src)
+	})
+
+	var err error
 	if o.Namespace == "" {
 		o.Namespace, o.ExplicitNamespace, err = o.factory.ToRawKubeConfigLoader().Namespace()
 		if err != nil {
@@ -171,11 +159,24 @@ func (o *GetOptions) Complete(cmd *cobra.Command, args []string) error {
 
 	o.NoHeaders = cmdutil.GetFlagBool(cmd, "no-headers")
 
+	// -o custom-columns[-file]=... chooses the columns, not the output
+	// format: the objects are printed as a table.
+	switch {
+	case strings.HasPrefix(o.Output, "custom-columns="):
+		o.customColumns, o.Output = strings.TrimPrefix(o.Output, "custom-columns="), ""
+		if _, err := ParseCustomColumnsSpec(o.customColumns); err != nil {
+			return err
+		}
+	case strings.HasPrefix(o.Output, "custom-columns-file="):
+		o.customColumnsFile, o.Output = strings.TrimPrefix(o.Output, "custom-columns-file="), ""
+	}
+
 	// If the user didn't pass --template, resolve the per-context/per-namespace
 	// default from config.yaml, falling back to "default".
 	if !cmd.Flag("template").Changed {
 		if cfg, err := utils.LoadConfig(); err == nil {
 			o.Template = cfg.ResolveDefaultTemplate(o.Context, o.Namespace)
+			o.templateRules = cfg
 		}
 	}
 
@@ -184,12 +185,26 @@ func (o *GetOptions) Complete(cmd *cobra.Command, args []string) error {
 
 // Validate checks that the resolved options are consistent.
 func (o *GetOptions) Validate() error {
-	if o.TemplateRootPath == "" {
-		return fmt.Errorf("template path is required: use --template-path or run 'init' first")
-	}
 	if o.WatchOnly && o.Watch {
 		return fmt.Errorf("--watch and --watch-only are mutually exclusive")
 	}
+	if o.Timeline && (o.Watch || o.WatchOnly) {
+		return fmt.Errorf("--timeline watches on its own and cannot be combined with --watch or --watch-only")
+	}
+	if o.Raw != "" {
+		if len(o.args) > 0 {
+			return fmt.Errorf("arguments may not be passed when --raw is specified")
+		}
+		if o.Watch || o.WatchOnly || o.Timeline || o.AllNamespaces || o.LabelSelector != "" || o.FieldSelector != "" || o.Subresource != "" {
+			return fmt.Errorf("--raw may not be specified with flags that select or watch objects")
+		}
+		if _, err := url.ParseRequestURI(o.Raw); err != nil {
+			return fmt.Errorf("--raw must be a valid URL path: %v", err)
+		}
+	}
+	if o.TimeFormat != "" && !funcs.IsTimestampFormat(o.TimeFormat) {
+		return fmt.Errorf("invalid --time-format %q (expected one of age, relative, rfc3339, local, unix)", o.TimeFormat)
+	}
 	return nil
 }
 
@@ -202,15 +217,37 @@ func (o *GetOptions) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if o.Raw != "" {
+		return o.raw(cmd.Context())
+	}
+	if o.Timeline {
+		return o.timeline(cmd.Context())
+	}
 	if o.Watch || o.WatchOnly {
-		return o.watch()
+		return o.watch(cmd.Context())
 	}
 
-	return o.list()
+	return o.list(cmd.Context())
+}
+
+// renderContext bounds one rendering pass (a listing, or a single watch
+// event) by --render-timeout. Template functions observe the returned
+// context through the printer; cells started after it expires render as
+// "<timeout>" instead of blocking the table.
+func (o *GetOptions) renderContext(ctx context.Context, printer *CustomColumnsPrinter) context.CancelFunc {
+	cancel := context.CancelFunc(func() {})
+	if o.RenderTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, o.RenderTimeout)
+	}
+	printer.SetContext(ctx)
+	return cancel
 }
 
-func (o *GetOptions) list() error {
+func (o *GetOptions) list(ctx context.Context) error {
 	r := o.buildRequest()
+	if o.IgnoreNotFound {
+		r.IgnoreErrors(apierrors.IsNotFound)
+	}
 
 	if err := r.Err(); err != nil {
 		return err
@@ -222,15 +259,10 @@ func (o *GetOptions) list() error {
 	}
 
 	if len(infos) == 0 {
-		fmt.Fprintf(o.ErrOut, "No resources found in %s namespace.\n", o.Namespace)
+		o.noResources()
 		return nil
 	}
 
-	printer, err := o.createPrinter(infos)
-	if err != nil {
-		return err
-	}
-
 	// Native output formats (yaml, json, name, jsonpath=...) dump the raw
 	// resource object like `kubectl get`. Template-based formats (csv,
 	// template-yaml, template-json) render the column template and emit
@@ -239,88 +271,57 @@ func (o *GetOptions) list() error {
 	if isNativeOutput(o.Output) {
 		return o.emitNative(infos)
 	}
-	if o.Output != "" || o.SortColumn != "" || len(o.FilterExprs) > 0 {
-		return o.emitStructured(printer, infos)
-	}
 
-	w := printers.GetNewTabWriter(os.Stdout)
-	for _, info := range infos {
-		if err := printer.PrintObj(info.Object, w); err != nil {
-			return fmt.Errorf("failed to print object: %w", err)
-		}
+	groups, err := o.groupByTemplate(infos)
+	if err != nil {
+		return err
 	}
+	return o.printGroups(ctx, groups)
+}
 
-	if printer.CustomTable != nil {
-		printer.CustomTable.Render()
-	} else {
-		w.Flush()
+// noResources reports, like kubectl get, that nothing was found, unless
+// --ignore-not-found is set.
+func (o *GetOptions) noResources() {
+	if !o.IgnoreNotFound {
+		fmt.Fprintf(o.ErrOut, "No resources found in %s namespace.\n", o.Namespace)
 	}
-
-	return nil
 }
 
-func (o *GetOptions) emitStructured(printer *CustomColumnsPrinter, infos []*resource.Info) error {
-	var rows [][]string
-	printer.RowSink = func(cols []string) { rows = append(rows, cols) }
-	for _, info := range infos {
-		if err := printer.PrintObj(info.Object, io.Discard); err != nil {
-			return fmt.Errorf("failed to render row: %w", err)
-		}
+// printGroups renders each group through its printer: as tables separated
+// by a blank line, or through emitStructured for -o, --sort-by and
+// --filter.
+func (o *GetOptions) printGroups(ctx context.Context, groups []*templateGroup) error {
+	if o.Output != "" || o.SortColumn != "" || len(o.FilterExprs) > 0 {
+		return o.emitStructured(ctx, groups)
 	}
 
-	if len(o.FilterExprs) > 0 {
-		filtered, err := filterRows(printer.Headers, rows, o.FilterExprs)
-		if err != nil {
-			return err
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(o.Out)
 		}
-		rows = filtered
-	}
-
-	if o.SortColumn != "" {
-		if err := sortRows(printer.Headers, rows, o.SortColumn); err != nil {
+		if err := o.printTable(ctx, g); err != nil {
 			return err
 		}
 	}
-
-	if o.Output != "" {
-		return renderRows(o.Out, o.Output, printer.Headers, rows)
+	for _, g := range groups {
+		g.printer.Errors.WriteSummary(o.ErrOut)
 	}
-
-	// No explicit -o: render the standard table using our own writer.
-	return renderRows(o.Out, "table", printer.Headers, rows)
+	return nil
 }
 
-func (o *GetOptions) watch() error {
-	r := o.buildRequest()
-
-	if err := r.Err(); err != nil {
-		return err
-	}
-
-	infos, err := r.Infos()
-	if err != nil {
-		return err
-	}
-	if multipleGVKsRequested(infos) {
-		return i18n.Errorf("watch is only supported on individual resources and resource collections - more than 1 resource was found")
-	}
-
-	if len(infos) == 0 {
-		fmt.Fprintf(o.ErrOut, "No resources found in %s namespace.\n", o.Namespace)
-		return nil
-	}
-
-	printer, err := o.createPrinter(infos)
-	if err != nil {
-		return err
-	}
-
-	outputObjects := ptr.To(!o.WatchOnly)
+// printTable prints one group as a table.
+func (o *GetOptions) printTable(ctx context.Context, g *templateGroup) error {
+	printer := g.printer
+	cancel := o.renderContext(ctx, printer)
+	defer cancel()
+	printer.PrefetchProbes(g.objs)
 
-	// print the current objects
-	w := printers.GetNewTabWriter(os.Stdout)
-	for _, info := range infos {
-		if err := printer.PrintObj(info.Object, w); err != nil {
+	w := utils.NewTabWriter(o.Out)
+	for _, obj := range g.objs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := printer.PrintObj(obj, w); err != nil {
 			return fmt.Errorf("failed to print object: %w", err)
 		}
 	}
@@ -330,59 +331,77 @@ func (o *GetOptions) watch() error {
 	} else {
 		w.Flush()
 	}
+	return nil
+}
 
-	obj, err := r.Object()
-	if err != nil {
-		return err
-	}
-	rv := "0"
-	isList := meta.IsListType(obj)
-	if isList {
-		rv, err = meta.NewAccessor().ResourceVersion(obj)
+// emitStructured renders every group into rows, filters and sorts each
+// group, and writes them in the -o format (the table by default).
+func (o *GetOptions) emitStructured(ctx context.Context, groups []*templateGroup) error {
+	rendered := make([]rowGroup, 0, len(groups))
+	for _, g := range groups {
+		rows, err := o.renderGroup(ctx, g)
 		if err != nil {
 			return err
 		}
+		rendered = append(rendered, rowGroup{headers: g.printer.Headers, rows: rows})
 	}
 
-	if isList {
-		*outputObjects = true
-	} else {
-		*outputObjects = false
+	format := o.Output
+	if format == "" {
+		// No explicit -o: render the standard table using our own writer.
+		format = "table"
 	}
-
-	watcher, err := r.Watch(rv)
-	if err != nil {
+	if err := renderGroups(o.Out, format, rendered); err != nil {
 		return err
 	}
+	for _, g := range groups {
+		g.printer.Errors.WriteSummary(o.ErrOut)
+	}
+	return nil
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// renderGroup renders one group's objects into rows, applying --filter and
+// --sort-by.
+func (o *GetOptions) renderGroup(ctx context.Context, g *templateGroup) ([]Row, error) {
+	printer := g.printer
+	cancel := o.renderContext(ctx, printer)
 	defer cancel()
-	intr := interrupt.New(nil, cancel)
-	intr.Run(func() error {
-		_, err := watchtools.UntilWithoutRetry(ctx, watcher, func(e watch.Event) (bool, error) {
-			objToPrint := e.Object
-			if o.OutputWatchEvents {
-				objToPrint = &metav1.WatchEvent{Type: string(e.Type), Object: runtime.RawExtension{Object: objToPrint}}
-			}
-
-			if err := printer.PrintObj(objToPrint, w); err != nil {
-				return false, err
-			}
-
-			if printer.CustomTable != nil {
-				printer.CustomTable.Render()
-			} else {
-				w.Flush()
-			}
-			*outputObjects = true
-			return false, nil
-		})
-		return err
-	})
-	return nil
+	printer.PrefetchProbes(g.objs)
+
+	var rows []Row
+	printer.RowSink = func(row Row) { rows = append(rows, row) }
+	for _, obj := range g.objs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := printer.PrintObj(obj, io.Discard); err != nil {
+			return nil, fmt.Errorf("failed to render row: %w", err)
+		}
+	}
+
+	if len(o.FilterExprs) > 0 {
+		filtered, err := filterTypedRows(printer.Headers, rows, o.FilterExprs)
+		if err != nil {
+			return nil, err
+		}
+		rows = filtered
+	}
+
+	if o.SortColumn != "" {
+		if err := sortTypedRows(printer.Headers, rows, o.SortColumn); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
 }
 
 func (o *GetOptions) buildRequest() *resource.Result {
+	return o.newBuilder().Flatten().Do()
+}
+
+// newBuilder returns a builder for the requested objects. Without Flatten,
+// each list request yields one info per list chunk.
+func (o *GetOptions) newBuilder() *resource.Builder {
 	return o.factory.NewBuilder().
 		Unstructured().
 		DefaultNamespace().
@@ -395,14 +414,14 @@ func (o *GetOptions) buildRequest() *resource.Result {
 		RequestChunksOf(o.ChunkSize).
 		ResourceTypeOrNameArgs(true, o.args...).
 		ContinueOnError().
-		Latest().
-		Flatten().
-		Do()
+		Latest()
 }
 
-func (o *GetOptions) createPrinter(infos []*resource.Info) (*CustomColumnsPrinter, error) {
-	crdTemplateDir := utils.GenerateDirNameByGVK(infos[0].Object.GetObjectKind().GroupVersionKind())
-
+// createPrinter resolves the template printer named templateName for objects
+// of kind gvk and adds the columns requested by flags. namespaced tells
+// whether the kind is namespaced, for the NAMESPACE column added with
+// --all-namespaces.
+func (o *GetOptions) createPrinter(gvk schema.GroupVersionKind, namespaced bool, templateName string) (*CustomColumnsPrinter, error) {
 	decoder := scheme.Codecs.UniversalDecoder(scheme.Scheme.PrioritizedVersionsAllGroups()...)
 
 	restConfig, err := o.factory.ToRESTConfig()
@@ -410,7 +429,20 @@ func (o *GetOptions) createPrinter(infos []*resource.Info) (*CustomColumnsPrinte
 		return nil, fmt.Errorf("failed to get REST config: %w", err)
 	}
 
-	printer, err := resolveTemplatePrinter(o.TemplateRootPath, crdTemplateDir, o.Template, decoder, restConfig)
+	var printer *CustomColumnsPrinter
+	switch {
+	case o.customColumns != "":
+		printer, err = NewCustomColumnsPrinterFromSpec(o.customColumns, decoder, o.NoHeaders)
+	case o.customColumnsFile != "":
+		var f *os.File
+		if f, err = os.Open(o.customColumnsFile); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		printer, err = NewCustomColumnsPrinterFromTemplate(f, decoder, restConfig)
+	default:
+		printer, err = ResolveTemplatePrinter(o.templateLayers, gvk, templateName, decoder, restConfig, o.ErrOut)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -420,11 +452,24 @@ func (o *GetOptions) createPrinter(infos []*resource.Info) (*CustomColumnsPrinte
 			return nil, err
 		}
 	}
+	if o.AllNamespaces && namespaced {
+		printer.InsertNamespaceColumn()
+	}
+	printer.AddLabelColumns(o.LabelColumns)
+	if o.ShowLabels {
+		printer.AddLabelsColumn()
+	}
+	printer.ShowKind = o.ShowKind
 
 	if o.EnableCustomTable {
 		printer.WithCustomTable()
 	}
 	printer.NoHeaders = o.NoHeaders
+	printer.Strict = o.Strict
+	printer.ErrorPlaceholder = o.ErrorPlaceholder
+	printer.CellTimeout = o.CellTimeout
+	printer.TimeFormat = o.TimeFormat
+	printer.ConfigureProbes(o.ProbeExec, o.ProbeConcurrency, o.CellTimeout)
 
 	return printer, nil
 }
@@ -454,9 +499,18 @@ in <root>/<kind>-<group>-<version>/<template>.yaml (falling back to .tpl).`,
   # Watch pods
   kubectl cwide get pods -w
 
+  # Watch pods and deployments, each with its own template
+  kubectl cwide get pods,deployments -w
+
+  # Stream pods that are not running as JSON lines
+  kubectl cwide get pods -A -w -o ndjson --filter 'STATUS!=Running'
+
+  # Log how pod columns change during an incident, as JSON on exit
+  kubectl cwide get pods --timeline -o template-json > timeline.json
+
   # List across all namespaces
   kubectl cwide get pods -A`,
-		Args:              cobra.MinimumNArgs(1),
+		Args:              getArgs,
 		ValidArgsFunction: completions.ResourceTypes,
 		RunE:              o.Run,
 	}
@@ -467,7 +521,13 @@ in <root>/<kind>-<group>-<version>/<template>.yaml (falling back to .tpl).`,
 	cmd.Flags().StringVar(&o.Raw, "raw", o.Raw, "Raw URI to request from the server. Uses the transport specified by the kubeconfig file.")
 	cmd.Flags().BoolVarP(&o.Watch, "watch", "w", o.Watch, "After listing/getting the requested object, watch for changes.")
 	cmd.Flags().BoolVar(&o.WatchOnly, "watch-only", o.WatchOnly, "Watch for changes to the requested object(s), without listing/getting first.")
+	cmd.Flags().BoolVar(&o.Timeline, "timeline", o.Timeline, "Watch the requested object(s) and log every change of a rendered cell with its time (e.g. '12:03:11  pod/web  STATUS  Running→Error'). "+
+		"-o csv and ndjson stream the changes; template-json and template-yaml write each object's change log on exit.")
+	cmd.Flags().BoolVar(&o.OutputWatchEvents, "output-watch-events", o.OutputWatchEvents, "Prefix table and csv watch output with an EVENT column (ADDED, MODIFIED, DELETED). Structured watch output always includes the event type.")
 	cmd.Flags().BoolVar(&o.IgnoreNotFound, "ignore-not-found", o.IgnoreNotFound, "If the requested object does not exist the command will return exit code 0.")
+	cmd.Flags().BoolVar(&o.ShowLabels, "show-labels", o.ShowLabels, "When printing, show all labels as the last column.")
+	cmd.Flags().BoolVar(&o.ShowKind, "show-kind", o.ShowKind, "If present, prefix names with the resource type, e.g. pod/web.")
+	cmd.Flags().StringSliceVarP(&o.LabelColumns, "label-columns", "L", o.LabelColumns, "Accepts a comma separated list of labels that are going to be presented as columns. Names are case-sensitive. You can also use multiple flag options like -L label1 -L label2...")
 	cmd.Flags().StringVar(&o.FieldSelector, "field-selector", o.FieldSelector, "Selector (field query) to filter on, supports '=', '==', and '!='.(e.g. --field-selector key1=value1,key2=value2). The server only supports a limited number of field queries per type.")
 	cmd.Flags().BoolVarP(&o.AllNamespaces, "all-namespaces", "A", o.AllNamespaces, "If present, list the requested object(s) across all namespaces. Namespace in current context is ignored even if specified with --namespace.")
 	cmdutil.AddChunkSizeFlag(cmd, &o.ChunkSize)
@@ -479,28 +539,42 @@ in <root>/<kind>-<group>-<version>/<template>.yaml (falling back to .tpl).`,
 	cmd.Flags().StringSliceVarP(&o.Columns, "columns", "c", nil, "Comma-separated list of column headers to display (subset of the template's columns, case-insensitive).")
 	cmd.Flags().StringVarP(&o.Output, "output", "o", "",
 		"Output format. Native (raw resource, like kubectl): yaml, json, name, wide, jsonpath=..., go-template=... "+
-			"Template-driven (rendered columns as records): csv, template-yaml, template-json. If empty, prints the standard table.")
+			"Template-driven (rendered columns as records): csv, ndjson, template-yaml, template-json. If empty, prints the standard table. "+
+			"custom-columns=SPEC or custom-columns-file=FILE print a table of kubectl-style columns instead of the template's. "+
+			"With --watch: table, csv, ndjson, template-json or template-yaml, one record per event.")
 	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(
-		[]string{"yaml", "json", "name", "wide", "csv", "template-yaml", "template-json", "jsonpath=", "go-template="},
+		[]string{"yaml", "json", "name", "wide", "csv", "ndjson", "template-yaml", "template-json", "jsonpath=", "go-template=", "custom-columns=", "custom-columns-file="},
 		cobra.ShellCompDirectiveNoFileComp))
-	cmd.Flags().StringVar(&o.SortColumn, "sort-by", "", "Column header to sort rows by (case-insensitive). Numeric strings sort numerically.")
-	cmd.Flags().StringArrayVar(&o.FilterExprs, "filter", nil, "Filter rows by column values: COL=val, COL!=val, COL~regex, COL!~regex (repeatable, ANDed).")
+	cmd.Flags().StringVar(&o.SortColumn, "sort-by", "", "Column header to sort rows by (case-insensitive). Numeric strings sort numerically; time and duration columns sort by their underlying value.")
+	cmd.Flags().StringArrayVar(&o.FilterExprs, "filter", nil, "Filter rows by column values: COL=val, COL!=val, COL~regex, COL!~regex, COL<val, COL<=val, COL>val, COL>=val (repeatable, ANDed). Ordering compares time columns by time, e.g. AGE>2d.")
+	cmd.Flags().StringVar(&o.TimeFormat, "time-format", "", "Override the format of every timestamp column: age, relative, rfc3339, local or unix.")
+	_ = cmd.RegisterFlagCompletionFunc("time-format", cobra.FixedCompletions(
+		[]string{"age", "relative", "rfc3339", "local", "unix"}, cobra.ShellCompDirectiveNoFileComp))
 	cmd.Flags().StringVarP(&o.Namespace, "namespace", "n", "", "If present, the namespace scope for this CLI request.")
 	cmd.Flags().StringVar(&o.Context, "context", "", "The name of the kubeconfig context to use.")
 	_ = cmd.RegisterFlagCompletionFunc("context", completions.KubeContexts)
 	cmd.Flags().BoolVar(&o.EnableCustomTable, "ctable", false, "Enable custom table output with borders.")
+	cmd.Flags().BoolVar(&o.Strict, "strict", false, "Abort on the first cell that fails to render instead of showing a placeholder (useful in CI).")
+	cmd.Flags().DurationVar(&o.CellTimeout, "cell-timeout", 5*time.Second, "Maximum time to render one template cell (covers lookup and probeCheck calls). A cell over budget renders as <timeout>. 0 disables the limit.")
+	cmd.Flags().DurationVar(&o.RenderTimeout, "render-timeout", 0, "Maximum time to render the whole table (or each watch event). Cells still pending when it expires render as <timeout>. 0 disables the limit.")
+	cmd.Flags().BoolVar(&o.ProbeExec, "probe-exec", false, "Allow probeCheck to run exec probes inside containers via the pods/exec subresource.")
+	cmd.Flags().IntVar(&o.ProbeConcurrency, "probe-concurrency", 8, "Maximum number of probeCheck/serviceCheck calls in flight across all rows.")
+	cmd.Flags().StringVar(&o.ErrorPlaceholder, "error-placeholder", defaultErrorPlaceholder, "Text rendered in place of a cell whose template or JSONPath evaluation failed (ignored with --strict).")
 	return cmd
 }
 
-func multipleGVKsRequested(infos []*resource.Info) bool {
-	if len(infos) < 2 {
-		return false
+// getArgs requires a resource type unless --raw is given.
+func getArgs(cmd *cobra.Command, args []string) error {
+	if raw, _ := cmd.Flags().GetString("raw"); raw != "" {
+		return nil
 	}
-	gvk := infos[0].Mapping.GroupVersionKind
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+func infoObjects(infos []*resource.Info) []runtime.Object {
+	objs := make([]runtime.Object, 0, len(infos))
 	for _, info := range infos {
-		if info.Mapping.GroupVersionKind != gvk {
-			return true
-		}
+		objs = append(objs, info.Object)
 	}
-	return false
+	return objs
 }
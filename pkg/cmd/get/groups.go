@@ -0,0 +1,81 @@
+package get
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"github.com/kubectl-cwide/pkg/models"
+)
+
+// templateGroup is the objects of one kind that render with one template.
+type templateGroup struct {
+	printer *CustomColumnsPrinter
+	objs    []runtime.Object
+}
+
+// groupByTemplate splits the objects of infos by kind and effective
+// template (see templateFor), in order of first appearance, and creates
+// the printer of each group.
+func (o *GetOptions) groupByTemplate(infos []*resource.Info) ([]*templateGroup, error) {
+	var groups []*templateGroup
+	index := map[string]*templateGroup{}
+	for _, info := range infos {
+		gvk := info.Object.GetObjectKind().GroupVersionKind()
+		name := o.effectiveTemplate(gvk, o.templateFor(info.Mapping, info.Object))
+		key := gvk.String() + "/" + name
+		g, ok := index[key]
+		if !ok {
+			namespaced := info.Mapping != nil && info.Mapping.Scope.Name() == meta.RESTScopeNameNamespace
+			printer, err := o.createPrinter(gvk, namespaced, name)
+			if err != nil {
+				return nil, err
+			}
+			g = &templateGroup{printer: printer}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.objs = append(g.objs, info.Object)
+	}
+	return groups, nil
+}
+
+// templateFor returns the template for obj: the template of the first rule
+// in config.yaml's templateRules that matches it, or o.Template. Rules only
+// apply when --template is not given.
+func (o *GetOptions) templateFor(mapping *meta.RESTMapping, obj runtime.Object) string {
+	if o.templateRules == nil {
+		return o.Template
+	}
+	var target models.RuleTarget
+	if mapping != nil {
+		target.Kind = mapping.GroupVersionKind.Kind
+		target.Resource = mapping.Resource.Resource
+	}
+	if obj != nil {
+		target.Kind = obj.GetObjectKind().GroupVersionKind().Kind
+		if acc, err := meta.Accessor(obj); err == nil {
+			target.Namespace = acc.GetNamespace()
+			target.Labels = acc.GetLabels()
+			target.Annotations = acc.GetAnnotations()
+		}
+	}
+	if name, ok := o.templateRules.ResolveRuleTemplate(target); ok {
+		return name
+	}
+	return o.Template
+}
+
+// effectiveTemplate returns name if kind gvk has a template by that name,
+// in any template layer or API version, and o.Template otherwise: a rule
+// may name a template that only some kinds have.
+func (o *GetOptions) effectiveTemplate(gvk schema.GroupVersionKind, name string) string {
+	if name == o.Template {
+		return name
+	}
+	if _, ok := o.templateLayers.Resolve(gvk, name); ok {
+		return name
+	}
+	return o.Template
+}
@@ -0,0 +1,164 @@
+package get
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+
+	"github.com/kubectl-cwide/pkg/models"
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+func rulePod(namespace string, labels, annotations map[string]interface{}) runtime.Object {
+	md := map[string]interface{}{"name": "p", "namespace": namespace}
+	if labels != nil {
+		md["labels"] = labels
+	}
+	if annotations != nil {
+		md["annotations"] = annotations
+	}
+	return testObj(map[string]interface{}{"metadata": md})
+}
+
+func TestTemplateFor(t *testing.T) {
+	o := &GetOptions{Template: "default", templateRules: &models.Config{TemplateRules: []models.TemplateRule{
+		{Template: "helm", Labels: map[string]string{"app.kubernetes.io/managed-by": "Helm"}},
+		{Template: "gpu", Annotations: map[string]string{"cwide.io/template": "gpu"}},
+		{Template: "charts", Annotations: map[string]string{"cwide.io/source": "https://git.example.com/*/charts"}},
+		{Template: "team", Namespaces: []string{"team-*"}},
+		{Template: "deploys", Kinds: []string{"deployments"}},
+	}}}
+	podMapping := &meta.RESTMapping{
+		GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+		Resource:         schema.GroupVersionResource{Version: "v1", Resource: "pods"},
+	}
+
+	cases := []struct {
+		name string
+		obj  runtime.Object
+		want string
+	}{
+		{"helm label", rulePod("default", map[string]interface{}{"app.kubernetes.io/managed-by": "Helm"}, nil), "helm"},
+		{"gpu annotation", rulePod("default", nil, map[string]interface{}{"cwide.io/template": "gpu"}), "gpu"},
+		{"glob across slashes", rulePod("default", nil, map[string]interface{}{"cwide.io/source": "https://git.example.com/platform/infra/charts"}), "charts"},
+		{"glob suffix must match", rulePod("default", nil, map[string]interface{}{"cwide.io/source": "https://git.example.com/platform/charts/web"}), "default"},
+		{"team namespace", rulePod("team-a", nil, nil), "team"},
+		{"first rule wins", rulePod("team-a", map[string]interface{}{"app.kubernetes.io/managed-by": "Helm"}, nil), "helm"},
+		{"label value must match", rulePod("default", map[string]interface{}{"app.kubernetes.io/managed-by": "argocd"}, nil), "default"},
+		{"no match", rulePod("default", nil, nil), "default"},
+	}
+	for _, c := range cases {
+		if got := o.templateFor(podMapping, c.obj); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	deployMapping := &meta.RESTMapping{
+		GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Resource:         schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+	}
+	if got := o.templateFor(deployMapping, nil); got != "deploys" {
+		t.Errorf("kind rule: got %q", got)
+	}
+
+	o.templateRules = nil
+	if got := o.templateFor(podMapping, rulePod("team-a", nil, nil)); got != "default" {
+		t.Errorf("explicit --template: got %q", got)
+	}
+}
+
+func TestEffectiveTemplate(t *testing.T) {
+	root := t.TempDir()
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	dir := filepath.Join(root, utils.GenerateDirNameByGVK(gvk))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "helm.yaml"), []byte("columns: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o := &GetOptions{Template: "default", templateLayers: utils.TemplateSearchPath{utils.DirLayer(utils.LayerUser, root)}}
+	if got := o.effectiveTemplate(gvk, "helm"); got != "helm" {
+		t.Errorf("existing template: got %q", got)
+	}
+	if got := o.effectiveTemplate(gvk, "gpu"); got != "default" {
+		t.Errorf("missing template should fall back: got %q", got)
+	}
+}
+
+func TestRenderGroups(t *testing.T) {
+	groups := []rowGroup{
+		{headers: []string{"NAME", "STATUS"}, rows: textRows([][]string{{"web", "Running"}})},
+		{headers: []string{"NAME", "CHART"}, rows: textRows([][]string{{"db", "postgres-12"}})},
+	}
+
+	var buf bytes.Buffer
+	if err := renderGroups(&buf, "table", groups); err != nil {
+		t.Fatal(err)
+	}
+	want := "NAME   STATUS\nweb    Running\n\nNAME   CHART\ndb     postgres-12\n"
+	if buf.String() != want {
+		t.Errorf("table:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := renderGroups(&buf, "template-json", groups); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "[\n") || strings.Count(buf.String(), `"NAME"`) != 2 || !strings.Contains(buf.String(), `"CHART": "postgres-12"`) {
+		t.Errorf("template-json should merge groups into one list:\n%s", buf.String())
+	}
+}
+
+func TestPrintGroupsWritesToOut(t *testing.T) {
+	group := func(header, field, name string) *templateGroup {
+		printer, err := NewCustomColumnsPrinterFromYAML([]byte("columns:\n  - header: "+header+"\n    fieldSpec: "+field+"\n"), testDecoder(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj := testObj(map[string]interface{}{"metadata": map[string]interface{}{"name": name, "namespace": "prod"}})
+		return &templateGroup{printer: printer, objs: []runtime.Object{obj}}
+	}
+	var out, errOut bytes.Buffer
+	o := &GetOptions{IOStreams: genericiooptions.IOStreams{Out: &out, ErrOut: &errOut}}
+	if err := o.printGroups(context.Background(), []*templateGroup{group("NAME", ".metadata.name", "web"), group("NAMESPACE", ".metadata.namespace", "db")}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "NAME\nweb\n\nNAMESPACE\nprod\n"; out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestResolveTemplatePrinterOtherVersion(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "horizontalpodautoscaler-autoscaling-v2beta2")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "default.yaml"), []byte("columns:\n  - header: NAME\n    fieldSpec: .metadata.name\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layers := utils.TemplateSearchPath{utils.DirLayer(utils.LayerUser, root)}
+	gvk := schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"}
+
+	var warn bytes.Buffer
+	if _, err := ResolveTemplatePrinter(layers, gvk, "default", testDecoder(), nil, &warn); err != nil {
+		t.Fatal(err)
+	}
+	want := "Warning: no \"default\" template for autoscaling/v2 HorizontalPodAutoscaler; using the template for v2beta2\n"
+	if warn.String() != want {
+		t.Errorf("got warning %q, want %q", warn.String(), want)
+	}
+
+	if _, err := ResolveTemplatePrinter(layers, gvk.GroupVersion().WithKind("Scale"), "default", testDecoder(), nil, &warn); err == nil {
+		t.Error("resolved a template for a kind with none")
+	}
+}
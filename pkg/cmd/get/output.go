@@ -1,6 +1,7 @@
 package get
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -10,9 +11,12 @@ import (
 	"strconv"
 	"strings"
 
-	"sigs.k8s.io/yaml"
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
 
-	"k8s.io/cli-runtime/pkg/printers"
+	"github.com/kubectl-cwide/pkg/parser"
+	"github.com/kubectl-cwide/pkg/utils"
 )
 
 // renderRows emits the collected rows in the requested format. Note that
@@ -20,20 +24,48 @@ import (
 // this function; the "template-yaml"/"template-json" values below dump the
 // rendered template columns as records rather than the raw resource.
 func renderRows(out io.Writer, format string, headers []string, rows [][]string) error {
+	return renderTypedRows(out, format, headers, textRows(rows))
+}
+
+// Render writes objs through printer, as get would without a cluster:
+// format is "table" or one of the structured -o formats.
+func Render(out io.Writer, printer *CustomColumnsPrinter, objs []runtime.Object, format string) error {
+	if format == "" || format == "table" {
+		w := utils.NewTabWriter(out)
+		for _, obj := range objs {
+			if err := printer.PrintObj(obj, w); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	}
+	var rows []Row
+	sink := printer.RowSink
+	defer func() { printer.RowSink = sink }()
+	printer.RowSink = func(row Row) { rows = append(rows, row) }
+	for _, obj := range objs {
+		if err := printer.PrintObj(obj, io.Discard); err != nil {
+			return err
+		}
+	}
+	return renderTypedRows(out, format, printer.Headers, rows)
+}
+
+// renderTypedRows is renderRows for rendered rows. Structured formats keep
+// each cell's typed value and prefix every record with the identity of the
+// object it was rendered from.
+func renderTypedRows(out io.Writer, format string, headers []string, rows []Row) error {
 	switch strings.ToLower(format) {
-	case "template-json":
-		objs := rowsAsObjects(headers, rows)
+	case "template-json", "template-yaml":
+		return encodeRecords(out, format, rowsAsRecords(headers, rows))
+	case "ndjson":
 		enc := json.NewEncoder(out)
-		enc.SetIndent("", "  ")
-		return enc.Encode(objs)
-	case "template-yaml":
-		objs := rowsAsObjects(headers, rows)
-		data, err := yaml.Marshal(objs)
-		if err != nil {
-			return err
+		for _, rec := range rowsAsRecords(headers, rows) {
+			if err := enc.Encode(rec); err != nil {
+				return err
+			}
 		}
-		_, err = out.Write(data)
-		return err
+		return nil
 	case "csv":
 		w := csv.NewWriter(out)
 		defer w.Flush()
@@ -42,7 +74,8 @@ func renderRows(out io.Writer, format string, headers []string, rows [][]string)
 				return err
 			}
 		}
-		for _, r := range rows {
+		for _, row := range rows {
+			r := row.Cells
 			// pad short rows to header width so csv shape stays regular
 			if len(r) < len(headers) {
 				padded := make([]string, len(headers))
@@ -55,24 +88,99 @@ func renderRows(out io.Writer, format string, headers []string, rows [][]string)
 		}
 		return nil
 	case "table", "":
-		w := printers.GetNewTabWriter(out)
+		w := utils.NewTabWriter(out)
 		defer w.Flush()
 		if len(headers) > 0 {
 			fmt.Fprintln(w, strings.Join(headers, "\t"))
 		}
 		for _, r := range rows {
-			fmt.Fprintln(w, strings.Join(r, "\t"))
+			fmt.Fprintln(w, strings.Join(r.Cells, "\t"))
 		}
 		return nil
 	default:
-		return fmt.Errorf("unsupported output format %q (expected csv, template-json, template-yaml, or table)", format)
+		return fmt.Errorf("unsupported output format %q (expected csv, ndjson, template-json, template-yaml, or table)", format)
 	}
 }
 
+// rowGroup is a group of rows sharing the same columns.
+type rowGroup struct {
+	headers []string
+	rows    []Row
+}
+
+// renderGroups emits groups of rows that have different columns, e.g.
+// rendered with different templates. template-json and template-yaml merge
+// them into one list of records; other formats write one block per group,
+// separated by a blank line.
+func renderGroups(out io.Writer, format string, groups []rowGroup) error {
+	switch strings.ToLower(format) {
+	case "template-json", "template-yaml":
+		records := []record{}
+		for _, g := range groups {
+			records = append(records, rowsAsRecords(g.headers, g.rows)...)
+		}
+		return encodeRecords(out, format, records)
+	}
+	for i, g := range groups {
+		if i > 0 && !strings.EqualFold(format, "ndjson") {
+			fmt.Fprintln(out)
+		}
+		if err := renderTypedRows(out, format, g.headers, g.rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeRecords writes records as one template-json or template-yaml list.
+func encodeRecords(out io.Writer, format string, records []record) error {
+	if strings.EqualFold(format, "template-json") {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	}
+	enc := yamlv3.NewEncoder(out)
+	enc.SetIndent(2)
+	if err := enc.Encode(records); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// textRows wraps plain string rows as Rows without typed values.
+func textRows(rows [][]string) []Row {
+	out := make([]Row, len(rows))
+	for i, r := range rows {
+		out[i] = Row{Cells: r}
+	}
+	return out
+}
+
+// rowCells returns the rendered text of each row.
+func rowCells(rows []Row) [][]string {
+	out := make([][]string, len(rows))
+	for i, r := range rows {
+		out[i] = r.Cells
+	}
+	return out
+}
+
 // filterRows keeps rows where every expression evaluates true.
-// Supported operators: =, ==, !=, ~ (regex match), !~ (regex non-match).
-// Column names are case-insensitive.
+// Supported operators: =, ==, !=, ~ (regex match), !~ (regex non-match),
+// and <, <=, >, >= (ordering). Column names are case-insensitive.
 func filterRows(headers []string, rows [][]string, exprs []string) ([][]string, error) {
+	filtered, err := filterTypedRows(headers, textRows(rows), exprs)
+	if err != nil {
+		return nil, err
+	}
+	return rowCells(filtered), nil
+}
+
+// filterTypedRows is filterRows for rendered rows. Ordering operators
+// compare typed cells by their value, so "AGE>2d" means older than two days
+// and "EXPIRES<2025-01-01" compares timestamps; other cells compare
+// numerically when both sides are numbers, else as text.
+func filterTypedRows(headers []string, rows []Row, exprs []string) ([]Row, error) {
 	preds := make([]filterPredicate, 0, len(exprs))
 	headerIdx := headerIndexMap(headers)
 	for _, e := range exprs {
@@ -83,14 +191,15 @@ func filterRows(headers []string, rows [][]string, exprs []string) ([][]string,
 		preds = append(preds, p)
 	}
 
-	out := make([][]string, 0, len(rows))
+	out := make([]Row, 0, len(rows))
 	for _, r := range rows {
 		keep := true
 		for _, p := range preds {
 			var cell string
-			if p.colIdx < len(r) {
-				cell = r[p.colIdx]
+			if p.colIdx < len(r.Cells) {
+				cell = r.Cells[p.colIdx]
 			}
+			value := r.value(p.colIdx)
 			switch p.op {
 			case "=", "==":
 				if cell != p.value {
@@ -108,6 +217,12 @@ func filterRows(headers []string, rows [][]string, exprs []string) ([][]string,
 				if p.re.MatchString(cell) {
 					keep = false
 				}
+			default:
+				ok, err := p.compare(cell, value)
+				if err != nil {
+					return nil, err
+				}
+				keep = ok
 			}
 			if !keep {
 				break
@@ -129,54 +244,102 @@ func headerIndexMap(headers []string) map[string]int {
 }
 
 type filterPredicate struct {
+	expr   string
 	colIdx int
 	op     string
 	value  string
 	re     *regexp.Regexp
 }
 
+// filterOperators lists the supported operators, longer ones first so that
+// at the same position "<=" wins over "<" and "!=" over "=".
+var filterOperators = []string{"!~", "!=", "==", "<=", ">=", "~", "=", "<", ">"}
+
 func parseFilterExpr(expr string, headerIdx map[string]int) (filterPredicate, error) {
-	// Order matters: match longer operators first.
-	for _, op := range []string{"!~", "!=", "==", "~", "="} {
-		if i := strings.Index(expr, op); i > 0 {
-			col := strings.TrimSpace(expr[:i])
-			val := expr[i+len(op):]
-			idx, ok := headerIdx[strings.ToUpper(col)]
-			if !ok {
-				return filterPredicate{}, fmt.Errorf("unknown column %q in filter %q", col, expr)
-			}
-			p := filterPredicate{colIdx: idx, op: op, value: val}
-			if op == "~" || op == "!~" {
-				re, err := regexp.Compile(val)
-				if err != nil {
-					return filterPredicate{}, fmt.Errorf("bad regex in filter %q: %w", expr, err)
-				}
-				p.re = re
-			}
-			return p, nil
+	// The operator is the leftmost one, so values may contain operator
+	// characters (e.g. NAME~a=b).
+	pos, op := -1, ""
+	for _, candidate := range filterOperators {
+		if i := strings.Index(expr, candidate); i > 0 && (pos < 0 || i < pos) {
+			pos, op = i, candidate
+		}
+	}
+	if pos < 0 {
+		return filterPredicate{}, fmt.Errorf("filter %q must contain =, ==, !=, ~, !~, <, <=, > or >=", expr)
+	}
+	col := strings.TrimSpace(expr[:pos])
+	val := expr[pos+len(op):]
+	idx, ok := headerIdx[strings.ToUpper(col)]
+	if !ok {
+		return filterPredicate{}, fmt.Errorf("unknown column %q in filter %q", col, expr)
+	}
+	p := filterPredicate{expr: expr, colIdx: idx, op: op, value: val}
+	if op == "~" || op == "!~" {
+		re, err := regexp.Compile(val)
+		if err != nil {
+			return filterPredicate{}, fmt.Errorf("bad regex in filter %q: %w", expr, err)
+		}
+		p.re = re
+	}
+	return p, nil
+}
+
+// compare evaluates an ordering predicate against one cell.
+func (p filterPredicate) compare(cell string, value parser.CellValue) (bool, error) {
+	var c int
+	if value.Kind != parser.ValueText {
+		want, err := value.Kind.ParseKey(p.value)
+		if err != nil {
+			return false, fmt.Errorf("filter %q: %w", p.expr, err)
 		}
+		c = cmpFloat(value.Key(), want)
+	} else if a, b, ok := parseFloats(cell, p.value); ok {
+		c = cmpFloat(a, b)
+	} else {
+		c = strings.Compare(cell, p.value)
+	}
+	switch p.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
 	}
-	return filterPredicate{}, fmt.Errorf("filter %q must contain =, ==, !=, ~, or !~", expr)
 }
 
 // sortRows sorts rows in place by the named column. Numeric strings sort
 // numerically; otherwise, lexicographically.
 func sortRows(headers []string, rows [][]string, colName string) error {
+	typed := textRows(rows)
+	if err := sortTypedRows(headers, typed, colName); err != nil {
+		return err
+	}
+	copy(rows, rowCells(typed))
+	return nil
+}
+
+// sortTypedRows is sortRows for rendered rows. Typed cells sort by their
+// value: timestamps chronologically, ages and durations by length.
+func sortTypedRows(headers []string, rows []Row, colName string) error {
 	idx, ok := headerIndexMap(headers)[strings.ToUpper(colName)]
 	if !ok {
 		return fmt.Errorf("unknown sort-by column %q", colName)
 	}
 	sort.SliceStable(rows, func(i, j int) bool {
+		if va, vb := rows[i].value(idx), rows[j].value(idx); va.Kind != parser.ValueText && va.Kind == vb.Kind {
+			return va.Key() < vb.Key()
+		}
 		var a, b string
-		if idx < len(rows[i]) {
-			a = rows[i][idx]
+		if idx < len(rows[i].Cells) {
+			a = rows[i].Cells[idx]
 		}
-		if idx < len(rows[j]) {
-			b = rows[j][idx]
+		if idx < len(rows[j].Cells) {
+			b = rows[j].Cells[idx]
 		}
-		af, aErr := strconv.ParseFloat(a, 64)
-		bf, bErr := strconv.ParseFloat(b, 64)
-		if aErr == nil && bErr == nil {
+		if af, bf, ok := parseFloats(a, b); ok {
 			return af < bf
 		}
 		return a < b
@@ -184,18 +347,143 @@ func sortRows(headers []string, rows [][]string, colName string) error {
 	return nil
 }
 
-func rowsAsObjects(headers []string, rows [][]string) []map[string]string {
-	objs := make([]map[string]string, 0, len(rows))
+func parseFloats(a, b string) (float64, float64, bool) {
+	af, aErr := strconv.ParseFloat(a, 64)
+	bf, bErr := strconv.ParseFloat(b, 64)
+	return af, bf, aErr == nil && bErr == nil
+}
+
+func cmpFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// identityKeys are the object identity fields every structured record
+// starts with, so consumers can join records back to the cluster.
+var identityKeys = []string{"apiVersion", "kind", "namespace", "name", "uid"}
+
+// record is one row of template-json/template-yaml output: the object's
+// identity followed by the columns in template order. It marshals as an
+// ordered mapping.
+type record struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (r *record) set(key string, value interface{}) {
+	if _, ok := r.values[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+	r.values[key] = value
+}
+
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(r.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (r record) MarshalYAML() (interface{}, error) {
+	node := &yamlv3.Node{Kind: yamlv3.MappingNode}
+	for _, k := range r.keys {
+		var value yamlv3.Node
+		if err := value.Encode(r.values[k]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: k}, &value)
+	}
+	return node, nil
+}
+
+// rowsAsRecords builds one record per row. Cells use their typed value
+// when the row has one and their text otherwise. A column whose header
+// equals an identity key takes precedence over the identity field.
+func rowsAsRecords(headers []string, rows []Row) []record {
+	records := make([]record, 0, len(rows))
 	for _, r := range rows {
-		m := make(map[string]string, len(headers))
-		for i, h := range headers {
-			if i < len(r) {
-				m[h] = r[i]
-			} else {
-				m[h] = ""
-			}
+		records = append(records, newRecord(headers, r))
+	}
+	return records
+}
+
+// newRecord builds the record for one row.
+func newRecord(headers []string, r Row) record {
+	rec := record{values: make(map[string]interface{}, len(identityKeys)+len(headers))}
+	columns := make(map[string]bool, len(headers))
+	for _, h := range headers {
+		columns[h] = true
+	}
+	for _, kv := range objectIdentity(r.Object) {
+		if !columns[kv[0]] {
+			rec.set(kv[0], kv[1])
 		}
-		objs = append(objs, m)
 	}
-	return objs
+	for i, h := range headers {
+		switch {
+		case len(r.Values) > 0:
+			rec.set(h, r.value(i).Data)
+		case i < len(r.Cells):
+			rec.set(h, r.Cells[i])
+		default:
+			rec.set(h, "")
+		}
+	}
+	return rec
+}
+
+// objectIdentity returns the identityKeys of obj that are set, in order.
+// Watch events are unwrapped to the object they carry.
+func objectIdentity(obj runtime.Object) [][2]string {
+	if obj == nil {
+		return nil
+	}
+	obj = unwrapEvent(obj)
+	acc, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	apiVersion, kind := obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+	var identity [][2]string
+	for _, kv := range [][2]string{
+		{"apiVersion", apiVersion},
+		{"kind", kind},
+		{"namespace", acc.GetNamespace()},
+		{"name", acc.GetName()},
+		{"uid", string(acc.GetUID())},
+	} {
+		if kv[1] != "" {
+			identity = append(identity, kv)
+		}
+	}
+	return identity
+}
+
+// value returns the typed value of column i, or a zero CellValue.
+func (r Row) value(i int) parser.CellValue {
+	if i < len(r.Values) {
+		return r.Values[i]
+	}
+	return parser.CellValue{}
 }
@@ -2,9 +2,13 @@ package get
 
 import (
 	"bytes"
+	"io"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/kubectl-cwide/pkg/parser"
 )
 
 func TestFilterRowsEquality(t *testing.T) {
@@ -106,3 +110,159 @@ func TestRenderTemplateJSON(t *testing.T) {
 		t.Fatalf("unexpected json: %q", buf.String())
 	}
 }
+
+func TestSortTypedRowsByAge(t *testing.T) {
+	headers := []string{"NAME", "AGE"}
+	// Rendered "3d" sorts before "5m" as text; the values put 5m first.
+	age := func(name, text string, d time.Duration) Row {
+		return Row{Cells: []string{name, text}, Values: []parser.CellValue{{}, {Kind: parser.ValueAge, Duration: d}}}
+	}
+	rows := []Row{age("old", "3d", 72*time.Hour), age("new", "5m", 5*time.Minute), age("mid", "20h", 20*time.Hour)}
+	if err := sortTypedRows(headers, rows, "AGE"); err != nil {
+		t.Fatalf("sort err: %v", err)
+	}
+	if rows[0].Cells[0] != "new" || rows[1].Cells[0] != "mid" || rows[2].Cells[0] != "old" {
+		t.Fatalf("typed sort broken: %v", rows)
+	}
+}
+
+func TestFilterTypedRowsOrdering(t *testing.T) {
+	headers := []string{"NAME", "AGE", "EXPIRES"}
+	expires := func(s string) parser.CellValue {
+		ts, _ := time.Parse(time.RFC3339, s)
+		return parser.CellValue{Kind: parser.ValueTime, Time: ts}
+	}
+	row := func(name, ageText string, age time.Duration, notAfter string) Row {
+		return Row{
+			Cells:  []string{name, ageText, notAfter},
+			Values: []parser.CellValue{{}, {Kind: parser.ValueAge, Duration: age}, expires(notAfter)},
+		}
+	}
+	rows := []Row{
+		row("a", "3d", 72*time.Hour, "2025-01-01T00:00:00Z"),
+		row("b", "5m", 5*time.Minute, "2026-06-01T00:00:00Z"),
+		row("c", "in 2h", -2*time.Hour, "2027-01-01T00:00:00Z"),
+	}
+
+	got, err := filterTypedRows(headers, rows, []string{"AGE>1h"})
+	if err != nil {
+		t.Fatalf("filter err: %v", err)
+	}
+	if len(got) != 1 || got[0].Cells[0] != "a" {
+		t.Fatalf("AGE>1h: got %v", got)
+	}
+
+	got, err = filterTypedRows(headers, rows, []string{"AGE<=0s"})
+	if err != nil {
+		t.Fatalf("filter err: %v", err)
+	}
+	if len(got) != 1 || got[0].Cells[0] != "c" {
+		t.Fatalf("future rows: got %v", got)
+	}
+
+	got, err = filterTypedRows(headers, rows, []string{"EXPIRES<2026-12-31"})
+	if err != nil {
+		t.Fatalf("filter err: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("EXPIRES<2026-12-31: got %v", got)
+	}
+
+	if _, err := filterTypedRows(headers, rows, []string{"AGE>soon"}); err == nil {
+		t.Fatal("want error for unparseable age operand")
+	}
+}
+
+func TestFilterRowsNumericOrdering(t *testing.T) {
+	rows := [][]string{{"9"}, {"10"}, {"100"}}
+	got, err := filterRows([]string{"RESTARTS"}, rows, []string{"RESTARTS>=10"})
+	if err != nil {
+		t.Fatalf("filter err: %v", err)
+	}
+	want := [][]string{{"10"}, {"100"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestRenderTypedRecords(t *testing.T) {
+	yamlTmpl := []byte(`
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: RESTARTS
+    fieldSpec: .status.containerStatuses[0].restartCount
+  - header: READY
+    template: '{{ (index .status.containerStatuses 0).ready }}'
+  - header: IMAGES
+    fieldSpec: .spec.containers[*].image
+  - header: PORT
+    template: '{{ "8080" }}'
+    type: string
+  - header: NODE
+    fieldSpec: .spec.nodeName
+`)
+	printer, err := NewCustomColumnsPrinterFromYAML(yamlTmpl, testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	obj := testObj(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default", "uid": "1234"},
+		"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"image": "nginx"}, map[string]interface{}{"image": "envoy"},
+		}},
+		"status": map[string]interface{}{"containerStatuses": []interface{}{
+			map[string]interface{}{"restartCount": int64(3), "ready": true},
+		}},
+	})
+	var rows []Row
+	printer.RowSink = func(row Row) { rows = append(rows, row) }
+	if err := printer.PrintObj(obj, io.Discard); err != nil {
+		t.Fatalf("PrintObj error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := renderTypedRows(&buf, "template-json", printer.Headers, rows); err != nil {
+		t.Fatalf("template-json render: %v", err)
+	}
+	want := `[
+  {
+    "apiVersion": "v1",
+    "kind": "Pod",
+    "namespace": "default",
+    "name": "web",
+    "uid": "1234",
+    "NAME": "web",
+    "RESTARTS": 3,
+    "READY": true,
+    "IMAGES": [
+      "nginx",
+      "envoy"
+    ],
+    "PORT": "8080",
+    "NODE": null
+  }
+]
+`
+	if buf.String() != want {
+		t.Fatalf("template-json:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := renderTypedRows(&buf, "ndjson", printer.Headers, rows); err != nil {
+		t.Fatalf("ndjson render: %v", err)
+	}
+	if want := `{"apiVersion":"v1","kind":"Pod","namespace":"default","name":"web","uid":"1234","NAME":"web","RESTARTS":3,"READY":true,"IMAGES":["nginx","envoy"],"PORT":"8080","NODE":null}` + "\n"; buf.String() != want {
+		t.Fatalf("ndjson:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := renderTypedRows(&buf, "template-yaml", printer.Headers, rows); err != nil {
+		t.Fatalf("template-yaml render: %v", err)
+	}
+	if !strings.Contains(buf.String(), "- apiVersion: v1\n  kind: Pod\n") || !strings.Contains(buf.String(), "RESTARTS: 3\n") {
+		t.Fatalf("unexpected yaml:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,85 @@
+package get
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// raw requests the --raw URI. A response that decodes as a Kubernetes
+// object or list is rendered through the template for its kind; anything
+// else (and any response with a native -o format) is written as is.
+func (o *GetOptions) raw(ctx context.Context) error {
+	client, err := o.factory.RESTClient()
+	if err != nil {
+		return fmt.Errorf("failed to get REST client: %w", err)
+	}
+	body, err := client.Get().RequestURI(o.Raw).DoRaw(ctx)
+	if err != nil {
+		return err
+	}
+	mapper, err := o.factory.ToRESTMapper()
+	if err != nil {
+		return fmt.Errorf("failed to get REST mapper: %w", err)
+	}
+	return o.printRaw(ctx, body, mapper)
+}
+
+// printRaw renders a --raw response. The objects are grouped as the kind
+// the response decoded as, mapped through mapper, and an empty list is
+// reported like an empty listing.
+func (o *GetOptions) printRaw(ctx context.Context, body []byte, mapper meta.RESTMapper) error {
+	objs, gvk, ok := decodeRaw(body)
+	if !ok || isNativeOutput(o.Output) {
+		_, err := o.Out.Write(body)
+		return err
+	}
+	if len(objs) == 0 {
+		o.noResources()
+		return nil
+	}
+	var mapping *meta.RESTMapping
+	if mapper != nil {
+		// An unknown kind (e.g. an aggregated API missing from discovery)
+		// is still rendered, as if it were cluster-scoped.
+		mapping, _ = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	infos := make([]*resource.Info, 0, len(objs))
+	for _, obj := range objs {
+		if obj.GetObjectKind().GroupVersionKind().Kind == "" {
+			obj.GetObjectKind().SetGroupVersionKind(gvk)
+		}
+		infos = append(infos, &resource.Info{Object: obj, Mapping: mapping})
+	}
+	groups, err := o.groupByTemplate(infos)
+	if err != nil {
+		return err
+	}
+	return o.printGroups(ctx, groups)
+}
+
+// decodeRaw decodes a raw response as an object or a list of objects with
+// apiVersion and kind, returning the objects and the kind they are. An
+// empty list yields no objects and the kind its name implies.
+func decodeRaw(body []byte) ([]runtime.Object, schema.GroupVersionKind, bool) {
+	obj, gvk, err := unstructured.UnstructuredJSONScheme.Decode(body, nil, nil)
+	if err != nil || gvk == nil || gvk.Kind == "" {
+		return nil, schema.GroupVersionKind{}, false
+	}
+	list, ok := obj.(*unstructured.UnstructuredList)
+	if !ok {
+		return []runtime.Object{obj}, *gvk, true
+	}
+	itemKind := gvk.GroupVersion().WithKind(strings.TrimSuffix(gvk.Kind, "List"))
+	objs := make([]runtime.Object, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return objs, itemKind, true
+}
@@ -0,0 +1,55 @@
+package get
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+func TestDecodeRaw(t *testing.T) {
+	objs, gvk, ok := decodeRaw([]byte(`{"apiVersion":"v1","kind":"PodList","metadata":{},"items":[{"metadata":{"name":"a"}},{"metadata":{"name":"b"}}]}`))
+	if !ok || len(objs) != 2 {
+		t.Fatalf("list: ok=%v objs=%d", ok, len(objs))
+	}
+	if want := (schema.GroupVersionKind{Version: "v1", Kind: "Pod"}); gvk != want {
+		t.Errorf("list kind = %v, want %v", gvk, want)
+	}
+	if k := objs[1].GetObjectKind().GroupVersionKind().Kind; k != "Pod" {
+		t.Errorf("item kind = %q", k)
+	}
+
+	objs, gvk, ok = decodeRaw([]byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web"}}`))
+	if !ok || len(objs) != 1 || gvk.Kind != "Deployment" || gvk.Group != "apps" {
+		t.Errorf("object: ok=%v objs=%d gvk=%v", ok, len(objs), gvk)
+	}
+
+	for _, body := range []string{`{"major":"1","minor":"30"}`, `ok`, `[1,2]`} {
+		if _, _, ok := decodeRaw([]byte(body)); ok {
+			t.Errorf("%s decoded as an object", body)
+		}
+	}
+}
+
+func TestPrintRawEmptyList(t *testing.T) {
+	body := []byte(`{"apiVersion":"v1","kind":"PodList","metadata":{},"items":[]}`)
+	for _, ignore := range []bool{false, true} {
+		var out, errOut bytes.Buffer
+		o := &GetOptions{IOStreams: genericiooptions.IOStreams{Out: &out, ErrOut: &errOut}, Namespace: "default", IgnoreNotFound: ignore}
+		if err := o.printRaw(context.Background(), body, nil); err != nil {
+			t.Fatalf("printRaw: %v", err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("ignore=%v: stdout = %q", ignore, out.String())
+		}
+		want := "No resources found in default namespace.\n"
+		if ignore {
+			want = ""
+		}
+		if errOut.String() != want {
+			t.Errorf("ignore=%v: stderr = %q, want %q", ignore, errOut.String(), want)
+		}
+	}
+}
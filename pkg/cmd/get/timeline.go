@@ -0,0 +1,294 @@
+package get
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	yamlv3 "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/kubectl-cwide/pkg/parser"
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+// timelineOutputFormats are the -o values supported with --timeline. table,
+// csv and ndjson write each change as it happens; template-json and
+// template-yaml write the log of every object on exit.
+var timelineOutputFormats = []string{"table", "csv", "ndjson", "template-json", "template-yaml"}
+
+// timelineClock is the layout of the TIME column in table output.
+const timelineClock = "15:04:05"
+
+// timelineChange is one entry of an object's change log: a cell that
+// changed, or the object being added or deleted.
+type timelineChange struct {
+	Time  time.Time `json:"time" yaml:"time"`
+	Event string    `json:"event" yaml:"event"`
+	// Column, From and To are set for changed cells (event MODIFIED).
+	Column string  `json:"column,omitempty" yaml:"column,omitempty"`
+	From   *string `json:"from,omitempty" yaml:"from,omitempty"`
+	To     *string `json:"to,omitempty" yaml:"to,omitempty"`
+}
+
+// values returns the old and new text of a changed cell.
+func (c timelineChange) values() (string, string) {
+	if c.From == nil || c.To == nil {
+		return "", ""
+	}
+	return *c.From, *c.To
+}
+
+// timelineObject is the change log of one object.
+type timelineObject struct {
+	Kind      string           `json:"kind" yaml:"kind"`
+	Namespace string           `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name      string           `json:"name" yaml:"name"`
+	Changes   []timelineChange `json:"changes" yaml:"changes"`
+}
+
+// ref renders the object as kind/name, like kubectl's -o name.
+func (t *timelineObject) ref() string {
+	return strings.ToLower(t.Kind) + "/" + t.Name
+}
+
+// timeline is a watchSink that records every change of every rendered cell.
+// Cells with a typed value are compared by value, so that ages and relative
+// times, whose text moves with the clock, are logged only when the
+// timestamp behind them changes. Volatile columns are ignored.
+type timeline struct {
+	out           io.Writer
+	format        string
+	noHeaders     bool
+	showNamespace bool
+	now           func() time.Time
+
+	// rows holds the last rendered row of each tracked object.
+	rows    map[string]Row
+	objects map[string]*timelineObject
+	// order lists objects by their first change.
+	order []*timelineObject
+
+	table  *utils.TabWriter
+	csv    *csv.Writer
+	headed bool
+}
+
+func newTimeline(out io.Writer, format string, noHeaders, showNamespace bool) *timeline {
+	return &timeline{
+		out:           out,
+		format:        format,
+		noHeaders:     noHeaders,
+		showNamespace: showNamespace,
+		now:           time.Now,
+		rows:          map[string]Row{},
+		objects:       map[string]*timelineObject{},
+		table:         utils.NewTabWriter(out),
+		csv:           csv.NewWriter(out),
+	}
+}
+
+// timeline watches the requested objects and logs how their rendered cells
+// change, until interrupted.
+func (o *GetOptions) timeline(ctx context.Context) error {
+	format := strings.ToLower(o.Output)
+	if format == "" {
+		format = "table"
+	}
+	if !isTimelineOutput(format) {
+		return fmt.Errorf("--timeline does not support -o %s (expected one of %s)", o.Output, strings.Join(timelineOutputFormats, ", "))
+	}
+	return o.runWatch(ctx, newTimeline(o.Out, format, o.NoHeaders, o.AllNamespaces), true)
+}
+
+// list records the initial cells of each object without logging them.
+func (t *timeline) list(_ *watchSource, rows []Row) error {
+	for _, row := range rows {
+		t.rows[timelineKey(row)] = row
+	}
+	return nil
+}
+
+// event logs the cells that changed since the object was last rendered.
+// An object seen for the first time is logged as added if the event says
+// so, and otherwise (e.g. it only now passes --filter) tracked silently.
+func (t *timeline) event(src *watchSource, eventType watch.EventType, rows []Row) error {
+	now := t.now()
+	for _, row := range rows {
+		key := timelineKey(row)
+		prev, tracked := t.rows[key]
+		switch {
+		case eventType == watch.Deleted:
+			delete(t.rows, key)
+			if err := t.record(key, row, timelineChange{Time: now, Event: string(watch.Deleted)}); err != nil {
+				return err
+			}
+		case !tracked:
+			t.rows[key] = row
+			if eventType == watch.Added {
+				if err := t.record(key, row, timelineChange{Time: now, Event: string(watch.Added)}); err != nil {
+					return err
+				}
+			}
+		default:
+			t.rows[key] = row
+			for i, header := range src.printer.Headers {
+				if i >= len(row.Cells) || (i < len(src.printer.Columns) && src.printer.Columns[i].Volatile) || !cellChanged(prev, row, i) {
+					continue
+				}
+				var from string
+				if i < len(prev.Cells) {
+					from = prev.Cells[i]
+				}
+				to := row.Cells[i]
+				change := timelineChange{Time: now, Event: string(watch.Modified), Column: header, From: &from, To: &to}
+				if err := t.record(key, row, change); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// record appends change to the object's log and writes it out in
+// streaming formats.
+func (t *timeline) record(key string, row Row, change timelineChange) error {
+	obj, ok := t.objects[key]
+	if !ok {
+		obj = &timelineObject{}
+		if acc, err := meta.Accessor(row.Object); err == nil {
+			obj.Namespace, obj.Name = acc.GetNamespace(), acc.GetName()
+		}
+		obj.Kind = row.Object.GetObjectKind().GroupVersionKind().Kind
+		t.objects[key] = obj
+		t.order = append(t.order, obj)
+	}
+	obj.Changes = append(obj.Changes, change)
+
+	switch t.format {
+	case "ndjson":
+		rec := record{values: map[string]interface{}{}}
+		rec.set("time", change.Time.UTC().Format(time.RFC3339))
+		rec.set("kind", obj.Kind)
+		if obj.Namespace != "" {
+			rec.set("namespace", obj.Namespace)
+		}
+		rec.set("name", obj.Name)
+		rec.set("event", change.Event)
+		if change.Column != "" {
+			rec.set("column", change.Column)
+			rec.set("from", *change.From)
+			rec.set("to", *change.To)
+		}
+		return json.NewEncoder(t.out).Encode(rec)
+	case "csv":
+		if !t.headed && !t.noHeaders {
+			if err := t.csv.Write([]string{"TIME", "KIND", "NAMESPACE", "NAME", "EVENT", "COLUMN", "FROM", "TO"}); err != nil {
+				return err
+			}
+		}
+		t.headed = true
+		from, to := change.values()
+		if err := t.csv.Write([]string{change.Time.UTC().Format(time.RFC3339), obj.Kind, obj.Namespace, obj.Name, change.Event, change.Column, from, to}); err != nil {
+			return err
+		}
+		t.csv.Flush()
+		return t.csv.Error()
+	case "table":
+		if !t.headed && !t.noHeaders {
+			fmt.Fprintln(t.table, strings.Join(t.namespaced("NAMESPACE", "TIME", "OBJECT", "COLUMN", "CHANGE"), "\t"))
+		}
+		t.headed = true
+		what := change.Event
+		if change.Column != "" {
+			from, to := change.values()
+			what = oneLine(from) + "→" + oneLine(to)
+		}
+		fmt.Fprintln(t.table, strings.Join(t.namespaced(obj.Namespace, change.Time.Local().Format(timelineClock), obj.ref(), change.Column, what), "\t"))
+		return t.table.Flush()
+	}
+	return nil
+}
+
+// close writes the change log of every object in the formats that are not
+// streamed.
+func (t *timeline) close() error {
+	objects := t.order
+	if objects == nil {
+		objects = []*timelineObject{}
+	}
+	switch t.format {
+	case "template-json":
+		enc := json.NewEncoder(t.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(objects)
+	case "template-yaml":
+		enc := yamlv3.NewEncoder(t.out)
+		enc.SetIndent(2)
+		if err := enc.Encode(objects); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+	return nil
+}
+
+// namespaced prepends namespace to cells when objects from several
+// namespaces are shown.
+func (t *timeline) namespaced(namespace string, cells ...string) []string {
+	if !t.showNamespace {
+		return cells
+	}
+	return append([]string{namespace}, cells...)
+}
+
+// cellChanged reports whether cell i of row differs from that of prev. Times
+// and ages are compared by the timestamp they were rendered from and
+// durations by their length; other cells by their text.
+func cellChanged(prev, row Row, i int) bool {
+	if i >= len(prev.Cells) {
+		return true
+	}
+	from, to := prev.value(i), row.value(i)
+	if from.Kind == to.Kind {
+		switch from.Kind {
+		case parser.ValueTime, parser.ValueAge:
+			return !from.Time.Equal(to.Time)
+		case parser.ValueDuration:
+			return from.Duration != to.Duration
+		}
+	}
+	return prev.Cells[i] != row.Cells[i]
+}
+
+// timelineKey identifies the object a row was rendered from.
+func timelineKey(row Row) string {
+	acc, err := meta.Accessor(row.Object)
+	if err != nil {
+		return ""
+	}
+	if uid := string(acc.GetUID()); uid != "" {
+		return uid
+	}
+	return row.Object.GetObjectKind().GroupVersionKind().Kind + "/" + acc.GetNamespace() + "/" + acc.GetName()
+}
+
+// oneLine joins the lines of a multi-line cell.
+func oneLine(s string) string {
+	return strings.ReplaceAll(s, "\n", " ")
+}
+
+func isTimelineOutput(format string) bool {
+	for _, f := range timelineOutputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
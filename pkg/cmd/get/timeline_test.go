@@ -0,0 +1,191 @@
+package get
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// timelineSteps feeds a pod through Pending → Running → Error and a
+// deletion, one minute apart, starting from a listed Pending pod.
+func timelineSteps(t *testing.T, tl *timeline, src *watchSource) {
+	t.Helper()
+	o := &GetOptions{}
+	clock := time.Date(2025, 3, 1, 12, 3, 11, 0, time.UTC)
+	tl.now = func() time.Time { return clock }
+
+	rows, err := o.watchRows(src, watchPod("web", "1", "Pending"))
+	if err != nil {
+		t.Fatalf("watchRows: %v", err)
+	}
+	if err := tl.list(src, rows); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	for _, step := range []struct {
+		eventType watch.EventType
+		obj       runtime.Object
+	}{
+		{watch.Modified, watchPod("web", "2", "Pending")},
+		{watch.Modified, watchPod("web", "3", "Running")},
+		{watch.Modified, watchPod("web", "4", "Error")},
+		{watch.Added, watchPod("db", "5", "Pending")},
+		{watch.Deleted, watchPod("web", "6", "Error")},
+	} {
+		rows, err := o.watchRows(src, step.obj)
+		if err != nil {
+			t.Fatalf("watchRows: %v", err)
+		}
+		if err := tl.event(src, step.eventType, rows); err != nil {
+			t.Fatalf("event: %v", err)
+		}
+		clock = clock.Add(time.Minute)
+	}
+	if err := tl.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
+
+func TestTimelineTable(t *testing.T) {
+	var buf bytes.Buffer
+	tl := newTimeline(&buf, "table", false, false)
+	timelineSteps(t, tl, watchTestSource(t, &buf))
+
+	clock := func(min int) string {
+		return time.Date(2025, 3, 1, 12, min, 11, 0, time.UTC).Local().Format(timelineClock)
+	}
+	want := "TIME       OBJECT    COLUMN   CHANGE\n" +
+		clock(4) + "   pod/web   STATUS   Pending→Running\n" +
+		clock(5) + "   pod/web   STATUS   Running→Error\n" +
+		clock(6) + "   pod/db             ADDED\n" +
+		clock(7) + "   pod/web            DELETED\n"
+	if buf.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestTimelineJSON(t *testing.T) {
+	var buf bytes.Buffer
+	tl := newTimeline(&buf, "template-json", false, false)
+	timelineSteps(t, tl, watchTestSource(t, &buf))
+
+	var got []timelineObject
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+	if len(got) != 2 || got[0].Name != "web" || got[1].Name != "db" {
+		t.Fatalf("objects = %+v", got)
+	}
+	web := got[0].Changes
+	if len(web) != 3 || *web[0].From != "Pending" || *web[1].To != "Error" || web[2].Event != "DELETED" {
+		t.Fatalf("web changes = %s", buf.String())
+	}
+	if strings.Contains(buf.String(), `"from": ""`) {
+		t.Errorf("added/deleted entries should not carry from/to:\n%s", buf.String())
+	}
+}
+
+func TestTimelineCSV(t *testing.T) {
+	var buf bytes.Buffer
+	tl := newTimeline(&buf, "csv", false, false)
+	timelineSteps(t, tl, watchTestSource(t, &buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != "TIME,KIND,NAMESPACE,NAME,EVENT,COLUMN,FROM,TO" {
+		t.Fatalf("header = %q", lines[0])
+	}
+	if lines[1] != "2025-03-01T12:04:11Z,Pod,default,web,MODIFIED,STATUS,Pending,Running" {
+		t.Fatalf("first change = %q", lines[1])
+	}
+	if len(lines) != 5 {
+		t.Fatalf("want 4 changes, got:\n%s", buf.String())
+	}
+}
+
+func TestTimelineComparesAgesByTimestamp(t *testing.T) {
+	printer, err := NewCustomColumnsPrinterFromYAML([]byte(`
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: AGE
+    fieldSpec: .metadata.creationTimestamp
+`), testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	printer.SetClock(func() time.Time { return clock })
+	var buf bytes.Buffer
+	src := &watchSource{printer: printer}
+	tl := newTimeline(&buf, "csv", true, false)
+	pod := watchPod("web", "1", "Running")
+	pod.(*unstructured.Unstructured).SetCreationTimestamp(metav1.NewTime(clock.Add(-3 * time.Minute)))
+	render := func() []Row {
+		rows, err := (&GetOptions{}).watchRows(src, pod)
+		if err != nil {
+			t.Fatalf("watchRows: %v", err)
+		}
+		return rows
+	}
+	tl.list(src, render())
+
+	// A minute later the age reads 4m, but it is the same timestamp.
+	clock = clock.Add(time.Minute)
+	if err := tl.event(src, watch.Modified, render()); err != nil {
+		t.Fatalf("event: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("age change logged:\n%s", buf.String())
+	}
+
+	// The object was recreated: the timestamp itself changed.
+	pod.(*unstructured.Unstructured).SetCreationTimestamp(metav1.NewTime(clock))
+	if err := tl.event(src, watch.Modified, render()); err != nil {
+		t.Fatalf("event: %v", err)
+	}
+	if !strings.Contains(buf.String(), ",AGE,4m,0s") {
+		t.Fatalf("want the new timestamp logged, got:\n%s", buf.String())
+	}
+}
+
+func TestTimelineIgnoresVolatileColumns(t *testing.T) {
+	printer, err := NewCustomColumnsPrinterFromYAML([]byte(`
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: STATUS
+    fieldSpec: .status.phase
+  - header: SEEN
+    template: '{{ .metadata.resourceVersion }}'
+    volatile: true
+`), testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	src := &watchSource{printer: printer}
+	tl := newTimeline(&buf, "csv", true, false)
+	o := &GetOptions{}
+	rows, err := o.watchRows(src, watchPod("web", "1", "Pending"))
+	if err != nil {
+		t.Fatalf("watchRows: %v", err)
+	}
+	tl.list(src, rows)
+	rows, err = o.watchRows(src, watchPod("web", "2", "Running"))
+	if err != nil {
+		t.Fatalf("watchRows: %v", err)
+	}
+	if err := tl.event(src, watch.Modified, rows); err != nil {
+		t.Fatalf("event: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], ",STATUS,Pending,Running") {
+		t.Fatalf("want only the STATUS change, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,535 @@
+package get
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	yamlv3 "gopkg.in/yaml.v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+	"k8s.io/kubectl/pkg/util/interrupt"
+
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+// watchOutputFormats are the -o values supported with --watch. Structured
+// formats emit one record per event; table and csv stream rows.
+var watchOutputFormats = []string{"table", "csv", "ndjson", "template-json", "template-yaml"}
+
+// watchSource is one kind (and namespace) being watched. Each kind renders
+// with its own template.
+type watchSource struct {
+	mapping   *meta.RESTMapping
+	helper    *resource.Helper
+	namespace string
+	// names restricts the watch to these objects when specific objects
+	// were requested (by name or with -f). Empty means every object.
+	names map[string]bool
+	// objects is the initial listing.
+	objects []runtime.Object
+	printer *CustomColumnsPrinter
+	// table aligns this source's rows in table output.
+	table  *utils.TabWriter
+	headed bool
+	// widths are the column widths of this source's --ctable table, set
+	// when its first rows are printed.
+	widths []int
+}
+
+// watchEvent is an event received from one source.
+type watchEvent struct {
+	src   *watchSource
+	event watch.Event
+}
+
+// watchSink receives the rows rendered by runWatch.
+type watchSink interface {
+	// list receives the rows of the initial listing of one source.
+	list(src *watchSource, rows []Row) error
+	// event receives the rows rendered for one event.
+	event(src *watchSource, eventType watch.EventType, rows []Row) error
+	// close is called once when the watch ends.
+	close() error
+}
+
+// watch lists the requested objects, then prints every change until
+// interrupted.
+func (o *GetOptions) watch(ctx context.Context) error {
+	format := strings.ToLower(o.Output)
+	if format == "" {
+		format = "table"
+	}
+	if !isWatchOutput(format) {
+		return fmt.Errorf("--watch does not support -o %s (expected one of %s)", o.Output, strings.Join(watchOutputFormats, ", "))
+	}
+	return o.runWatch(ctx, newWatchWriter(o.Out, format, o.OutputWatchEvents, o.NoHeaders), !o.WatchOnly)
+}
+
+// runWatch renders the requested objects and then every change to them
+// into sink, until interrupted. Each kind is watched through an informer,
+// which relists and resumes when the watch expires (410 Gone) or the
+// connection drops; the objects the relist replays are only passed on if
+// they changed. The initial listing is rendered only if listInitial is set.
+func (o *GetOptions) runWatch(ctx context.Context, sink watchSink, listInitial bool) error {
+	r := o.newBuilder().Do()
+	if o.IgnoreNotFound {
+		r.IgnoreErrors(apierrors.IsNotFound)
+	}
+	if err := r.Err(); err != nil {
+		return err
+	}
+	infos, err := r.Infos()
+	if err != nil {
+		return err
+	}
+	sources, err := o.watchSources(infos)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		if !o.IgnoreNotFound {
+			fmt.Fprintf(o.ErrOut, "No resources found in %s namespace.\n", o.Namespace)
+		}
+		return nil
+	}
+
+	seen := seenVersions{}
+	for _, src := range sources {
+		for _, obj := range src.objects {
+			seen.observe(watch.Event{Type: watch.Added, Object: obj})
+		}
+		if !listInitial {
+			continue
+		}
+		cancelRender := o.renderContext(ctx, src.printer)
+		src.printer.PrefetchProbes(src.objects)
+		rows, err := o.watchRows(src, src.objects...)
+		cancelRender()
+		if err != nil {
+			return err
+		}
+		if o.SortColumn != "" {
+			if err := sortTypedRows(src.printer.Headers, rows, o.SortColumn); err != nil {
+				return err
+			}
+		}
+		if err := sink.list(src, rows); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := make(chan watchEvent)
+	for _, src := range sources {
+		_, _, w, done := watchtools.NewIndexerInformerWatcher(src.listWatch(o.LabelSelector, o.FieldSelector), &unstructured.Unstructured{})
+		defer func() { <-done }()
+		defer w.Stop()
+		go forwardEvents(ctx, src, w, events)
+	}
+
+	// An interrupt ends the loop instead of exiting, so that the sink and
+	// the error summary can still write their output.
+	intr := interrupt.New(func(os.Signal) {}, cancel)
+	err = intr.Run(func() error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case e := <-events:
+				eventType, ok := seen.observe(e.event)
+				if !ok || !e.src.wants(e.event.Object) {
+					continue
+				}
+				cancelRender := o.renderContext(ctx, e.src.printer)
+				rows, err := o.watchRows(e.src, e.event.Object)
+				cancelRender()
+				if err != nil {
+					return err
+				}
+				if err := sink.event(e.src, eventType, rows); err != nil {
+					return err
+				}
+			}
+		}
+	})
+	if closeErr := sink.close(); err == nil {
+		err = closeErr
+	}
+	for _, src := range sources {
+		src.printer.Errors.WriteSummary(o.ErrOut)
+	}
+	return err
+}
+
+// watchSources groups the requested objects into one source per kind and
+// namespace, each with the printer for its kind. Lists contribute their
+// items and watch the whole collection; single objects restrict the source
+// to the names requested.
+func (o *GetOptions) watchSources(infos []*resource.Info) ([]*watchSource, error) {
+	var sources []*watchSource
+	index := map[string]*watchSource{}
+	printers := map[string]*CustomColumnsPrinter{}
+	for _, info := range infos {
+		gvk := info.Mapping.GroupVersionKind
+		key := gvk.String() + "/" + info.Namespace
+		src, ok := index[key]
+		if !ok {
+			// Template rules are matched per source (kind and namespace),
+			// not per object.
+			target := &unstructured.Unstructured{}
+			target.SetGroupVersionKind(gvk)
+			target.SetNamespace(info.Namespace)
+			name := o.effectiveTemplate(gvk, o.templateFor(info.Mapping, target))
+			printer, ok := printers[gvk.String()+"/"+name]
+			if !ok {
+				var err error
+				printer, err = o.createPrinter(gvk, info.Mapping.Scope.Name() == meta.RESTScopeNameNamespace, name)
+				if err != nil {
+					return nil, err
+				}
+				if len(o.FilterExprs) > 0 {
+					// Reject bad expressions now rather than at the first event.
+					if _, err := filterTypedRows(printer.Headers, nil, o.FilterExprs); err != nil {
+						return nil, err
+					}
+				}
+				printers[gvk.String()+"/"+name] = printer
+			}
+			src = &watchSource{
+				mapping:   info.Mapping,
+				helper:    resource.NewHelper(info.Client, info.Mapping),
+				namespace: info.Namespace,
+				names:     map[string]bool{},
+				printer:   printer,
+				table:     utils.NewTabWriter(o.Out),
+			}
+			index[key] = src
+			sources = append(sources, src)
+		}
+		if meta.IsListType(info.Object) {
+			items, err := meta.ExtractList(info.Object)
+			if err != nil {
+				return nil, err
+			}
+			src.objects = append(src.objects, items...)
+			continue
+		}
+		src.names[info.Name] = true
+		src.objects = append(src.objects, info.Object)
+	}
+	return sources, nil
+}
+
+// watchRows renders objs with the source's printer and keeps the rows that
+// pass --filter.
+func (o *GetOptions) watchRows(src *watchSource, objs ...runtime.Object) ([]Row, error) {
+	var rows []Row
+	src.printer.RowSink = func(row Row) { rows = append(rows, row) }
+	for _, obj := range objs {
+		if err := src.printer.PrintObj(obj, io.Discard); err != nil {
+			return nil, fmt.Errorf("failed to render row: %w", err)
+		}
+	}
+	if len(o.FilterExprs) == 0 {
+		return rows, nil
+	}
+	return filterTypedRows(src.printer.Headers, rows, o.FilterExprs)
+}
+
+// listWatch lists and watches the source's objects with the given
+// selectors. A source for a single named object selects it by name.
+func (src *watchSource) listWatch(labelSelector, fieldSelector string) *cache.ListWatch {
+	fieldSelector = src.fieldSelector(fieldSelector)
+	apiVersion := src.mapping.GroupVersionKind.GroupVersion().String()
+	selectors := func(options *metav1.ListOptions) {
+		options.LabelSelector = labelSelector
+		options.FieldSelector = fieldSelector
+	}
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			selectors(&options)
+			return src.helper.List(src.namespace, apiVersion, &options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			selectors(&options)
+			return src.helper.Watch(src.namespace, apiVersion, &options)
+		},
+	}
+}
+
+// fieldSelector adds a metadata.name term to selector when the source is a
+// single named object. Several names are filtered client-side by wants.
+func (src *watchSource) fieldSelector(selector string) string {
+	if len(src.names) != 1 {
+		return selector
+	}
+	for name := range src.names {
+		byName := fields.OneTermEqualSelector("metadata.name", name).String()
+		if selector == "" {
+			return byName
+		}
+		return selector + "," + byName
+	}
+	return selector
+}
+
+// wants reports whether obj belongs to the source.
+func (src *watchSource) wants(obj runtime.Object) bool {
+	if len(src.names) == 0 {
+		return true
+	}
+	acc, err := meta.Accessor(obj)
+	return err == nil && src.names[acc.GetName()]
+}
+
+// forwardEvents sends the events of w to events until w stops or ctx ends.
+func forwardEvents(ctx context.Context, src *watchSource, w watch.Interface, events chan<- watchEvent) {
+	for e := range w.ResultChan() {
+		select {
+		case events <- watchEvent{src: src, event: e}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// seenVersions records the resourceVersion last printed for each object,
+// so that neither the initial listing nor the objects an informer replays
+// after a relist are printed twice.
+type seenVersions map[string]string
+
+// observe records e and reports the event type to print it as, or false if
+// it carries nothing new. An object added again with a different version
+// (it changed while the watch was down) is reported as modified.
+func (s seenVersions) observe(e watch.Event) (watch.EventType, bool) {
+	acc, err := meta.Accessor(e.Object)
+	if err != nil {
+		return "", false
+	}
+	key := string(acc.GetUID())
+	if key == "" {
+		key = e.Object.GetObjectKind().GroupVersionKind().Kind + "/" + acc.GetNamespace() + "/" + acc.GetName()
+	}
+	switch e.Type {
+	case watch.Deleted:
+		delete(s, key)
+		return watch.Deleted, true
+	case watch.Added, watch.Modified:
+		rv := acc.GetResourceVersion()
+		prev, ok := s[key]
+		if ok && prev == rv {
+			return "", false
+		}
+		s[key] = rv
+		if ok && e.Type == watch.Added {
+			return watch.Modified, true
+		}
+		return e.Type, true
+	}
+	return "", false
+}
+
+// watchWriter streams watch rows in one output format. Structured formats
+// write one record per row, starting with the event type; table and csv
+// prefix rows with an EVENT column when events is set.
+type watchWriter struct {
+	out       io.Writer
+	format    string
+	events    bool
+	noHeaders bool
+	csv       *csv.Writer
+	yaml      *yamlv3.Encoder
+}
+
+func newWatchWriter(out io.Writer, format string, events, noHeaders bool) *watchWriter {
+	w := &watchWriter{out: out, format: format, events: events, noHeaders: noHeaders}
+	switch format {
+	case "csv":
+		w.csv = csv.NewWriter(out)
+	case "template-yaml":
+		w.yaml = yamlv3.NewEncoder(out)
+		w.yaml.SetIndent(2)
+	}
+	return w
+}
+
+func (w *watchWriter) list(src *watchSource, rows []Row) error {
+	return w.event(src, watch.Added, rows)
+}
+
+func (w *watchWriter) close() error {
+	return nil
+}
+
+// event emits rows rendered by src for an event of the given type.
+func (w *watchWriter) event(src *watchSource, eventType watch.EventType, rows []Row) error {
+	headers := src.printer.Headers
+	switch w.format {
+	case "ndjson", "template-json":
+		// A stream of indented documents can't be read line by line, so
+		// template-json writes one compact record per line, like ndjson.
+		enc := json.NewEncoder(w.out)
+		for _, row := range rows {
+			if err := enc.Encode(eventRecord(eventType, headers, row)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "template-yaml":
+		for _, row := range rows {
+			if err := w.yaml.Encode(eventRecord(eventType, headers, row)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		if !src.headed && !w.noHeaders {
+			if err := w.csv.Write(w.prefix("EVENT", headers)); err != nil {
+				return err
+			}
+		}
+		src.headed = true
+		for _, row := range rows {
+			cells := row.Cells
+			if len(cells) < len(headers) {
+				cells = append(cells, make([]string, len(headers)-len(cells))...)
+			}
+			if err := w.csv.Write(w.prefix(string(eventType), cells)); err != nil {
+				return err
+			}
+		}
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+
+	if src.printer.CustomTable != nil {
+		return w.customTable(src, eventType, rows)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if !src.headed && !w.noHeaders {
+		fmt.Fprintln(src.table, strings.Join(w.prefix("EVENT", headers), "\t"))
+	}
+	src.headed = true
+	for _, row := range rows {
+		writeTabRow(src.table, w.prefix(string(eventType), row.Cells))
+	}
+	return src.table.Flush()
+}
+
+// customTable emits rows as lines of src's --ctable table: the top border
+// and header with the first rows, then only the rows of each event, padded
+// to the column widths of the first. The table is left open, as the stream
+// has no end.
+func (w *watchWriter) customTable(src *watchSource, eventType watch.EventType, rows []Row) error {
+	headers := src.printer.Headers
+	first := src.widths == nil
+	if !first && len(rows) == 0 {
+		return nil
+	}
+	lines := make([][]string, 0, len(rows)+1)
+	if first && !w.noHeaders {
+		lines = append(lines, w.prefix("EVENT", headers))
+	}
+	for _, row := range rows {
+		cells := row.Cells
+		if len(cells) < len(headers) {
+			cells = append(cells, make([]string, len(headers)-len(cells))...)
+		}
+		lines = append(lines, w.prefix(string(eventType), cells))
+	}
+	if first {
+		src.widths = make([]int, len(w.prefix("EVENT", headers)))
+		if w.events {
+			// Wide enough for every event type, whichever comes first.
+			src.widths[0] = len(watch.Modified)
+		}
+		for _, line := range lines {
+			for ix, cell := range line {
+				for _, part := range strings.Split(cell, "\n") {
+					if ix < len(src.widths) && text.RuneWidthWithoutEscSequences(part) > src.widths[ix] {
+						src.widths[ix] = text.RuneWidthWithoutEscSequences(part)
+					}
+				}
+			}
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	t := table.NewWriter()
+	t.SetStyle(*src.printer.CustomTable.Style())
+	configs := make([]table.ColumnConfig, len(src.widths))
+	for ix, width := range src.widths {
+		configs[ix] = table.ColumnConfig{Number: ix + 1, WidthMin: width}
+	}
+	t.SetColumnConfigs(configs)
+	for ix, line := range lines {
+		r := make(table.Row, len(line))
+		for jx, cell := range line {
+			r[jx] = cell
+		}
+		if ix == 0 && first && !w.noHeaders {
+			t.AppendHeader(r)
+		} else {
+			t.AppendRow(r)
+		}
+	}
+	out := strings.Split(t.Render(), "\n")
+	if t.Style().Options.DrawBorder {
+		// Drop the bottom border, and the top one past the first rows.
+		out = out[:len(out)-1]
+		if !first {
+			out = out[1:]
+		}
+	}
+	_, err := fmt.Fprintln(w.out, strings.Join(out, "\n"))
+	return err
+}
+
+// prefix prepends first to cells when event types are shown.
+func (w *watchWriter) prefix(first string, cells []string) []string {
+	if !w.events {
+		return cells
+	}
+	return append([]string{first}, cells...)
+}
+
+// eventRecord is the structured record for one watch event: the event type
+// followed by the row's record.
+func eventRecord(eventType watch.EventType, headers []string, row Row) record {
+	rec := newRecord(headers, row)
+	event := record{values: make(map[string]interface{}, len(rec.keys)+1)}
+	event.set("type", string(eventType))
+	for _, k := range rec.keys {
+		event.set(k, rec.values[k])
+	}
+	return event
+}
+
+func isWatchOutput(format string) bool {
+	for _, f := range watchOutputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
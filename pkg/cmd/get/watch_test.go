@@ -0,0 +1,212 @@
+package get
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+func watchPod(name, rv, phase string) runtime.Object {
+	return testObj(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name": name, "namespace": "default", "uid": "uid-" + name, "resourceVersion": rv,
+		},
+		"status": map[string]interface{}{"phase": phase},
+	})
+}
+
+func TestSeenVersions(t *testing.T) {
+	seen := seenVersions{}
+	seen.observe(watch.Event{Type: watch.Added, Object: watchPod("a", "1", "Running")})
+
+	cases := []struct {
+		name   string
+		event  watch.Event
+		want   watch.EventType
+		wantOK bool
+	}{
+		{"replayed by the informer's initial list", watch.Event{Type: watch.Added, Object: watchPod("a", "1", "Running")}, "", false},
+		{"changed while the watch was down", watch.Event{Type: watch.Added, Object: watchPod("a", "2", "Failed")}, watch.Modified, true},
+		{"relist update without change", watch.Event{Type: watch.Modified, Object: watchPod("a", "2", "Failed")}, "", false},
+		{"new object", watch.Event{Type: watch.Added, Object: watchPod("b", "3", "Pending")}, watch.Added, true},
+		{"deleted", watch.Event{Type: watch.Deleted, Object: watchPod("a", "2", "Failed")}, watch.Deleted, true},
+		{"recreated with the same version", watch.Event{Type: watch.Added, Object: watchPod("a", "2", "Failed")}, watch.Added, true},
+		{"bookmark", watch.Event{Type: watch.Bookmark, Object: watchPod("b", "4", "Pending")}, "", false},
+	}
+	for _, c := range cases {
+		got, ok := seen.observe(c.event)
+		if got != c.want || ok != c.wantOK {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", c.name, got, ok, c.want, c.wantOK)
+		}
+	}
+}
+
+func watchTestSource(t *testing.T, out *bytes.Buffer) *watchSource {
+	t.Helper()
+	printer, err := NewCustomColumnsPrinterFromYAML([]byte(`
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: STATUS
+    fieldSpec: .status.phase
+`), testDecoder(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &watchSource{printer: printer, names: map[string]bool{}, table: utils.NewTabWriter(out)}
+}
+
+func TestWatchRowsFilter(t *testing.T) {
+	src := watchTestSource(t, &bytes.Buffer{})
+	o := &GetOptions{FilterExprs: []string{"STATUS!=Running"}}
+	rows, err := o.watchRows(src, watchPod("a", "1", "Running"), watchPod("b", "1", "Failed"))
+	if err != nil {
+		t.Fatalf("watchRows: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Cells[0] != "b" {
+		t.Fatalf("got %v, want only b", rowCells(rows))
+	}
+}
+
+func TestWatchWriterTableEvents(t *testing.T) {
+	var buf bytes.Buffer
+	src := watchTestSource(t, &buf)
+	w := newWatchWriter(&buf, "table", true, false)
+	o := &GetOptions{}
+
+	for _, step := range []struct {
+		eventType watch.EventType
+		obj       runtime.Object
+	}{
+		{watch.Added, watchPod("web", "1", "Pending")},
+		{watch.Modified, watchPod("web", "2", "Running")},
+		{watch.Deleted, watchPod("web", "3", "Running")},
+	} {
+		rows, err := o.watchRows(src, step.obj)
+		if err != nil {
+			t.Fatalf("watchRows: %v", err)
+		}
+		if err := w.event(src, step.eventType, rows); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	// Rows are flushed as they arrive, so a wider cell widens its column
+	// from that row on.
+	want := "EVENT   NAME   STATUS\n" +
+		"ADDED   web    Pending\n" +
+		"MODIFIED   web    Running\n" +
+		"DELETED    web    Running\n"
+	if buf.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWatchWriterCustomTableEvents(t *testing.T) {
+	var buf bytes.Buffer
+	src := watchTestSource(t, &buf)
+	src.printer.WithCustomTable()
+	w := newWatchWriter(&buf, "table", true, false)
+	o := &GetOptions{}
+
+	for _, step := range []struct {
+		eventType watch.EventType
+		obj       runtime.Object
+	}{
+		{watch.Added, watchPod("web", "1", "Pending")},
+		{watch.Modified, watchPod("web", "2", "Running")},
+		{watch.Deleted, watchPod("web", "3", "Running")},
+	} {
+		rows, err := o.watchRows(src, step.obj)
+		if err != nil {
+			t.Fatalf("watchRows: %v", err)
+		}
+		if err := w.event(src, step.eventType, rows); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	// The header is printed once, and each event adds only its row, at
+	// the widths of the first.
+	want := "+----------+------+---------+\n" +
+		"| EVENT    | NAME | STATUS  |\n" +
+		"+----------+------+---------+\n" +
+		"| ADDED    | web  | Pending |\n" +
+		"| MODIFIED | web  | Running |\n" +
+		"| DELETED  | web  | Running |\n"
+	if buf.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWatchWriterTemplateJSON(t *testing.T) {
+	var buf bytes.Buffer
+	src := watchTestSource(t, &buf)
+	w := newWatchWriter(&buf, "template-json", false, false)
+	rows, err := (&GetOptions{}).watchRows(src, watchPod("web", "1", "Running"), watchPod("db", "1", "Pending"))
+	if err != nil {
+		t.Fatalf("watchRows: %v", err)
+	}
+	if err := w.event(src, watch.Added, rows); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], `{"type":"ADDED"`) {
+		t.Fatalf("want one record per line, got:\n%s", buf.String())
+	}
+}
+
+func TestWatchWriterNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	src := watchTestSource(t, &buf)
+	w := newWatchWriter(&buf, "ndjson", false, false)
+	rows, err := (&GetOptions{}).watchRows(src, watchPod("web", "1", "Running"))
+	if err != nil {
+		t.Fatalf("watchRows: %v", err)
+	}
+	if err := w.event(src, watch.Modified, rows); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	want := `{"type":"MODIFIED","apiVersion":"v1","kind":"Pod","namespace":"default","name":"web","uid":"uid-web","NAME":"web","STATUS":"Running"}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWatchSourceNames(t *testing.T) {
+	src := &watchSource{names: map[string]bool{"web": true}}
+	if !src.wants(watchPod("web", "1", "Running")) || src.wants(watchPod("db", "1", "Running")) {
+		t.Error("named source should only want its objects")
+	}
+	if !(&watchSource{}).wants(watchPod("db", "1", "Running")) {
+		t.Error("collection source should want every object")
+	}
+}
+
+func TestWatchSourceFieldSelector(t *testing.T) {
+	cases := []struct {
+		names    []string
+		selector string
+		want     string
+	}{
+		{nil, "", ""},
+		{nil, "status.phase=Running", "status.phase=Running"},
+		{[]string{"web"}, "", "metadata.name=web"},
+		{[]string{"web"}, "status.phase=Running", "status.phase=Running,metadata.name=web"},
+		{[]string{"web", "db"}, "", ""},
+	}
+	for _, c := range cases {
+		src := &watchSource{names: map[string]bool{}}
+		for _, n := range c.names {
+			src.names[n] = true
+		}
+		if got := src.fieldSelector(c.selector); got != c.want {
+			t.Errorf("names %v, selector %q: got %q, want %q", c.names, c.selector, got, c.want)
+		}
+	}
+}
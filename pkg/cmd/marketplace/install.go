@@ -101,9 +101,17 @@ is specified.`,
 			if err := os.MkdirAll(localDir, 0755); err != nil {
 				return fmt.Errorf("failed to create directory %s: %w", localDir, err)
 			}
-			if err := os.WriteFile(localPath, data, 0644); err != nil {
+			action := "install " + repo
+			if ref != "" {
+				action += "@" + ref
+			}
+			historyErr, err := utils.WriteTemplateVersioned(absPath, remoteDir, templateName, ".yaml", data, action)
+			if err != nil {
 				return fmt.Errorf("failed to write template: %w", err)
 			}
+			if historyErr != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "WARN %s: failed to keep its history: %v\n", localPath, historyErr)
+			}
 
 			// Record the pin (best-effort; a failure here doesn't fail the install).
 			if lf, err := LoadLockFile(); err == nil {
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -77,3 +78,36 @@ func (lf *LockFile) Upsert(p MarketplacePin) {
 	}
 	lf.Pins = append(lf.Pins, p)
 }
+
+// Matches reports whether the pin is for template name in resource
+// directory dir of the template root.
+func (p MarketplacePin) Matches(dir, name string) bool {
+	return p.Template == name && strings.HasPrefix(dir, strings.ToLower(p.Resource)+"-")
+}
+
+// Rename points the pins of template name in directory dir at newName,
+// returning how many it changed.
+func (lf *LockFile) Rename(dir, name, newName string) int {
+	n := 0
+	for i, p := range lf.Pins {
+		if p.Matches(dir, name) {
+			lf.Pins[i].Template = newName
+			n++
+		}
+	}
+	return n
+}
+
+// Remove drops the pins of template name in directory dir, returning how
+// many it dropped.
+func (lf *LockFile) Remove(dir, name string) int {
+	kept := lf.Pins[:0]
+	for _, p := range lf.Pins {
+		if !p.Matches(dir, name) {
+			kept = append(kept, p)
+		}
+	}
+	n := len(lf.Pins) - len(kept)
+	lf.Pins = kept
+	return n
+}
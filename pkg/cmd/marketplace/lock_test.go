@@ -36,3 +36,26 @@ func TestLockFileUpsertDistinguishesByAllKeys(t *testing.T) {
 		t.Fatalf("expected 2 pins, got %d", got)
 	}
 }
+
+func TestLockFileRenameAndRemove(t *testing.T) {
+	lf := &LockFile{Pins: []MarketplacePin{
+		{Repo: "r", Resource: "pod", Template: "debug", Ref: "v1"},
+		{Repo: "r", Resource: "Deployment", Template: "debug", Ref: "v1"},
+		{Repo: "r", Resource: "pod", Template: "other", Ref: "v1"},
+	}}
+	if n := lf.Rename("pod-core-v1", "debug", "triage"); n != 1 {
+		t.Fatalf("expected 1 pin renamed, got %d", n)
+	}
+	if lf.Pins[0].Template != "triage" || lf.Pins[1].Template != "debug" {
+		t.Fatalf("unexpected pins after rename: %+v", lf.Pins)
+	}
+	if n := lf.Remove("deployment-apps-v1", "debug"); n != 1 {
+		t.Fatalf("expected 1 pin removed, got %d", n)
+	}
+	if got := len(lf.Pins); got != 2 {
+		t.Fatalf("expected 2 pins after remove, got %d", got)
+	}
+	if n := lf.Remove("pod-core-v1", "missing"); n != 0 {
+		t.Fatalf("expected no pin removed, got %d", n)
+	}
+}
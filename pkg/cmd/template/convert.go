@@ -0,0 +1,461 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kubectl-cwide/pkg/cmd/get"
+	"github.com/kubectl-cwide/pkg/models"
+	"github.com/kubectl-cwide/pkg/parser"
+)
+
+func NewCmdConvert() *cobra.Command {
+	var to string
+	var check, stdout, force bool
+	cmd := &cobra.Command{
+		Use:   "convert <path>...",
+		Short: "Convert templates between the .tpl and YAML formats",
+		Long: `Convert each template under the paths to the format given by --to, and
+write it next to the source with the other extension. The source is kept:
+within a directory a .yaml template hides the .tpl of the same name, so
+remove the .tpl once the conversion is checked. Directories are searched
+recursively; test cases (tests/) and _shared helpers are skipped.
+
+From .tpl to YAML, a spec that is a Go template ({{ ... }}) becomes the
+template of its column and any other spec its fieldSpec. The lines after the
+spec line, such as define blocks, become the helpers, and the comments that
+stand on their own lines outside define blocks become YAML comments.
+
+From YAML to .tpl, the YAML comments become {{/* */}} comments after the spec
+line. A .tpl has one line of headers and one line of specs and nothing else,
+so conversion fails for templates with funcs, with column options (format,
+default, separator, maxItems, unique, type, volatile) or with headers and specs that do
+not fit in one space-separated field.
+
+Note that a YAML template with helpers of its own does not get the _shared
+helpers, while a .tpl always does; convert warns when a converted template
+calls templates it does not define.
+
+--check writes nothing and exits non-zero if any converted file is missing
+or differs from the conversion, for CI.`,
+		Example: `  # Convert every .tpl template under the template root to YAML
+  kubectl cwide template convert ~/.kubectl-cwide/templates
+
+  # Print the .tpl form of a YAML template
+  kubectl cwide template convert --to tpl --stdout ~/.kubectl-cwide/templates/pod--v1/default.yaml
+
+  # Fail if a .tpl template has no up-to-date YAML conversion
+  kubectl cwide template convert --check templates/`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var from string
+			var convert func([]byte) ([]byte, []string, error)
+			switch to {
+			case "yaml":
+				from, convert = ".tpl", tplToYAML
+			case "tpl":
+				from, convert = ".yaml", yamlToTPL
+			default:
+				return fmt.Errorf("unknown --to %q (want yaml or tpl)", to)
+			}
+			files, err := templateFiles(args, from)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 && !check {
+				return fmt.Errorf("no %s templates found under %s", from, strings.Join(args, ", "))
+			}
+			failed, stale := 0, 0
+			for _, path := range files {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				out, warnings, err := convert(data)
+				if err != nil {
+					failed++
+					fmt.Fprintf(cmd.ErrOrStderr(), "FAIL %s: %v\n", path, err)
+					continue
+				}
+				for _, w := range warnings {
+					fmt.Fprintf(cmd.ErrOrStderr(), "WARN %s: %s\n", path, w)
+				}
+				if stdout {
+					if _, err := cmd.OutOrStdout().Write(out); err != nil {
+						return err
+					}
+					continue
+				}
+				target := strings.TrimSuffix(path, filepath.Ext(path)) + "." + to
+				current, err := os.ReadFile(target)
+				switch {
+				case err == nil && bytes.Equal(current, out):
+					continue
+				case err != nil && !os.IsNotExist(err):
+					return err
+				case check:
+					stale++
+					fmt.Fprintf(cmd.OutOrStdout(), "%s -> %s\n", path, target)
+				case err == nil && !force:
+					failed++
+					fmt.Fprintf(cmd.ErrOrStderr(), "FAIL %s: %s exists and differs; use --force to overwrite it\n", path, target)
+				default:
+					if err := os.WriteFile(target, out, 0644); err != nil {
+						return err
+					}
+					fmt.Fprintf(cmd.OutOrStdout(), "%s -> %s\n", path, target)
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d template(s) could not be converted", failed)
+			}
+			if stale > 0 {
+				return fmt.Errorf("%d template(s) not converted", stale)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&to, "to", "yaml", "The format to convert to: yaml or tpl")
+	cmd.Flags().BoolVar(&check, "check", false, "Write nothing; exit non-zero if a conversion is missing or out of date")
+	cmd.Flags().BoolVar(&stdout, "stdout", false, "Print the conversions instead of writing them")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite converted files that differ")
+	return cmd
+}
+
+// templateFiles returns the files with extension ext under paths, sorted.
+// Paths that are files are returned whatever their extension. Test cases,
+// shared helpers and hidden directories are skipped.
+func templateFiles(paths []string, ext string) ([]string, error) {
+	var files []string
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, root)
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if name := d.Name(); path != root && (name == "tests" || name == "_shared" || strings.HasPrefix(name, ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if filepath.Ext(path) == ext {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// tplTemplate is a .tpl template: a line of headers, a line of specs, and
+// the rest of the file, its helpers.
+type tplTemplate struct {
+	headers []string
+	specs   []string
+	rest    string
+}
+
+func parseTPL(data []byte) (*tplTemplate, error) {
+	lines := strings.SplitN(string(data), "\n", 3)
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("tpl needs at least a header line and a spec line")
+	}
+	t := &tplTemplate{
+		headers: get.SplitIgnoringTemplateSpaces(lines[0]),
+		specs:   get.SplitIgnoringTemplateSpaces(lines[1]),
+	}
+	if len(t.headers) != len(t.specs) {
+		return nil, fmt.Errorf("%d header(s) but %d field spec(s)", len(t.headers), len(t.specs))
+	}
+	if len(lines) == 3 {
+		t.rest = lines[2]
+	}
+	return t, nil
+}
+
+// tplColumn returns the column of a .tpl header and spec: a template
+// column if spec is a Go template, a JSONPath column otherwise.
+func tplColumn(header, spec string) models.YAMLColumn {
+	if parser.IsTemplate(spec) {
+		return models.YAMLColumn{Header: header, Template: spec}
+	}
+	return models.YAMLColumn{Header: header, FieldSpec: spec}
+}
+
+// alignTPL returns the header and spec lines of a .tpl with the fields of
+// each column padded to the same width.
+func alignTPL(headers, specs []string) string {
+	var header, spec strings.Builder
+	for i := range headers {
+		if i < len(headers)-1 {
+			width := len(headers[i])
+			if len(specs[i]) > width {
+				width = len(specs[i])
+			}
+			fmt.Fprintf(&header, "%-*s ", width, headers[i])
+			fmt.Fprintf(&spec, "%-*s ", width, specs[i])
+		} else {
+			header.WriteString(headers[i])
+			spec.WriteString(specs[i])
+		}
+	}
+	return header.String() + "\n" + spec.String() + "\n"
+}
+
+// tplToYAML converts the .tpl template data to a YAML template in the
+// layout of fmt. The warnings are about differences in how get renders the
+// two.
+func tplToYAML(data []byte) ([]byte, []string, error) {
+	t, err := parseTPL(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	helpers, comments := splitTPLComments(t.rest)
+	helpers = strings.TrimRight(strings.TrimLeft(helpers, "\n"), " \t\n")
+
+	str := func(v string) *yaml.Node { return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v} }
+	columns := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	var templates []string
+	for i, h := range t.headers {
+		key := "fieldSpec"
+		if parser.IsTemplate(t.specs[i]) {
+			key = "template"
+			templates = append(templates, t.specs[i])
+		}
+		columns.Content = append(columns.Content, &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+			str("header"), str(h), str(key), str(t.specs[i]),
+		}})
+	}
+	columnsKey := str("columns")
+	for _, c := range comments {
+		for _, line := range strings.Split(c, "\n") {
+			columnsKey.HeadComment += strings.TrimRight("# "+line, " ") + "\n"
+		}
+	}
+	columnsKey.HeadComment = strings.TrimSuffix(columnsKey.HeadComment, "\n")
+	root := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{columnsKey, columns}}
+
+	var warnings []string
+	if helpers != "" {
+		root.Content = append(root.Content, str("helpers"), str(helpers+"\n"))
+		for _, name := range undefinedTemplates(helpers, templates) {
+			warnings = append(warnings, fmt.Sprintf("calls template %q, which it does not define; a YAML template with helpers does not get the _shared helpers", name))
+		}
+	}
+	out, err := encodeYAMLTemplate(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}})
+	return out, warnings, err
+}
+
+// splitTPLComments removes from helpers the comments that stand on their
+// own lines outside define blocks, and returns the rest of helpers and the
+// text of the comments. Helpers that do not parse are returned as they are.
+func splitTPLComments(helpers string) (string, []string) {
+	tree := parse.New("helpers")
+	tree.Mode = parse.ParseComments | parse.SkipFuncCheck
+	if _, err := tree.Parse(helpers, "", "", map[string]*parse.Tree{}); err != nil || tree.Root == nil {
+		return helpers, nil
+	}
+	var comments []string
+	var cut [][2]int
+	for _, n := range tree.Root.Nodes {
+		c, ok := n.(*parse.CommentNode)
+		if !ok {
+			continue
+		}
+		pos := int(c.Pos)
+		start := strings.LastIndex(helpers[:pos], "{{")
+		end := strings.Index(helpers[pos+len(c.Text):], "}}")
+		if start < 0 || end < 0 {
+			continue
+		}
+		end += pos + len(c.Text) + len("}}")
+		lineStart := strings.LastIndex(helpers[:start], "\n") + 1
+		lineEnd := len(helpers)
+		if i := strings.Index(helpers[end:], "\n"); i >= 0 {
+			lineEnd = end + i + 1
+		}
+		if strings.TrimSpace(helpers[lineStart:start]) != "" || strings.TrimSpace(helpers[end:lineEnd]) != "" {
+			continue
+		}
+		text := strings.TrimSuffix(strings.TrimPrefix(c.Text, "/*"), "*/")
+		comments = append(comments, strings.TrimSpace(text))
+		cut = append(cut, [2]int{lineStart, lineEnd})
+	}
+	for i := len(cut) - 1; i >= 0; i-- {
+		helpers = helpers[:cut[i][0]] + helpers[cut[i][1]:]
+	}
+	return helpers, comments
+}
+
+// undefinedTemplates returns the names of the templates that helpers and
+// the column templates call but helpers does not define, sorted.
+func undefinedTemplates(helpers string, columns []string) []string {
+	trees := map[string]*parse.Tree{}
+	var roots []*parse.Tree
+	for i, text := range append([]string{helpers}, columns...) {
+		tree := parse.New(fmt.Sprintf("text%d", i))
+		tree.Mode = parse.SkipFuncCheck
+		if _, err := tree.Parse(text, "", "", trees); err != nil {
+			return nil
+		}
+		roots = append(roots, tree)
+	}
+	calls := map[string]bool{}
+	for _, t := range trees {
+		templateCalls(t.Root, calls)
+	}
+	for _, t := range roots {
+		templateCalls(t.Root, calls)
+	}
+	var names []string
+	for name := range calls {
+		if _, ok := trees[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateCalls adds the names of the templates node calls to calls.
+func templateCalls(node parse.Node, calls map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			templateCalls(c, calls)
+		}
+	case *parse.IfNode:
+		templateCalls(n.List, calls)
+		templateCalls(n.ElseList, calls)
+	case *parse.RangeNode:
+		templateCalls(n.List, calls)
+		templateCalls(n.ElseList, calls)
+	case *parse.WithNode:
+		templateCalls(n.List, calls)
+		templateCalls(n.ElseList, calls)
+	case *parse.TemplateNode:
+		calls[n.Name] = true
+	}
+}
+
+// yamlToTPL converts the YAML template data to a .tpl template. Templates
+// that use what a .tpl cannot express are refused.
+func yamlToTPL(data []byte) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	var tmpl models.YAMLTemplate
+	if err := doc.Decode(&tmpl); err != nil {
+		return nil, nil, err
+	}
+
+	var problems []string
+	if len(tmpl.Columns) == 0 {
+		problems = append(problems, "template has no columns")
+	}
+	if len(tmpl.Funcs) > 0 {
+		problems = append(problems, "a .tpl cannot have funcs")
+	}
+	headers := make([]string, len(tmpl.Columns))
+	specs := make([]string, len(tmpl.Columns))
+	for i, c := range tmpl.Columns {
+		col := fmt.Sprintf("column[%d] (%s)", i, c.Header)
+		for _, option := range []struct {
+			name string
+			set  bool
+		}{
+			{"format", c.Format != ""}, {"default", c.Default != ""}, {"separator", c.Separator != ""},
+			{"maxItems", c.MaxItems != 0}, {"unique", c.Unique}, {"type", c.Type != ""},
+			{"volatile", c.Volatile},
+		} {
+			if option.set {
+				problems = append(problems, fmt.Sprintf("%s: a .tpl column cannot have %s", col, option.name))
+			}
+		}
+		// get renders the template of a column that has both.
+		spec := c.FieldSpec
+		if c.Template != "" {
+			spec = c.Template
+			if !parser.IsTemplate(spec) {
+				problems = append(problems, fmt.Sprintf("%s: a .tpl template must start with {{ and end with }}", col))
+			}
+		}
+		if !fitsTPLField(c.Header) {
+			problems = append(problems, fmt.Sprintf("%s: header %q does not fit in one field of the header line", col, c.Header))
+		}
+		if !fitsTPLField(spec) {
+			problems = append(problems, fmt.Sprintf("%s: %q does not fit in one field of the spec line", col, spec))
+		}
+		headers[i], specs[i] = c.Header, spec
+	}
+	if len(problems) > 0 {
+		return nil, nil, fmt.Errorf("cannot convert to .tpl:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+
+	var b strings.Builder
+	b.WriteString(alignTPL(headers, specs))
+	for _, c := range yamlComments(&doc) {
+		fmt.Fprintf(&b, "{{/* %s */}}\n", strings.ReplaceAll(c, "*/", "* /"))
+	}
+	if helpers := strings.TrimRight(strings.TrimLeft(tmpl.Helpers, "\n"), " \t\n"); helpers != "" {
+		b.WriteString(helpers + "\n")
+	}
+	return []byte(b.String()), nil, nil
+}
+
+// fitsTPLField reports whether s reads back as one field of a .tpl line.
+func fitsTPLField(s string) bool {
+	if s == "" || strings.Contains(s, "\n") {
+		return false
+	}
+	fields := get.SplitIgnoringTemplateSpaces(s)
+	return len(fields) == 1 && fields[0] == s
+}
+
+// yamlComments returns the text of the comments of n and its children, in
+// document order, without their #.
+func yamlComments(n *yaml.Node) []string {
+	var comments []string
+	add := func(c string) {
+		if c == "" {
+			return
+		}
+		lines := strings.Split(c, "\n")
+		for i, line := range lines {
+			line = strings.TrimPrefix(strings.TrimSpace(line), "#")
+			lines[i] = strings.TrimPrefix(line, " ")
+		}
+		comments = append(comments, strings.TrimSpace(strings.Join(lines, "\n")))
+	}
+	add(n.HeadComment)
+	add(n.LineComment)
+	for _, c := range n.Content {
+		comments = append(comments, yamlComments(c)...)
+	}
+	add(n.FootComment)
+	return comments
+}
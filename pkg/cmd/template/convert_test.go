@@ -0,0 +1,128 @@
+package template
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const ownerTPL = `NAME OWNER STATUS
+.metadata.name {{ template "owner" . }} {.status.phase}
+{{/* Owner of the pod. */}}
+{{ define "owner" }}{{/* first owner */}}{{ with index .metadata.ownerReferences 0 }}{{ .name }}{{ end }}{{ end }}
+`
+
+func TestTPLToYAML(t *testing.T) {
+	out, warnings, err := tplToYAML([]byte(ownerTPL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Owner of the pod.
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: OWNER
+    template: '{{ template "owner" . }}'
+  - header: STATUS
+    fieldSpec: '{.status.phase}'
+helpers: |
+  {{ define "owner" }}{{/* first owner */}}{{ with index .metadata.ownerReferences 0 }}{{ .name }}{{ end }}{{ end }}
+`
+	if string(out) != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+
+	back, _, err := yamlToTPL(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatted, err := formatTPL([]byte(ownerTPL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(back, formatted) {
+		t.Errorf("round trip got\n%s\nwant\n%s", back, formatted)
+	}
+
+	_, warnings, err = tplToYAML([]byte("NAME\n{{ template \"shared\" . }}\n{{ define \"x\" }}x{{ end }}\n"))
+	if err != nil || len(warnings) != 1 || !strings.Contains(warnings[0], `"shared"`) {
+		t.Errorf("got %v, %v; want a warning about \"shared\"", warnings, err)
+	}
+}
+
+func TestYAMLToTPLRefused(t *testing.T) {
+	body := `columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: CREATED AT
+    fieldSpec: .metadata.creationTimestamp
+    format: age
+  - header: READY
+    template: '{{ .status.ready }}/{{ .spec.replicas }}'
+funcs:
+  double: '{{ . }}{{ . }}'
+`
+	_, _, err := yamlToTPL([]byte(body))
+	if err == nil {
+		t.Fatal("conversion should have been refused")
+	}
+	for _, want := range []string{
+		"a .tpl cannot have funcs",
+		"column[1] (CREATED AT): a .tpl column cannot have format",
+		`column[1] (CREATED AT): header "CREATED AT" does not fit`,
+		`column[2] (READY): "{{ .status.ready }}/{{ .spec.replicas }}" does not fit`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error lacks %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestConvertCheck(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pod--v1")
+	if err := os.MkdirAll(filepath.Join(dir, "tests", "case"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(dir, "owner.tpl")
+	if err := os.WriteFile(src, []byte(ownerTPL), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := NewCmdConvert()
+		cmd.SetArgs(args)
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SilenceUsage = true
+		err := cmd.Execute()
+		return out.String(), err
+	}
+	target := filepath.Join(dir, "owner.yaml")
+	if out, err := run("--check", dir); err == nil || !strings.Contains(out, target) {
+		t.Fatalf("check of an unconverted template: %v\n%s", err, out)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Fatalf("--check wrote %s", target)
+	}
+	if _, err := run(dir); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := run("--check", dir); err != nil {
+		t.Fatalf("check after converting: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(target, []byte("columns: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(dir); err == nil {
+		t.Fatal("convert overwrote a file that differs without --force")
+	}
+	if _, err := run("--force", dir); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -5,13 +5,19 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/kubectl-cwide/pkg/clients"
 	"github.com/kubectl-cwide/pkg/cmd/completions"
+	"github.com/kubectl-cwide/pkg/parser/funcs"
 	"github.com/kubectl-cwide/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
 func NewCmdEdit() *cobra.Command {
+	var previewMode, force, allNamespaces bool
+	var objectsFile, previewTo, namespace, selector, kubeContext string
+	var limit int
 	editCMD := &cobra.Command{
 		Use:        "edit",
 		Aliases:    []string{"e"},
@@ -22,7 +28,20 @@ editor. The editor is determined by the EDITOR environment variable, falling
 back to vi.
 
 By default the "default" template is opened. Use -t to specify a different
-template name.`,
+template name.
+
+With --preview, the template is rendered, as get renders it, every time it
+is saved, for a set of objects: those of --objects, a file of objects as
+kubectl get -o yaml or -o json writes them, or those the cluster lists for
+the template's kind (-n, -A, -l; at most --limit). The preview is written
+to --preview-to: a second terminal (its tty, as printed by the tty command)
+is cleared before each render, a file is rewritten. Without --preview-to, a
+temporary file is used; follow it with tail -F. A template that does not
+parse or render shows its error in place of the table.
+
+When the editor exits, a previewed template is linted. If it does not
+lint, the previous version is restored and the edit is kept in a temporary
+file, unless --force is given.`,
 		Example: `  # Edit the default template for pods
   kubectl cwide template edit -r pod
 
@@ -30,7 +49,13 @@ template name.`,
   kubectl cwide template edit -r deployment -t minimal
 
   # Edit from a specific directory
-  kubectl cwide template edit -r pod --template-path ~/my-templates`,
+  kubectl cwide template edit -r pod --template-path ~/my-templates
+
+  # Preview the pods of a namespace in a second terminal while editing
+  kubectl cwide template edit -r pod --preview -n default --preview-to /dev/pts/3
+
+  # Preview saved objects, offline
+  kubectl cwide template edit -r deployment --preview --objects deployments.yaml`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			absPath, err := utils.ResolveTemplatePath(cmd)
 			if err != nil {
@@ -77,13 +102,33 @@ template name.`,
 			// overwritten mid-edit (e.g. by a concurrent configmap sync).
 			preStat, preErr := os.Stat(targetPath)
 
+			// The content before the edit, for the template's history and
+			// to restore an edit that does not lint.
+			original, err := os.ReadFile(targetPath)
+			if err != nil {
+				return err
+			}
+			var stopPreview func()
+			if previewMode {
+				if stopPreview, err = startPreview(cmd, absPath, targetPath, previewOptions{
+					objectsFile: objectsFile, previewTo: previewTo, namespace: namespace,
+					allNamespaces: allNamespaces, selector: selector, kubeContext: kubeContext, limit: limit,
+				}); err != nil {
+					return err
+				}
+			}
+
 			editorCmd := exec.Command(editor, targetPath)
 			editorCmd.Stdin = os.Stdin
 			editorCmd.Stdout = os.Stdout
 			editorCmd.Stderr = os.Stderr
 
-			if err := editorCmd.Run(); err != nil {
-				return fmt.Errorf("editor exited with error: %w", err)
+			runErr := editorCmd.Run()
+			if stopPreview != nil {
+				stopPreview()
+			}
+			if runErr != nil {
+				return fmt.Errorf("editor exited with error: %w", runErr)
 			}
 
 			if preErr == nil {
@@ -97,6 +142,15 @@ template name.`,
 						targetPath)
 				}
 			}
+			if previewMode {
+				if err := keepIfLints(cmd, targetPath, original, force); err != nil {
+					return err
+				}
+			}
+			ext := filepath.Ext(targetPath)
+			if err := utils.RecordTemplateVersion(absPath, filepath.Base(files[0]), templateName, ext, original, "edit"); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "WARN %s: failed to keep its history: %v\n", targetPath, err)
+			}
 			return nil
 		},
 	}
@@ -105,6 +159,15 @@ template name.`,
 	_ = editCMD.RegisterFlagCompletionFunc("resource", completions.ResourceTypes)
 	editCMD.Flags().StringP("template", "t", "default", "Name of the template to edit (without extension)")
 	_ = editCMD.RegisterFlagCompletionFunc("template", completions.TemplateNames)
+	editCMD.Flags().BoolVar(&previewMode, "preview", false, "Render the template every time it is saved")
+	editCMD.Flags().StringVar(&objectsFile, "objects", "", "Preview the objects of a file instead of the cluster's")
+	editCMD.Flags().StringVar(&previewTo, "preview-to", "", "Terminal or file to write the preview to (default: a temporary file)")
+	editCMD.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace of the objects to preview")
+	editCMD.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Preview objects across all namespaces")
+	editCMD.Flags().StringVarP(&selector, "selector", "l", "", "Label selector of the objects to preview")
+	editCMD.Flags().IntVar(&limit, "limit", 20, "Most objects to preview")
+	editCMD.Flags().BoolVar(&force, "force", false, "With --preview, keep an edit that does not lint")
+	editCMD.Flags().StringVar(&kubeContext, "context", "", "The name of the kubeconfig context to list the previewed objects from")
 	editCMD.MarkFlagRequired("resource")
 
 	return editCMD
@@ -114,3 +177,88 @@ func fileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+// previewOptions are the flags of edit --preview.
+type previewOptions struct {
+	objectsFile, previewTo           string
+	namespace, selector, kubeContext string
+	allNamespaces                    bool
+	limit                            int
+}
+
+// startPreview renders the template at path, in template root root, and
+// again every time it is saved, until the returned function is called.
+func startPreview(cmd *cobra.Command, root, path string, opts previewOptions) (func(), error) {
+	if opts.limit < 1 {
+		return nil, fmt.Errorf("--limit must be at least 1")
+	}
+	dir := filepath.Base(filepath.Dir(path))
+	p := &preview{path: path, root: root, dir: dir, name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	objs, err := previewObjects(opts.objectsFile, func() ([]map[string]interface{}, error) {
+		resource, err := previewResource(dir)
+		if err != nil {
+			return nil, err
+		}
+		if p.restConfig, err = clients.FactoryFromCmd(cmd, opts.kubeContext).ToRESTConfig(); err != nil {
+			return nil, err
+		}
+		return listObjects(cmd, opts.kubeContext, resource, opts.namespace, opts.allNamespaces, opts.selector)
+	}, opts.limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the objects to preview: %w", err)
+	}
+	p.objs = objs
+	if p.sink, err = newPreviewSink(opts.previewTo); err != nil {
+		return nil, err
+	}
+	// Escape codes would be noise in a file.
+	if !p.sink.terminal {
+		funcs.SetColorDisabled(true)
+	}
+	if opts.previewTo == "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Previewing %d object(s) in %s; follow it with: tail -F %s\n", len(objs), p.sink.path, p.sink.path)
+	}
+	p.update()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := p.watch(path, stop); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "WARN preview: %v\n", err)
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}, nil
+}
+
+// keepIfLints lints the template at path after an edit. If it does not
+// lint and force is not set, original is written back and the edit is
+// saved to a temporary file.
+func keepIfLints(cmd *cobra.Command, path string, original []byte, force bool) error {
+	lintErr := lintOne(cmd, path, nil)
+	if lintErr == nil || force {
+		return nil
+	}
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp("", "cwide-edit-*"+filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(edited); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, original, 0644); err != nil {
+		return err
+	}
+	return fmt.Errorf("template %s does not lint (%v); restored the previous version, the edit is saved in %s; use --force to keep an edit that does not lint", path, lintErr, f.Name())
+}
@@ -0,0 +1,400 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	sigsyaml "sigs.k8s.io/yaml"
+
+	"github.com/kubectl-cwide/pkg/cmd/get"
+	"github.com/kubectl-cwide/pkg/common"
+	"github.com/kubectl-cwide/pkg/models"
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+// exportFormats are the formats template export writes.
+var exportFormats = []string{"kubectl", "k9s", "crd"}
+
+func NewCmdExport() *cobra.Command {
+	var format, plural, crdFile string
+	var strict bool
+	cmd := &cobra.Command{
+		Use:   "export <template-file>",
+		Short: "Write a template's columns for kubectl, k9s or a CRD",
+		Long: `Write the columns of a .yaml or .tpl template to stdout in the format of
+another tool, given by --format:
+  kubectl   a kubectl custom-columns-file: kubectl get -o custom-columns-file=FILE
+  k9s       a views.yaml entry of k9s, keyed by the template's resource
+  crd       a JSON patch that sets the additionalPrinterColumns of the
+            template's version in its CustomResourceDefinition:
+            kubectl patch crd NAME --type=json --patch-file=FILE
+
+Only JSONPath columns can be exported. Every column that is not exported,
+such as Go template columns, is reported on stderr as SKIP, and every column
+exported without an option the format cannot express (a format, default,
+separator, maxItems or unique) as WARN. --strict makes either an error.
+
+The kind is taken from the template's directory (<kind>-<group>-<version>).
+The resource name k9s and CRDs use is the kind's plural, guessed from the
+kind unless --plural or --crd gives it. --crd reads the CRD manifest to find
+the plural and the index of the template's version in spec.versions; without
+it the patch sets the first version, which may not be the template's: that
+is reported as WARN, so --strict requires --crd.`,
+		Example: `  # Use a template's columns with plain kubectl
+  kubectl cwide template export --format kubectl ~/.kubectl-cwide/templates/pod--v1/default.yaml > pod.cols
+  kubectl get pods -o custom-columns-file=pod.cols
+
+  # Add a template's columns to k9s
+  kubectl cwide template export --format k9s ~/.kubectl-cwide/templates/deployment-apps-v1/default.yaml >> ~/.config/k9s/views.yaml
+
+  # Ship a template's columns with a CRD
+  kubectl cwide template export --format crd --crd config/crd/widgets.yaml templates/widget-example.com-v1/default.yaml > printer-columns.yaml`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := args[0]
+			columns, err := loadTemplateColumns(path)
+			if err != nil {
+				return err
+			}
+			var gvk schema.GroupVersionKind
+			if format != "kubectl" {
+				dir := filepath.Base(filepath.Dir(path))
+				var ok bool
+				if gvk, ok = utils.ParseTemplateDirName(dir); !ok || gvk.Version == "" {
+					return fmt.Errorf("directory %q is not named <kind>-<group>-<version>", dir)
+				}
+			}
+			target := &exportTarget{gvk: gvk, plural: plural}
+			if crdFile != "" {
+				if err := target.readCRD(crdFile); err != nil {
+					return err
+				}
+			}
+
+			var out []byte
+			var report exportReport
+			switch format {
+			case "kubectl":
+				out, report = exportKubectl(columns)
+			case "k9s":
+				out, report, err = exportK9s(columns, target)
+			case "crd":
+				out, report, err = exportCRD(columns, target)
+			default:
+				return fmt.Errorf("unknown --format %q (expected one of %s)", format, strings.Join(exportFormats, ", "))
+			}
+			if err != nil {
+				return err
+			}
+			for _, line := range report.lines {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s %s: %s\n", line.level, path, line.msg)
+			}
+			if report.exported == 0 {
+				return fmt.Errorf("no column of %s can be exported to %s", path, format)
+			}
+			if _, err := cmd.OutOrStdout().Write(out); err != nil {
+				return err
+			}
+			if strict && report.lossy() {
+				return fmt.Errorf("%s cannot be exported to %s without losses", path, format)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", "kubectl", "The format to export to: "+strings.Join(exportFormats, ", "))
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(exportFormats, cobra.ShellCompDirectiveNoFileComp))
+	cmd.Flags().StringVar(&plural, "plural", "", "The plural resource name of the template's kind, for k9s and crd")
+	cmd.Flags().StringVar(&crdFile, "crd", "", "The CustomResourceDefinition manifest of the template's kind, for crd")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail if a column is skipped or exported without an option")
+	return cmd
+}
+
+// loadTemplateColumns returns the columns of the .yaml or .tpl template at
+// path.
+func loadTemplateColumns(path string) ([]models.YAMLColumn, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		tmpl, _, err := models.ParseYAMLTemplate(path, data)
+		if err != nil {
+			return nil, err
+		}
+		return tmpl.Columns, nil
+	case ".tpl":
+		t, err := parseTPL(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		columns := make([]models.YAMLColumn, len(t.headers))
+		for i, h := range t.headers {
+			columns[i] = tplColumn(h, t.specs[i])
+		}
+		return columns, nil
+	default:
+		return nil, fmt.Errorf("unsupported extension %q (want .yaml, .yml, or .tpl)", ext)
+	}
+}
+
+// exportTarget is the kind a template is exported for.
+type exportTarget struct {
+	gvk    schema.GroupVersionKind
+	plural string
+	// crd is the CRD of the kind, if read with --crd.
+	crd *apiextensionsv1.CustomResourceDefinition
+}
+
+// readCRD reads the CRD of the target's kind from the manifest at path.
+func (t *exportTarget) readCRD(path string) error {
+	schemas, err := newFileSchemas(path)
+	if err != nil {
+		return err
+	}
+	for i, crd := range schemas.crds {
+		if crd.Spec.Group == t.gvk.Group && strings.EqualFold(crd.Spec.Names.Kind, t.gvk.Kind) {
+			t.crd = &schemas.crds[i]
+			t.gvk.Kind = crd.Spec.Names.Kind
+			if t.plural == "" {
+				t.plural = crd.Spec.Names.Plural
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has no CustomResourceDefinition for %s in group %q", path, t.gvk.Kind, t.gvk.Group)
+}
+
+// resource returns the plural resource name of the target's kind.
+func (t *exportTarget) resource() string {
+	if t.plural != "" {
+		return t.plural
+	}
+	return guessPlural(strings.ToLower(t.gvk.Kind))
+}
+
+// guessPlural returns the plural of kind by the rules of English that
+// most Kubernetes kinds follow.
+func guessPlural(kind string) string {
+	switch {
+	case strings.HasSuffix(kind, "s"), strings.HasSuffix(kind, "x"), strings.HasSuffix(kind, "z"),
+		strings.HasSuffix(kind, "ch"), strings.HasSuffix(kind, "sh"):
+		return kind + "es"
+	case strings.HasSuffix(kind, "y") && len(kind) > 1 && !strings.ContainsAny(kind[len(kind)-2:len(kind)-1], "aeiou"):
+		return kind[:len(kind)-1] + "ies"
+	}
+	return kind + "s"
+}
+
+// exportReport is what an export left out.
+type exportReport struct {
+	exported int
+	lines    []exportReportLine
+}
+
+type exportReportLine struct {
+	// level is SKIP for a column left out, WARN for an option left out
+	// and NOTE for what the target has anyway.
+	level string
+	msg   string
+}
+
+func (r *exportReport) add(level string, i int, c models.YAMLColumn, format string, args ...interface{}) {
+	r.lines = append(r.lines, exportReportLine{level: level, msg: fmt.Sprintf("column[%d] (%s): ", i, c.Header) + fmt.Sprintf(format, args...)})
+}
+
+func (r *exportReport) lossy() bool {
+	for _, l := range r.lines {
+		if l.level != "NOTE" {
+			return true
+		}
+	}
+	return false
+}
+
+// exportPath returns the JSONPath of column i in the .field form every
+// target reads, or "" when the column has none; that is reported as a
+// skip. The list options of the column, which no target has, are reported
+// as warnings.
+func exportPath(r *exportReport, i int, c models.YAMLColumn, target string) string {
+	switch {
+	case c.Template != "":
+		r.add("SKIP", i, c, "Go template columns cannot be exported to %s", target)
+		return ""
+	case c.FieldSpec == common.DefaultPrinterField:
+		r.add("SKIP", i, c, "the server-side column it shows cannot be exported to %s", target)
+		return ""
+	}
+	spec, err := get.RelaxedJSONPathExpression(c.FieldSpec)
+	if err != nil || spec == "" {
+		r.add("SKIP", i, c, "fieldSpec %q is not a JSONPath %s can read", c.FieldSpec, target)
+		return ""
+	}
+	for _, option := range []struct {
+		name string
+		set  bool
+	}{
+		{"default", c.Default != ""}, {"separator", c.Separator != "" && c.Separator != ","},
+		{"maxItems", c.MaxItems != 0}, {"unique", c.Unique},
+	} {
+		if option.set {
+			r.add("WARN", i, c, "%s cannot express %s; exported without it", target, option.name)
+		}
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(spec, "{"), "}")
+}
+
+// exportKubectl writes columns as a kubectl custom-columns-file.
+func exportKubectl(columns []models.YAMLColumn) ([]byte, exportReport) {
+	var r exportReport
+	var headers, specs []string
+	for i, c := range columns {
+		path := exportPath(&r, i, c, "kubectl")
+		if path == "" {
+			continue
+		}
+		if strings.ContainsAny(c.Header, " \t") || strings.ContainsAny(path, " \t") {
+			r.add("SKIP", i, c, "kubectl splits custom-columns-file lines at spaces")
+			continue
+		}
+		if c.Format != "" {
+			r.add("WARN", i, c, "kubectl cannot express format %s; exported without it", c.Format)
+		}
+		headers = append(headers, c.Header)
+		specs = append(specs, path)
+	}
+	r.exported = len(headers)
+	if r.exported == 0 {
+		return nil, r
+	}
+	return []byte(alignTPL(headers, specs)), r
+}
+
+// exportK9s writes columns as the views.yaml entry of the target's
+// resource. Age columns become time columns (T) and numbers are right
+// aligned (N).
+func exportK9s(columns []models.YAMLColumn, target *exportTarget) ([]byte, exportReport, error) {
+	var r exportReport
+	var specs []string
+	for i, c := range columns {
+		path := exportPath(&r, i, c, "k9s")
+		if path == "" {
+			continue
+		}
+		if strings.ContainsAny(c.Header, ":|") {
+			r.add("SKIP", i, c, "k9s column headers cannot contain : or |")
+			continue
+		}
+		var attrs string
+		switch c.Format {
+		case "":
+		case "age":
+			attrs += "T"
+		default:
+			r.add("WARN", i, c, "k9s cannot express format %s; exported without it", c.Format)
+		}
+		if c.Type == "integer" || c.Type == "number" {
+			attrs += "N"
+		}
+		spec := c.Header + ":" + path
+		if attrs != "" {
+			spec += "|" + attrs
+		}
+		specs = append(specs, spec)
+	}
+	r.exported = len(specs)
+
+	gvr := target.gvk.GroupVersion().WithResource(target.resource())
+	key := gvr.GroupVersion().String() + "/" + gvr.Resource
+	views := map[string]interface{}{
+		"views": map[string]interface{}{
+			key: map[string]interface{}{"columns": specs},
+		},
+	}
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(views); err != nil {
+		return nil, r, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, r, err
+	}
+	return b.Bytes(), r, nil
+}
+
+// crdColumnTypes are the types of additionalPrinterColumns.
+var crdColumnTypes = map[string]bool{"string": true, "integer": true, "number": true, "boolean": true, "date": true}
+
+// exportCRD writes columns as a JSON patch of the additionalPrinterColumns
+// of the target's version. Age columns become date columns, which kubectl
+// shows as an age.
+func exportCRD(columns []models.YAMLColumn, target *exportTarget) ([]byte, exportReport, error) {
+	if target.gvk.Group == "" {
+		return nil, exportReport{}, fmt.Errorf("%s is a built-in kind; only custom resources have a CustomResourceDefinition", target.gvk.Kind)
+	}
+	var r exportReport
+	var printerColumns []apiextensionsv1.CustomResourceColumnDefinition
+	for i, c := range columns {
+		path := exportPath(&r, i, c, "a CRD")
+		if path == "" {
+			continue
+		}
+		if path == ".metadata.name" {
+			r.add("NOTE", i, c, "the API server always shows the name; left out")
+			continue
+		}
+		col := apiextensionsv1.CustomResourceColumnDefinition{Name: c.Header, Type: c.Type, JSONPath: path}
+		switch c.Format {
+		case "":
+		case "age":
+			col.Type = "date"
+		default:
+			r.add("WARN", i, c, "a CRD cannot express format %s; exported without it", c.Format)
+		}
+		if col.Type == "" {
+			col.Type = "string"
+		} else if !crdColumnTypes[col.Type] {
+			r.add("WARN", i, c, "a CRD column cannot have type %s; exported as a string", col.Type)
+			col.Type = "string"
+		}
+		printerColumns = append(printerColumns, col)
+	}
+	r.exported = len(printerColumns)
+
+	index := 0
+	if target.crd != nil {
+		index = -1
+		for i, v := range target.crd.Spec.Versions {
+			if v.Name == target.gvk.Version {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, r, fmt.Errorf("the CustomResourceDefinition of %s has no version %s", target.gvk.Kind, target.gvk.Version)
+		}
+	} else {
+		// The first version may not be the template's: the columns could
+		// land on another version, which --strict must not let pass.
+		r.lines = append(r.lines, exportReportLine{level: "WARN", msg: fmt.Sprintf("the patch sets the first version of the CRD, which may not be %s; use --crd to patch %s", target.gvk.Version, target.gvk.Version)})
+	}
+	patch := []map[string]interface{}{{
+		"op":    "add",
+		"path":  fmt.Sprintf("/spec/versions/%d/additionalPrinterColumns", index),
+		"value": printerColumns,
+	}}
+	data, err := sigsyaml.Marshal(patch)
+	if err != nil {
+		return nil, r, err
+	}
+	name := target.resource() + "." + target.gvk.Group
+	header := fmt.Sprintf("# additionalPrinterColumns of %s %s\n# kubectl patch crd %s --type=json --patch-file=FILE\n", target.gvk.GroupVersion(), target.gvk.Kind, name)
+	return append([]byte(header), data...), r, nil
+}
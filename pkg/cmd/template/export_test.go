@@ -0,0 +1,140 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kubectl-cwide/pkg/models"
+)
+
+var exportColumns = []models.YAMLColumn{
+	{Header: "NAME", FieldSpec: ".metadata.name"},
+	{Header: "REPLICAS", FieldSpec: "{.spec.replicas}", Type: "integer"},
+	{Header: "PORTS", FieldSpec: ".spec.ports[*].name", Separator: " ", MaxItems: 2},
+	{Header: "OWNER", Template: "{{ .metadata.ownerReferences }}"},
+	{Header: "AGE", FieldSpec: ".metadata.creationTimestamp", Format: "age"},
+}
+
+// reportOf returns the report lines as "LEVEL msg".
+func reportOf(r exportReport) string {
+	var lines []string
+	for _, l := range r.lines {
+		lines = append(lines, l.level+" "+l.msg)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestExportKubectl(t *testing.T) {
+	out, r := exportKubectl(exportColumns)
+	want := "NAME           REPLICAS       PORTS               AGE\n" +
+		".metadata.name .spec.replicas .spec.ports[*].name .metadata.creationTimestamp\n"
+	if string(out) != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+	report := reportOf(r)
+	for _, line := range []string{
+		"WARN column[2] (PORTS): kubectl cannot express separator; exported without it",
+		"WARN column[2] (PORTS): kubectl cannot express maxItems; exported without it",
+		"SKIP column[3] (OWNER): Go template columns cannot be exported to kubectl",
+		"WARN column[4] (AGE): kubectl cannot express format age; exported without it",
+	} {
+		if !strings.Contains(report, line) {
+			t.Errorf("report lacks %q:\n%s", line, report)
+		}
+	}
+	if r.exported != 4 || !r.lossy() {
+		t.Errorf("exported %d, lossy %v", r.exported, r.lossy())
+	}
+}
+
+func TestExportK9s(t *testing.T) {
+	target := &exportTarget{gvk: schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "policy"}}
+	out, _, err := exportK9s(exportColumns, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `views:
+  example.com/v1/policies:
+    columns:
+      - NAME:.metadata.name
+      - REPLICAS:.spec.replicas|N
+      - PORTS:.spec.ports[*].name
+      - AGE:.metadata.creationTimestamp|T
+`
+	if string(out) != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+}
+
+func TestExportCRD(t *testing.T) {
+	crd := filepath.Join(t.TempDir(), "widgets.yaml")
+	manifest := strings.Replace(widgetCRD, "  versions:\n", "  versions:\n    - name: v1beta1\n      served: true\n      storage: false\n", 1)
+	if err := os.WriteFile(crd, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := &exportTarget{gvk: schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "widget"}}
+	if err := target.readCRD(crd); err != nil {
+		t.Fatal(err)
+	}
+	out, r, err := exportCRD(exportColumns, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# additionalPrinterColumns of example.com/v1 Widget
+# kubectl patch crd widgets.example.com --type=json --patch-file=FILE
+- op: add
+  path: /spec/versions/1/additionalPrinterColumns
+  value:
+  - jsonPath: .spec.replicas
+    name: REPLICAS
+    type: integer
+  - jsonPath: .spec.ports[*].name
+    name: PORTS
+    type: string
+  - jsonPath: .metadata.creationTimestamp
+    name: AGE
+    type: date
+`
+	if string(out) != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+	if report := reportOf(r); !strings.Contains(report, "NOTE column[0] (NAME): the API server always shows the name") {
+		t.Errorf("report lacks the NAME note:\n%s", report)
+	}
+
+	// Without --crd, the first version is patched, which may not be the
+	// template's: --strict fails.
+	guessed := &exportTarget{gvk: schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "widget"}}
+	out, r, err = exportCRD(exportColumns, guessed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "path: /spec/versions/0/additionalPrinterColumns") {
+		t.Errorf("without --crd, want the first version patched:\n%s", out)
+	}
+	if report := reportOf(r); !strings.Contains(report, "WARN the patch sets the first version of the CRD, which may not be v1") {
+		t.Errorf("report lacks the version warning:\n%s", report)
+	}
+	if !r.lossy() {
+		t.Error("a patch of a guessed version passes --strict")
+	}
+
+	builtin := &exportTarget{gvk: schema.GroupVersionKind{Version: "v1", Kind: "pod"}}
+	if _, _, err := exportCRD(exportColumns, builtin); err == nil {
+		t.Error("built-in kinds have no CRD")
+	}
+}
+
+func TestGuessPlural(t *testing.T) {
+	for kind, want := range map[string]string{
+		"pod": "pods", "ingress": "ingresses", "networkpolicy": "networkpolicies", "gateway": "gateways", "mesh": "meshes",
+	} {
+		if got := guessPlural(kind); got != want {
+			t.Errorf("guessPlural(%q) = %q, want %q", kind, got, want)
+		}
+	}
+}
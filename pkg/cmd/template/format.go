@@ -0,0 +1,252 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kubectl-cwide/pkg/models"
+)
+
+// Canonical key order of YAML templates and their columns, that of
+// models.YAMLTemplate and models.YAMLColumn. Other keys follow, in the
+// order of the file.
+var (
+	yamlTemplateKeys = []string{"columns", "helpers", "funcs"}
+	yamlColumnKeys   = []string{"header", "fieldSpec", "template", "format", "default", "separator", "maxItems", "unique", "type", "volatile"}
+)
+
+func NewCmdFmt() *cobra.Command {
+	var check bool
+	cmd := &cobra.Command{
+		Use:   "fmt <path>...",
+		Short: "Rewrite templates in the canonical layout",
+		Long: `Rewrite each .yaml and .tpl template under the paths in the canonical
+layout, and print the names of the files it changes. Directories are searched
+recursively; test cases (tests/) and _shared helpers are skipped.
+
+A YAML template has its keys in the order columns, helpers, funcs, the keys
+of its columns in the order header, fieldSpec, template, format, default,
+separator, maxItems, unique, type, volatile, and its funcs sorted by name. Mappings and
+lists are in block style, indented by two spaces; multi-line strings are
+literal block scalars (|) and other strings are only quoted when they need
+to be. Comments are kept.
+
+The header and spec lines of a .tpl are aligned in columns; the rest of the
+file is kept.
+
+--check writes nothing and exits non-zero if any file is not formatted, for
+CI.`,
+		Example: `  # Format every template under the template root
+  kubectl cwide template fmt ~/.kubectl-cwide/templates
+
+  # Fail if a template in the repository is not formatted
+  kubectl cwide template fmt --check templates/`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var files []string
+			for _, ext := range []string{".yaml", ".tpl"} {
+				found, err := templateFiles(args, ext)
+				if err != nil {
+					return err
+				}
+				files = append(files, found...)
+			}
+			files = uniqueSorted(files)
+			failed, unformatted := 0, 0
+			for _, path := range files {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				out, err := formatTemplate(path, data)
+				if err != nil {
+					failed++
+					fmt.Fprintf(cmd.ErrOrStderr(), "FAIL %s: %v\n", path, err)
+					continue
+				}
+				if bytes.Equal(data, out) {
+					continue
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), path)
+				if check {
+					unformatted++
+					continue
+				}
+				if err := os.WriteFile(path, out, 0644); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d template(s) could not be formatted", failed)
+			}
+			if unformatted > 0 {
+				return fmt.Errorf("%d template(s) not formatted", unformatted)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&check, "check", false, "Write nothing; exit non-zero if a template is not formatted")
+	return cmd
+}
+
+func uniqueSorted(files []string) []string {
+	sort.Strings(files)
+	var out []string
+	for i, f := range files {
+		if i == 0 || f != files[i-1] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// formatTemplate returns the template at path, data, in the canonical
+// layout for its extension.
+func formatTemplate(path string, data []byte) ([]byte, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		return formatYAMLTemplate(data)
+	case ".tpl":
+		return formatTPL(data)
+	default:
+		return nil, fmt.Errorf("unsupported extension %q (want .yaml, .yml, or .tpl)", ext)
+	}
+}
+
+// formatTPL aligns the header and spec lines of the .tpl template data.
+func formatTPL(data []byte) ([]byte, error) {
+	t, err := parseTPL(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(t.headers) == 0 {
+		return nil, fmt.Errorf("template has no columns")
+	}
+	out := alignTPL(t.headers, t.specs)
+	if rest := strings.TrimRight(t.rest, " \t\n"); rest != "" {
+		out += rest + "\n"
+	}
+	return []byte(out), nil
+}
+
+// formatYAMLTemplate returns the YAML template data in the canonical
+// layout, with its comments.
+func formatYAMLTemplate(data []byte) ([]byte, error) {
+	if _, _, err := models.ParseYAMLTemplate("", data); err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return data, nil
+	}
+	return encodeYAMLTemplate(&doc)
+}
+
+// encodeYAMLTemplate encodes doc, a YAML template, in the canonical layout.
+func encodeYAMLTemplate(doc *yaml.Node) ([]byte, error) {
+	resetStyles(doc)
+	root := doc.Content[0]
+	sortMapping(root, yamlTemplateKeys)
+	if columns := models.MappingValue(root, "columns"); columns != nil && columns.Kind == yaml.SequenceNode {
+		for _, c := range columns.Content {
+			sortMapping(c, yamlColumnKeys)
+		}
+	}
+	sortMapping(models.MappingValue(root, "funcs"), nil)
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// resetStyles puts n and its children in block style, multi-line strings
+// in literal style, and leaves the quoting of other scalars to the encoder.
+func resetStyles(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		n.Style = 0
+		if n.Tag == "!!str" && strings.Contains(n.Value, "\n") {
+			n.Style = yaml.LiteralStyle
+		}
+	case yaml.MappingNode, yaml.SequenceNode:
+		n.Style = 0
+	}
+	for _, c := range n.Content {
+		resetStyles(c)
+	}
+}
+
+// sortMapping orders the keys of mapping n: those of order first, in that
+// order, then the others in their order. A nil order sorts the keys by
+// name.
+func sortMapping(n *yaml.Node, order []string) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return
+	}
+	rank := func(key string) int {
+		for i, k := range order {
+			if k == key {
+				return i
+			}
+		}
+		return len(order)
+	}
+	pairs := make([][2]*yaml.Node, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{n.Content[i], n.Content[i+1]})
+	}
+	if len(pairs) == 0 {
+		return
+	}
+	// The comment above the mapping and the one after it belong to the
+	// first and last keys; they stay at the top and the bottom.
+	first, last := pairs[0][0], pairs[len(pairs)-1][0]
+	head, foot := first.HeadComment, last.FootComment
+	first.HeadComment, last.FootComment = "", ""
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if order == nil {
+			return pairs[i][0].Value < pairs[j][0].Value
+		}
+		return rank(pairs[i][0].Value) < rank(pairs[j][0].Value)
+	})
+	n.Content = n.Content[:0]
+	for _, p := range pairs {
+		n.Content = append(n.Content, p[0], p[1])
+	}
+	first, last = pairs[0][0], pairs[len(pairs)-1][0]
+	first.HeadComment = joinComments(head, first.HeadComment)
+	last.FootComment = joinComments(last.FootComment, foot)
+}
+
+func joinComments(a, b string) string {
+	if a == "" || b == "" {
+		return a + b
+	}
+	return a + "\n" + b
+}
+
+// marshalYAMLTemplate encodes tmpl in the canonical layout.
+func marshalYAMLTemplate(tmpl *models.YAMLTemplate) ([]byte, error) {
+	var root yaml.Node
+	if err := root.Encode(tmpl); err != nil {
+		return nil, err
+	}
+	return encodeYAMLTemplate(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&root}})
+}
@@ -0,0 +1,64 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestFormatYAMLTemplate(t *testing.T) {
+	body := `# Pod columns.
+funcs: {short: '{{ trunc 8 . }}', long: "{{ . }}"}
+helpers: "{{ define \"phase\" }}\n{{ .status.phase }}\n{{ end }}\n"
+columns:
+- template: "{{ template \"phase\" . }}"
+  header: PHASE   # from helpers
+- fieldSpec: .metadata.creationTimestamp
+  format: age
+  header: "AGE"
+  # age of the pod
+`
+	want := `# Pod columns.
+columns:
+  - header: PHASE # from helpers
+    template: '{{ template "phase" . }}'
+  - header: AGE
+    fieldSpec: .metadata.creationTimestamp
+    format: age
+    # age of the pod
+helpers: |
+  {{ define "phase" }}
+  {{ .status.phase }}
+  {{ end }}
+funcs:
+  long: '{{ . }}'
+  short: '{{ trunc 8 . }}'
+`
+	out, err := formatYAMLTemplate([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+	again, err := formatYAMLTemplate(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(out) {
+		t.Errorf("formatting is not idempotent:\n%s", again)
+	}
+
+	if _, err := formatYAMLTemplate([]byte("columns: {header: X}\n")); err == nil {
+		t.Error("a template that does not decode should not be formatted")
+	}
+}
+
+func TestFormatTPL(t *testing.T) {
+	out, err := formatTPL([]byte("NAME  READY\n.metadata.name   {{ .status.ready }}\n{{ define \"x\" }}{{ end }}\n\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "NAME           READY\n.metadata.name {{ .status.ready }}\n{{ define \"x\" }}{{ end }}\n"
+	if string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,238 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"text/template"
+	"text/template/parse"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+
+	"github.com/kubectl-cwide/pkg/models"
+	"github.com/kubectl-cwide/pkg/parser"
+)
+
+// lintTemplates is the set of Go templates of one template file, parsed as
+// get parses them: with the cwide functions and the file's own funcs, and
+// with the helpers defined first.
+type lintTemplates struct {
+	set     *template.Template
+	columns map[string]bool
+}
+
+// newLintTemplates parses helpers and the bodies of custom funcs. Parse
+// errors are reported at their position in sources.
+func newLintTemplates(helpers string, funcs map[string]string, sources models.TemplateSources) (*lintTemplates, error) {
+	m := parser.GetFuncMap(parser.NewScope(context.Background()), nil)
+	for name := range funcs {
+		m[name] = func(args ...interface{}) (string, error) { return "", nil }
+	}
+	set := template.New("local").Funcs(m)
+	if helpers != "" {
+		if _, err := set.Parse(helpers); err != nil {
+			return nil, sources.Wrap(err, "helpers")
+		}
+	}
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := set.New("__func_" + name).Parse(funcs[name]); err != nil {
+			return nil, sources.Wrap(err, fmt.Sprintf("custom func %q", name))
+		}
+	}
+	return &lintTemplates{set: set, columns: map[string]bool{}}, nil
+}
+
+// column parses the template of column ix.
+func (l *lintTemplates) column(ix int, body string) (*template.Template, error) {
+	name := fmt.Sprintf("column%d", ix)
+	l.columns[name] = true
+	return l.set.New(name).Parse(body)
+}
+
+// helpers returns the templates of the set that are not columns: the
+// helpers and custom funcs, sorted by name.
+func (l *lintTemplates) helpers() []*template.Template {
+	var ts []*template.Template
+	for _, t := range l.set.Templates() {
+		if t.Tree != nil && !l.columns[t.Name()] {
+			ts = append(ts, t)
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Name() < ts[j].Name() })
+	return ts
+}
+
+func (l *lintTemplates) defined(name string) bool {
+	t := l.set.Lookup(name)
+	return t != nil && t.Tree != nil
+}
+
+// check walks template t over the schema ks of the object it renders, if
+// any. It returns the fields t reads that ks rules out, and the calls of
+// templates that are not defined.
+func (l *lintTemplates) check(t *template.Template, ks *kindSchema) ([]*fieldIssue, []*parse.TemplateNode) {
+	w := &templateWalker{ks: ks, defined: l.defined}
+	root := fieldRef{}
+	if ks != nil {
+		root.s = ks.resolve(ks.root)
+	}
+	w.list(t.Tree.Root, root, map[string]fieldRef{"$": root})
+	return w.issues, w.undefined
+}
+
+// fieldRef is the schema of a value in a template and the path it was
+// read from. A nil schema is a value that cannot be checked.
+type fieldRef struct {
+	s    *spec.Schema
+	path string
+}
+
+// templateWalker follows the dot and variables of a template through the
+// schema, as far as it can tell what they hold: fields, variables, range
+// and with. The results of functions are not checked.
+type templateWalker struct {
+	ks        *kindSchema
+	defined   func(name string) bool
+	issues    []*fieldIssue
+	undefined []*parse.TemplateNode
+}
+
+func (w *templateWalker) list(list *parse.ListNode, dot fieldRef, vars map[string]fieldRef) {
+	if list == nil {
+		return
+	}
+	for _, n := range list.Nodes {
+		w.node(n, dot, vars)
+	}
+}
+
+func (w *templateWalker) node(n parse.Node, dot fieldRef, vars map[string]fieldRef) {
+	switch n := n.(type) {
+	case *parse.ActionNode:
+		w.pipe(n.Pipe, dot, vars)
+	case *parse.IfNode:
+		inner := copyVars(vars)
+		w.pipe(n.Pipe, dot, inner)
+		w.list(n.List, dot, inner)
+		w.list(n.ElseList, dot, inner)
+	case *parse.WithNode:
+		inner := copyVars(vars)
+		with := w.pipe(n.Pipe, dot, inner)
+		w.list(n.List, with, inner)
+		w.list(n.ElseList, dot, inner)
+	case *parse.RangeNode:
+		inner := copyVars(vars)
+		ranged := w.pipe(n.Pipe, dot, inner)
+		elem := fieldRef{}
+		if w.ks != nil && ranged.s != nil {
+			if items := w.ks.items(ranged.s); items != nil {
+				elem = fieldRef{s: items, path: ranged.path + "[*]"}
+			} else if ap := ranged.s.AdditionalProperties; ap != nil && ap.Schema != nil {
+				elem = fieldRef{s: w.ks.resolve(ap.Schema), path: ranged.path + "[*]"}
+			}
+		}
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner[n.Pipe.Decl[0].Ident[0]] = elem
+		case 2:
+			inner[n.Pipe.Decl[0].Ident[0]] = fieldRef{}
+			inner[n.Pipe.Decl[1].Ident[0]] = elem
+		}
+		w.list(n.List, elem, inner)
+		w.list(n.ElseList, dot, inner)
+	case *parse.TemplateNode:
+		if !w.defined(n.Name) {
+			w.undefined = append(w.undefined, n)
+		}
+		if n.Pipe != nil {
+			w.pipe(n.Pipe, dot, vars)
+		}
+	case *parse.ListNode:
+		w.list(n, dot, vars)
+	}
+}
+
+// pipe checks the fields pipe reads and returns what it yields. The
+// variables it declares are set in vars.
+func (w *templateWalker) pipe(pipe *parse.PipeNode, dot fieldRef, vars map[string]fieldRef) fieldRef {
+	if pipe == nil {
+		return fieldRef{}
+	}
+	var result fieldRef
+	for i, cmd := range pipe.Cmds {
+		result = w.command(cmd, dot, vars)
+		if i > 0 {
+			// The previous result is passed on as the last argument.
+			result = fieldRef{}
+		}
+	}
+	if !pipe.IsAssign {
+		for _, v := range pipe.Decl {
+			vars[v.Ident[0]] = result
+		}
+	}
+	return result
+}
+
+func (w *templateWalker) command(cmd *parse.CommandNode, dot fieldRef, vars map[string]fieldRef) fieldRef {
+	args := make([]fieldRef, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		args[i] = w.arg(arg, dot, vars)
+	}
+	if len(cmd.Args) == 1 {
+		return args[0]
+	}
+	// index of an array or map yields its items.
+	if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok && id.Ident == "index" && len(args) == 3 && w.ks != nil && args[1].s != nil {
+		if items := w.ks.items(args[1].s); items != nil {
+			return fieldRef{s: items, path: args[1].path + "[*]"}
+		}
+	}
+	return fieldRef{}
+}
+
+func (w *templateWalker) arg(n parse.Node, dot fieldRef, vars map[string]fieldRef) fieldRef {
+	switch n := n.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return w.fields(n, dot, n.Ident)
+	case *parse.VariableNode:
+		return w.fields(n, vars[n.Ident[0]], n.Ident[1:])
+	case *parse.ChainNode:
+		return w.fields(n, w.arg(n.Node, dot, vars), n.Field)
+	case *parse.PipeNode:
+		return w.pipe(n, dot, vars)
+	}
+	return fieldRef{}
+}
+
+// fields follows the names node reads from ref, recording the first issue.
+func (w *templateWalker) fields(node parse.Node, ref fieldRef, names []string) fieldRef {
+	for _, name := range names {
+		if w.ks == nil || ref.s == nil {
+			return fieldRef{}
+		}
+		next, issue := w.ks.field(ref.s, ref.path, name)
+		if issue != nil {
+			issue.node = node
+			w.issues = append(w.issues, issue)
+			return fieldRef{}
+		}
+		ref = fieldRef{s: next, path: ref.path + "." + name}
+	}
+	return ref
+}
+
+func copyVars(vars map[string]fieldRef) map[string]fieldRef {
+	c := make(map[string]fieldRef, len(vars))
+	for k, v := range vars {
+		c[k] = v
+	}
+	return c
+}
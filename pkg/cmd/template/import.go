@@ -0,0 +1,269 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kubectl-cwide/pkg/clients"
+	"github.com/kubectl-cwide/pkg/cmd/completions"
+	"github.com/kubectl-cwide/pkg/cmd/get"
+	"github.com/kubectl-cwide/pkg/models"
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+func NewCmdImport() *cobra.Command {
+	var fromSpec, fromFile, fromCustomCols string
+	var kind, name, kubeContext string
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "import [resource]",
+		Short: "Save kubectl custom-columns and kubectl-custom-cols templates as YAML templates",
+		Long: `Save columns written for kubectl or kubectl-custom-cols as YAML templates
+in the template root, in the directory of their kind (<kind>-<group>-<version>).
+
+The columns come from one of:
+  --from-spec SPEC          a kubectl -o custom-columns spec,
+                            NAME:.metadata.name,STATUS:.status.phase
+  --from-file FILE          a kubectl -o custom-columns-file file: a line of
+                            headers and a line of JSONPath field specs
+  --from-custom-cols DIR    the template files of kubectl-custom-cols, in the
+                            same format, one template per file
+
+The kind is the served version of the resource, found by discovery, or the
+kind given by --kind as Kind.version[.group] (Pod.v1, Deployment.v1.apps),
+without contacting the cluster. With --from-spec and --from-file, name the
+resource as the argument; the template is saved as --name.
+
+With --from-custom-cols, a file named after a resource (pod, deployments.apps)
+is saved as --name for that resource. With --kind, every file is saved for
+that kind, as the template of the file's name.
+
+Existing templates are not overwritten without --force.`,
+		Example: `  # Save the columns you pass to kubectl as the "wide" pod template
+  kubectl cwide template import pods --name wide --from-spec 'NAME:.metadata.name,NODE:.spec.nodeName,IP:.status.podIP'
+
+  # Save a custom-columns-file for a CRD, without contacting the cluster
+  kubectl cwide template import --kind Widget.v1.example.com --from-file widgets.cols
+
+  # Import the templates of kubectl-custom-cols
+  kubectl cwide template import --from-custom-cols ~/.custom-cols`,
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: completions.ResourceTypes,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sources := 0
+			for _, s := range []string{fromSpec, fromFile, fromCustomCols} {
+				if s != "" {
+					sources++
+				}
+			}
+			if sources != 1 {
+				return fmt.Errorf("give exactly one of --from-spec, --from-file and --from-custom-cols")
+			}
+			if fromCustomCols != "" && len(args) > 0 {
+				return fmt.Errorf("--from-custom-cols takes the kinds from the file names or --kind, not an argument")
+			}
+			if fromCustomCols == "" && len(args) == 0 && kind == "" {
+				return fmt.Errorf("name the resource as an argument or give --kind")
+			}
+			root, err := utils.ResolveTemplatePath(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to resolve template path: %w", err)
+			}
+
+			im := &importer{root: root, name: name, force: force, out: cmd.OutOrStdout(), errOut: cmd.ErrOrStderr(), written: map[string]string{}}
+			if kind != "" {
+				gvk, err := parseKindFlag(kind)
+				if err != nil {
+					return err
+				}
+				im.kind = &gvk
+			} else {
+				var mapper meta.RESTMapper
+				im.resolve = func(resource string) (schema.GroupVersionKind, error) {
+					if mapper == nil {
+						if mapper, err = clients.FactoryFromCmd(cmd, kubeContext).ToRESTMapper(); err != nil {
+							return schema.GroupVersionKind{}, fmt.Errorf("failed to get REST mapper: %w", err)
+						}
+					}
+					return resolveResource(mapper, utils.ResolveAliasString(resource))
+				}
+			}
+
+			switch {
+			case fromSpec != "":
+				return im.spec(firstArg(args), fromSpec)
+			case fromFile != "":
+				return im.file(firstArg(args), fromFile)
+			default:
+				return im.customCols(fromCustomCols)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&fromSpec, "from-spec", "", "A kubectl custom-columns spec: HEADER:FIELD,...")
+	cmd.Flags().StringVar(&fromFile, "from-file", "", "A kubectl custom-columns-file file")
+	cmd.Flags().StringVar(&fromCustomCols, "from-custom-cols", "", "A kubectl-custom-cols template directory")
+	cmd.Flags().StringVar(&kind, "kind", "", "The kind of the templates, as Kind.version[.group]; skips discovery")
+	cmd.Flags().StringVar(&name, "name", "default", "Name of the saved template (without extension)")
+	cmd.Flags().StringVar(&kubeContext, "context", "", "The name of the kubeconfig context to discover kinds with")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing templates")
+	return cmd
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// importer saves imported columns as YAML templates under root. The kind
+// of the templates is kind, if set, or that of their resource by resolve.
+type importer struct {
+	root    string
+	kind    *schema.GroupVersionKind
+	resolve func(resource string) (schema.GroupVersionKind, error)
+	name    string
+	force   bool
+	out     io.Writer
+	errOut  io.Writer
+	// written maps the templates saved so far to their source.
+	written map[string]string
+}
+
+// spec saves the custom-columns spec as the template of resource.
+func (im *importer) spec(resource, spec string) error {
+	columns, err := get.ParseCustomColumnsSpec(spec)
+	if err != nil {
+		return err
+	}
+	data, err := marshalYAMLTemplate(&models.YAMLTemplate{Columns: columns})
+	if err != nil {
+		return err
+	}
+	return im.save("--from-spec", resource, im.name, data)
+}
+
+// file saves the custom-columns-file path as the template of resource.
+func (im *importer) file(resource, path string) error {
+	data, err := im.convert(path)
+	if err != nil {
+		return err
+	}
+	return im.save(path, resource, im.name, data)
+}
+
+// customCols saves the templates of the kubectl-custom-cols directory dir.
+// Every file is tried; the command fails if any could not be saved.
+func (im *importer) customCols(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	imported, failed := 0, 0
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		base := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		resource, name := base, im.name
+		if im.kind != nil {
+			resource, name = "", base
+		}
+		data, err := im.convert(path)
+		if err == nil {
+			err = im.save(path, resource, name, data)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(im.errOut, "FAIL %s: %v\n", path, err)
+			continue
+		}
+		imported++
+	}
+	if imported+failed == 0 {
+		return fmt.Errorf("no templates found in %s", dir)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d template(s) could not be imported", failed)
+	}
+	return nil
+}
+
+// convert reads the two-line template at path as a YAML template.
+func (im *importer) convert(path string) ([]byte, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, warnings, err := tplToYAML(src)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(im.errOut, "WARN %s: %s\n", path, w)
+	}
+	return data, nil
+}
+
+// save writes data, imported from source, as template name of the kind of
+// resource.
+func (im *importer) save(source, resource, name string, data []byte) error {
+	var gvk schema.GroupVersionKind
+	if im.kind != nil {
+		gvk = *im.kind
+	} else {
+		var err error
+		if gvk, err = im.resolve(resource); err != nil {
+			return fmt.Errorf("cannot tell the kind of %q; name a resource the cluster serves or use --kind: %w", resource, err)
+		}
+	}
+	path := filepath.Join(im.root, utils.GenerateDirNameByGVK(gvk), name+".yaml")
+	if other, ok := im.written[path]; ok {
+		return fmt.Errorf("%s was already imported from %s", path, other)
+	}
+	if utils.CheckFileExists(path) && !im.force {
+		return fmt.Errorf("template %s already exists; use --force to overwrite it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	im.written[path] = source
+	fmt.Fprintf(im.out, "Imported %s -> %s\n", source, path)
+	return nil
+}
+
+// parseKindFlag parses a kind given as Kind.version[.group].
+func parseKindFlag(s string) (schema.GroupVersionKind, error) {
+	parts := strings.SplitN(s, ".", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return schema.GroupVersionKind{}, fmt.Errorf("invalid --kind %q (expected Kind.version[.group], e.g. Pod.v1 or Deployment.v1.apps)", s)
+	}
+	gvk := schema.GroupVersionKind{Kind: parts[0], Version: parts[1]}
+	if len(parts) == 3 {
+		gvk.Group = parts[2]
+	}
+	return gvk, nil
+}
+
+// resolveResource returns the kind of resource, such as pods, deploy or
+// deployments.v1.apps, as the cluster serves it.
+func resolveResource(mapper meta.RESTMapper, resource string) (schema.GroupVersionKind, error) {
+	fully, gr := schema.ParseResourceArg(strings.ToLower(resource))
+	if fully != nil {
+		if gvk, err := mapper.KindFor(*fully); err == nil {
+			return gvk, nil
+		}
+	}
+	return mapper.KindFor(gr.WithVersion(""))
+}
@@ -0,0 +1,107 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// testImporter returns an importer into a temporary root that knows the
+// resources pod and deployment.
+func testImporter(t *testing.T) (*importer, *bytes.Buffer) {
+	var errOut bytes.Buffer
+	return &importer{
+		root: t.TempDir(),
+		resolve: func(resource string) (schema.GroupVersionKind, error) {
+			switch resource {
+			case "pod", "pods":
+				return schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, nil
+			case "deployment":
+				return schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, nil
+			}
+			return schema.GroupVersionKind{}, fmt.Errorf("the server doesn't have a resource type %q", resource)
+		},
+		name:    "default",
+		out:     io.Discard,
+		errOut:  &errOut,
+		written: map[string]string{},
+	}, &errOut
+}
+
+func TestImportSpec(t *testing.T) {
+	im, _ := testImporter(t)
+	im.name = "wide"
+	if err := im.spec("pods", "NAME:.metadata.name,NODE:spec.nodeName"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(im.root, "pod--v1", "wide.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: NODE
+    fieldSpec: spec.nodeName
+`
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if err := im.spec("pods", "NAME:.metadata.name"); err == nil || !strings.Contains(err.Error(), "already") {
+		t.Errorf("second import of the same template: %v", err)
+	}
+
+	gvk, err := parseKindFlag("Widget.v1.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	im.kind = &gvk
+	if err := im.spec("", "NAME:.metadata.name"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(im.root, "widget-example.com-v1", "wide.yaml")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestImportCustomCols(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"pod":            "NAME STATUS\n.metadata.name .status.phase\n",
+		"deployment.tpl": "NAME READY\n.metadata.name .status.readyReplicas\n",
+		"gadget":         "NAME\n.metadata.name\n",
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	im, errOut := testImporter(t)
+	err := im.customCols(dir)
+	if err == nil || !strings.Contains(errOut.String(), `cannot tell the kind of "gadget"`) {
+		t.Fatalf("got %v\n%s", err, errOut)
+	}
+	for _, path := range []string{"pod--v1/default.yaml", "deployment-apps-v1/default.yaml"} {
+		if _, err := os.Stat(filepath.Join(im.root, path)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// With --kind, the files are templates of that kind.
+	im, _ = testImporter(t)
+	im.kind = &schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Gadget"}
+	if err := im.customCols(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"pod", "deployment", "gadget"} {
+		if _, err := os.Stat(filepath.Join(im.root, "gadget-example.com-v1", name+".yaml")); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/kubectl-cwide/pkg/clients"
+	"github.com/kubectl-cwide/pkg/cmd/completions"
+	"github.com/kubectl-cwide/pkg/models"
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+// inferNoise are the fields infer never proposes, wherever they are:
+// identifiers and bookkeeping that differ between every object without
+// telling anything about it.
+var inferNoise = map[string]bool{
+	"uid":                true,
+	"resourceVersion":    true,
+	"selfLink":           true,
+	"generation":         true,
+	"observedGeneration": true,
+	"managedFields":      true,
+	"collisionCount":     true,
+	"lastTransitionTime": true,
+	"lastUpdateTime":     true,
+	"lastHeartbeatTime":  true,
+	"lastProbeTime":      true,
+	"containerID":        true,
+	"imageID":            true,
+}
+
+// inferFieldName matches the field names a field spec can name without
+// quoting; fields of maps keyed by anything else (labels, data) are left
+// out.
+var inferFieldName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+func NewCmdInfer() *cobra.Command {
+	var resource, namespace, selector, filename, kubeContext string
+	var allNamespaces bool
+	var limit, maxColumns int
+	cmd := &cobra.Command{
+		Use:   "infer",
+		Short: "Propose a template from the fields that vary across live objects",
+		Long: `Sample objects of a resource and propose a YAML template of the columns
+that tell them apart best.
+
+Every field of the sampled objects is scored by how much its values vary
+across them (the entropy of the values), how many objects have it, and
+where it is: status fields first, then spec, shallow before deep. Fields
+whose values are long, or different in every object like identifiers, are
+scored down. Identifiers and bookkeeping (uid, resourceVersion,
+managedFields, generations, condition timestamps) and metadata are left
+out; NAMESPACE, NAME and AGE are always columns.
+
+The --max-columns best fields become columns, with headers from their
+names, format: age for timestamps and the type of numbers and booleans.
+When the objects have status conditions, a CONDITIONS column lists those
+that are True. The next best fields follow, commented out, with how many
+values they have.
+
+Objects are listed from the cluster, or read offline from --filename, a
+file of objects as kubectl get -o yaml or -o json writes them. At most
+--limit objects are sampled, evenly across the list.
+
+The template is written to stdout — pipe it to the destination path yourself.`,
+		Example: `  # Propose a template for the widgets of an operator, across all namespaces
+  kubectl cwide template infer -r widgets.example.com -A
+
+  # From objects saved earlier, without a cluster
+  kubectl get deployments -A -o yaml > deployments.yaml
+  kubectl cwide template infer -f deployments.yaml > ~/.kubectl-cwide/templates/deployment-apps-v1/inferred.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if limit < 1 || maxColumns < 1 {
+				return fmt.Errorf("--limit and --max-columns must be at least 1")
+			}
+			var objs []map[string]interface{}
+			var err error
+			switch {
+			case filename != "" && resource != "":
+				return fmt.Errorf("-r and -f cannot be used together")
+			case filename != "":
+				objs, err = readObjectsFile(filename)
+			case resource != "":
+				objs, err = listObjects(cmd, kubeContext, utils.ResolveAliasString(resource), namespace, allNamespaces, selector)
+			default:
+				return fmt.Errorf("give the resource with -r, or a file of objects with -f")
+			}
+			if err != nil {
+				return err
+			}
+			if len(objs) == 0 {
+				return fmt.Errorf("no objects to infer a template from")
+			}
+			body, err := inferTemplate(sampleObjects(objs, limit), maxColumns)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(body)
+			return err
+		},
+	}
+	cmd.Flags().StringVarP(&resource, "resource", "r", "", "Resource type to sample (e.g. pod, widgets.example.com)")
+	cmd.Flags().StringVarP(&namespace, "namespace", "n", "", "Namespace to sample objects from")
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Sample objects across all namespaces")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector of the objects to sample")
+	cmd.Flags().StringVarP(&filename, "filename", "f", "", "Read the objects from a file instead of the cluster")
+	cmd.Flags().IntVar(&limit, "limit", 500, "Most objects to sample")
+	cmd.Flags().IntVar(&maxColumns, "max-columns", 8, "Most inferred columns to enable, besides NAMESPACE, NAME, CONDITIONS and AGE")
+	cmd.Flags().StringVar(&kubeContext, "context", "", "The name of the kubeconfig context to list objects from")
+	_ = cmd.RegisterFlagCompletionFunc("resource", completions.ResourceTypes)
+	return cmd
+}
+
+// listObjects lists the objects of resource from the cluster.
+func listObjects(cmd *cobra.Command, kubeContext, resource, namespace string, allNamespaces bool, selector string) ([]map[string]interface{}, error) {
+	f := clients.FactoryFromCmd(cmd, kubeContext)
+	if namespace == "" {
+		var err error
+		if namespace, _, err = f.ToRawKubeConfigLoader().Namespace(); err != nil {
+			return nil, fmt.Errorf("failed to resolve namespace: %w", err)
+		}
+	}
+	infos, err := f.NewBuilder().
+		Unstructured().
+		DefaultNamespace().
+		NamespaceParam(namespace).
+		AllNamespaces(allNamespaces).
+		LabelSelectorParam(selector).
+		ResourceTypeOrNameArgs(true, resource).
+		Flatten().
+		Do().
+		Infos()
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]map[string]interface{}, 0, len(infos))
+	for _, info := range infos {
+		if u, ok := info.Object.(*unstructured.Unstructured); ok {
+			objs = append(objs, u.Object)
+		}
+	}
+	return objs, nil
+}
+
+// readObjectsFile reads the objects in path: YAML or JSON documents, each
+// an object or a List of them.
+func readObjectsFile(path string) ([]map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var objs []map[string]interface{}
+	dec := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		if obj == nil {
+			continue
+		}
+		items, isList := obj["items"].([]interface{})
+		if !isList {
+			objs = append(objs, obj)
+			continue
+		}
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				objs = append(objs, m)
+			}
+		}
+	}
+	return objs, nil
+}
+
+// sampleObjects returns at most limit of objs, evenly spaced.
+func sampleObjects(objs []map[string]interface{}, limit int) []map[string]interface{} {
+	if len(objs) <= limit {
+		return objs
+	}
+	sample := make([]map[string]interface{}, limit)
+	for i := range sample {
+		sample[i] = objs[i*len(objs)/limit]
+	}
+	return sample
+}
+
+// inferredField is a field of the sampled objects and its values.
+type inferredField struct {
+	path string
+	// present is the number of objects that have the field.
+	present int
+	// values counts the objects by value; the values of an array field
+	// are joined by commas.
+	values map[string]int
+	// kinds counts the values by kind: string, integer, number, boolean,
+	// date.
+	kinds  map[string]int
+	length int
+	score  float64
+}
+
+// kind returns the kind of every value of f, or "" if they differ.
+func (f *inferredField) kind() string {
+	if len(f.kinds) != 1 {
+		return ""
+	}
+	for k := range f.kinds {
+		return k
+	}
+	return ""
+}
+
+// examples returns up to n of the most common values of f.
+func (f *inferredField) examples(n int) []string {
+	values := make([]string, 0, len(f.values))
+	for v := range f.values {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if f.values[values[i]] != f.values[values[j]] {
+			return f.values[values[i]] > f.values[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	if len(values) > n {
+		values = values[:n]
+	}
+	return values
+}
+
+// inferTemplate returns the template proposed for objs, with maxColumns
+// inferred columns enabled and as many more commented out.
+func inferTemplate(objs []map[string]interface{}, maxColumns int) ([]byte, error) {
+	fields := map[string]*inferredField{}
+	namespaced, conditions := false, false
+	for _, obj := range objs {
+		if ns, _, _ := unstructured.NestedString(obj, "metadata", "namespace"); ns != "" {
+			namespaced = true
+		}
+		// Conditions are a column of their own, instead of their fields.
+		hasConditions := false
+		if conds, _, _ := unstructured.NestedSlice(obj, "status", "conditions"); len(conds) > 0 {
+			if c, ok := conds[0].(map[string]interface{}); ok && c["type"] != nil && c["status"] != nil {
+				hasConditions, conditions = true, true
+			}
+		}
+		leaves := map[string][]interface{}{}
+		for key, v := range obj {
+			if key == "apiVersion" || key == "kind" || key == "metadata" {
+				continue
+			}
+			flattenObject(v, "."+key, leaves)
+		}
+		for path, values := range leaves {
+			if hasConditions && strings.HasPrefix(path, ".status.conditions[*].") {
+				continue
+			}
+			f := fields[path]
+			if f == nil {
+				f = &inferredField{path: path, values: map[string]int{}, kinds: map[string]int{}}
+				fields[path] = f
+			}
+			strs := make([]string, len(values))
+			for i, v := range values {
+				strs[i] = fmt.Sprint(v)
+				f.kinds[valueKind(v)]++
+			}
+			value := strings.Join(strs, ",")
+			f.present++
+			f.values[value]++
+			f.length += len(value)
+		}
+	}
+
+	ranked := make([]*inferredField, 0, len(fields))
+	for _, f := range fields {
+		f.score = inferScore(f, len(objs))
+		ranked = append(ranked, f)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].path < ranked[j].path
+	})
+	if len(ranked) > 2*maxColumns {
+		ranked = ranked[:2*maxColumns]
+	}
+
+	var tmpl models.YAMLTemplate
+	if namespaced {
+		tmpl.Columns = append(tmpl.Columns, models.YAMLColumn{Header: "NAMESPACE", FieldSpec: ".metadata.namespace"})
+	}
+	tmpl.Columns = append(tmpl.Columns, models.YAMLColumn{Header: "NAME", FieldSpec: ".metadata.name"})
+	taken := []string{"NAMESPACE", "NAME", "CONDITIONS", "AGE"}
+	leaves := make([]scaffoldField, len(ranked))
+	for i, f := range ranked {
+		leaves[i] = scaffoldField{path: f.path}
+	}
+	headers := scaffoldHeaders(leaves, taken)
+	columns := make([]models.YAMLColumn, len(ranked))
+	for i, f := range ranked {
+		columns[i] = models.YAMLColumn{Header: headers[i], FieldSpec: f.path}
+		switch kind := f.kind(); kind {
+		case "date":
+			columns[i].Format = "age"
+		case "integer", "number", "boolean":
+			columns[i].Type = kind
+		}
+	}
+	enabled := min(maxColumns, len(columns))
+	tmpl.Columns = append(tmpl.Columns, columns[:enabled]...)
+	if conditions {
+		tmpl.Columns = append(tmpl.Columns, models.YAMLColumn{Header: "CONDITIONS", Template: conditionsTemplate})
+	}
+	tmpl.Columns = append(tmpl.Columns, models.YAMLColumn{Header: "AGE", FieldSpec: ".metadata.creationTimestamp", Format: "age"})
+
+	body, err := marshalYAMLTemplate(&tmpl)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	apiVersion, _ := objs[0]["apiVersion"].(string)
+	kind, _ := objs[0]["kind"].(string)
+	fmt.Fprintf(&b, "# Inferred from %d %s %s object(s); columns are ranked by how much\n", len(objs), apiVersion, kind)
+	b.WriteString("# their values vary across them.\n")
+	b.Write(body)
+	if enabled == len(columns) {
+		return []byte(b.String()), nil
+	}
+	b.WriteString("\n  # --- more fields, by rank; uncomment to include ---\n")
+	for i := enabled; i < len(columns); i++ {
+		f, c := ranked[i], columns[i]
+		fmt.Fprintf(&b, "  # %s: %d value(s) in %d/%d objects, e.g. %s\n", f.path, len(f.values), f.present, len(objs), exampleList(f.examples(3)))
+		fmt.Fprintf(&b, "  # - header: %s\n  #   fieldSpec: %s\n", c.Header, c.FieldSpec)
+		switch {
+		case c.Format != "":
+			fmt.Fprintf(&b, "  #   format: %s\n", c.Format)
+		case c.Type != "":
+			fmt.Fprintf(&b, "  #   type: %s\n", c.Type)
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// flattenObject adds the scalar values under v, at path, to leaves by
+// field spec. Items of arrays are [*]; noise and fields of maps keyed by
+// anything but field names are left out.
+func flattenObject(v interface{}, path string, leaves map[string][]interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if inferNoise[key] || !inferFieldName.MatchString(key) {
+				continue
+			}
+			flattenObject(child, path+"."+key, leaves)
+		}
+	case []interface{}:
+		for _, item := range v {
+			flattenObject(item, path+"[*]", leaves)
+		}
+	case nil:
+	default:
+		leaves[path] = append(leaves[path], v)
+	}
+}
+
+// valueKind returns the kind of a scalar value decoded from JSON.
+func valueKind(v interface{}) string {
+	switch v := v.(type) {
+	case bool:
+		return "boolean"
+	case int64, int:
+		return "integer"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		if _, err := time.Parse(time.RFC3339, v); err == nil {
+			return "date"
+		}
+	}
+	return "string"
+}
+
+// inferScore scores field f of n objects: the entropy of its values, plus
+// one so that fields that do not vary still rank, weighed by the share of
+// objects that have it and by where it is. Long values, and values that
+// differ in every object, are scored down.
+func inferScore(f *inferredField, n int) float64 {
+	entropy := 0.0
+	for _, count := range f.values {
+		p := float64(count) / float64(f.present)
+		entropy -= p * math.Log2(p)
+	}
+	score := (1 + entropy) * float64(f.present) / float64(n)
+	switch {
+	case strings.HasPrefix(f.path, ".status."):
+		score *= 1.5
+	case !strings.HasPrefix(f.path, ".spec."):
+		score *= 0.8
+	}
+	if depth := strings.Count(f.path, "."); depth > 3 {
+		score *= math.Pow(0.85, float64(depth-3))
+	}
+	if len(f.values) == f.present && f.present > 3 {
+		score *= 0.5
+	}
+	if f.length/f.present > 32 {
+		score *= 0.3
+	}
+	return score
+}
+
+func exampleList(values []string) string {
+	for i, v := range values {
+		if r := []rune(v); len(r) > 40 {
+			values[i] = string(r[:37]) + "..."
+		}
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,91 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kubectl-cwide/pkg/models"
+)
+
+func inferObjects() []map[string]interface{} {
+	var objs []map[string]interface{}
+	for i, phase := range []string{"Ready", "Ready", "Pending", "Failed", "Ready", "Ready"} {
+		objs = append(objs, map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Widget",
+			"metadata": map[string]interface{}{
+				"name":              fmt.Sprintf("w%d", i),
+				"namespace":         "default",
+				"uid":               fmt.Sprintf("uid-%d", i),
+				"resourceVersion":   fmt.Sprint(100 + i),
+				"creationTimestamp": "2024-01-01T00:00:00Z",
+				"managedFields":     []interface{}{map[string]interface{}{"manager": "kubectl"}},
+			},
+			"spec": map[string]interface{}{
+				"replicas": int64([]int{1, 3, 3, 2, 1, 5}[i]),
+				"size":     "large",
+				"labels":   map[string]interface{}{"example.com/tier": "web"},
+			},
+			"status": map[string]interface{}{
+				"phase":              phase,
+				"observedGeneration": int64(i),
+				"lastSync":           fmt.Sprintf("2024-01-0%dT00:00:00Z", i+1),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "True", "lastTransitionTime": "2024-01-01T00:00:00Z"},
+				},
+			},
+		})
+	}
+	return objs
+}
+
+func TestInferTemplate(t *testing.T) {
+	out, err := inferTemplate(inferObjects(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, _, err := models.ParseYAMLTemplate("", out)
+	if err != nil {
+		t.Fatalf("inferred template does not parse: %v\n%s", err, out)
+	}
+	var got []string
+	for _, c := range tmpl.Columns {
+		got = append(got, c.Header+"="+c.FieldSpec+c.Format+c.Type)
+	}
+	want := []string{
+		"NAMESPACE=.metadata.namespace",
+		"NAME=.metadata.name",
+		"PHASE=.status.phase",
+		"REPLICAS=.spec.replicasinteger",
+		"LAST_SYNC=.status.lastSyncage",
+		"CONDITIONS=",
+		"AGE=.metadata.creationTimestampage",
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("columns\n got %v\nwant %v\n%s", got, want, out)
+	}
+	if !strings.Contains(string(out), "  # .spec.size: 1 value(s) in 6/6 objects, e.g. large\n  # - header: SIZE\n") {
+		t.Errorf("runner-up .spec.size not commented out:\n%s", out)
+	}
+	for _, noise := range []string{"uid", "resourceVersion", "managedFields", "observedGeneration", "lastTransitionTime", "example.com/tier"} {
+		if strings.Contains(string(out), noise) {
+			t.Errorf("inferred template proposes %s:\n%s", noise, out)
+		}
+	}
+}
+
+func TestSampleObjects(t *testing.T) {
+	var objs []map[string]interface{}
+	for i := 0; i < 10; i++ {
+		objs = append(objs, map[string]interface{}{"i": i})
+	}
+	sample := sampleObjects(objs, 4)
+	var got []int
+	for _, o := range sample {
+		got = append(got, o["i"].(int))
+	}
+	if fmt.Sprint(got) != "[0 2 5 7]" {
+		t.Errorf("sampleObjects = %v, want [0 2 5 7]", got)
+	}
+}
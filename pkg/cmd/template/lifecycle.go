@@ -0,0 +1,571 @@
+package template
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubectl-cwide/pkg/clients"
+	"github.com/kubectl-cwide/pkg/cmd/completions"
+	"github.com/kubectl-cwide/pkg/cmd/marketplace"
+	"github.com/kubectl-cwide/pkg/common"
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+// configMapOptions are the flags of the commands that can also change the
+// keys of a template in a ConfigMap, as configmap push wrote them.
+type configMapOptions struct {
+	enabled         bool
+	name, namespace string
+	kubeContext     string
+}
+
+func (o *configMapOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&o.enabled, "configmap", false, "Also change the template's key in the ConfigMap")
+	cmd.Flags().StringVar(&o.name, "name", common.DefaultConfigMapName, "Name of the ConfigMap")
+	cmd.Flags().StringVar(&o.namespace, "cm-namespace", common.DefaultConfigMapNamespace, "Namespace of the ConfigMap")
+	cmd.Flags().StringVar(&o.kubeContext, "context", "", "The name of the kubeconfig context of the ConfigMap")
+}
+
+// update changes the data of the ConfigMap with change, which returns
+// whether it changed anything, and reports what it did.
+func (o *configMapOptions) update(cmd *cobra.Command, change func(data map[string]string) (string, bool)) error {
+	if !o.enabled {
+		return nil
+	}
+	clientset, err := clients.FactoryFromCmd(cmd, o.kubeContext).KubernetesClientSet()
+	if err != nil {
+		return fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	cmClient := clientset.CoreV1().ConfigMaps(o.namespace)
+	cm, err := cmClient.Get(cmd.Context(), o.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "NOTE ConfigMap %s/%s: not found, nothing to change\n", o.namespace, o.name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get ConfigMap %s/%s: %w", o.namespace, o.name, err)
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	msg, changed := change(cm.Data)
+	if !changed {
+		fmt.Fprintf(cmd.ErrOrStderr(), "NOTE ConfigMap %s/%s: %s\n", o.namespace, o.name, msg)
+		return nil
+	}
+	if _, err := cmClient.Update(cmd.Context(), cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s/%s: %w", o.namespace, o.name, err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Updated ConfigMap %s/%s: %s\n", o.namespace, o.name, msg)
+	return nil
+}
+
+// configMapKey is the key of template name of directory dir in a
+// ConfigMap.
+func configMapKey(dir, name string) string {
+	return dir + ".." + name
+}
+
+// findResourceDir returns the directory of resource in template root
+// root.
+func findResourceDir(root, resource string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(root, fmt.Sprintf("%s-*", resource)))
+	if err != nil {
+		return "", fmt.Errorf("failed to search for resource directories: %w", err)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no resource directory found for %q; run 'init' first", resource)
+	}
+	if len(files) != 1 {
+		return "", fmt.Errorf("found multiple directories for %q: %v; specify a more precise resource type", resource, files)
+	}
+	return filepath.Base(files[0]), nil
+}
+
+// findTemplateFile returns the path and extension of template name in
+// directory dir of root: .yaml, then .tpl.
+func findTemplateFile(root, dir, name string) (string, string, error) {
+	for _, ext := range []string{".yaml", ".tpl"} {
+		if path := filepath.Join(root, dir, name+ext); fileExists(path) {
+			return path, ext, nil
+		}
+	}
+	return "", "", fmt.Errorf("template %q not found in %s", name, filepath.Join(root, dir))
+}
+
+// checkNewTemplate fails if template name exists in directory dir of
+// root, in either format.
+func checkNewTemplate(root, dir, name string) error {
+	if strings.ContainsAny(name, `/\`) || name == "" || name == "." || name == ".." {
+		return fmt.Errorf("invalid template name %q", name)
+	}
+	if path, _, err := findTemplateFile(root, dir, name); err == nil {
+		return fmt.Errorf("template %q already exists at %s", name, path)
+	}
+	return nil
+}
+
+// warnHistory reports a failure to keep the history of path; the write it
+// follows is not failed for it.
+func warnHistory(cmd *cobra.Command, path string, err error) {
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "WARN %s: failed to keep its history: %v\n", path, err)
+	}
+}
+
+func NewCmdCopy() *cobra.Command {
+	var toResource, schemaFile string
+	var force bool
+	var cm configMapOptions
+	cpCMD := &cobra.Command{
+		Use:     "cp <new-name>",
+		Aliases: []string{"copy"},
+		Short:   "Copy a template, to a new name or to another resource",
+		Long: `Copy the template -t of resource -r to <new-name>, in the same resource
+directory or, with --to-resource, in the directory of another resource.
+
+A template copied to another resource reads the fields of the first one,
+which the second may not have: the copy is linted against the schema of
+its new kind, and the fields it reads that do not exist are warned about.
+The schema is the one the cluster serves, or, offline, that of --schema (a
+CRD manifest or an OpenAPI v3 document, as for lint). The copy is kept
+either way.
+
+The copy is kept in the history of <new-name>, and so is the template
+--force overwrites, so that rollback can restore it.
+
+With --configmap, the template's key in the ConfigMap (--name,
+--cm-namespace) is copied too.`,
+		Example: `  # Copy the default pod template to start a new one
+  kubectl cwide template cp -r pod -t default wide
+
+  # Reuse a deployment template for statefulsets
+  kubectl cwide template cp -r deployment -t rollout --to-resource statefulset rollout`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			absPath, err := utils.ResolveTemplatePath(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to resolve template path: %w", err)
+			}
+			name := cmd.Flag("template").Value.String()
+			newName := args[0]
+			dir, err := findResourceDir(absPath, cmd.Flag("resource").Value.String())
+			if err != nil {
+				return err
+			}
+			newDir := dir
+			if toResource != "" {
+				if newDir, err = findResourceDir(absPath, utils.ResolveAliasString(toResource)); err != nil {
+					return err
+				}
+			}
+			if newDir == dir && newName == name {
+				return fmt.Errorf("cannot copy template %q onto itself", name)
+			}
+			path, ext, err := findTemplateFile(absPath, dir, name)
+			if err != nil {
+				return err
+			}
+			if err := checkNewTemplate(absPath, newDir, newName); err != nil {
+				// --force overwrites a template of the same format only.
+				existing, existingExt, _ := findTemplateFile(absPath, newDir, newName)
+				if !force || existing == "" || existingExt != ext {
+					return fmt.Errorf("%w; use --force to overwrite", err)
+				}
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			newPath := filepath.Join(absPath, newDir, newName+ext)
+			historyErr, err := utils.WriteTemplateVersioned(absPath, newDir, newName, ext, data, "cp")
+			if err != nil {
+				return fmt.Errorf("failed to write template: %w", err)
+			}
+			warnHistory(cmd, newPath, historyErr)
+			fmt.Fprintf(cmd.OutOrStdout(), "Copied template: %s -> %s\n", path, newPath)
+
+			if newDir != dir {
+				if err := checkCopiedFields(cmd, newPath, data, schemaFile); err != nil {
+					return err
+				}
+			}
+			return cm.update(cmd, func(cmData map[string]string) (string, bool) {
+				value, ok := cmData[configMapKey(dir, name)]
+				if !ok {
+					return fmt.Sprintf("no key %s to copy", configMapKey(dir, name)), false
+				}
+				cmData[configMapKey(newDir, newName)] = value
+				return fmt.Sprintf("copied %s to %s", configMapKey(dir, name), configMapKey(newDir, newName)), true
+			})
+		},
+	}
+	cpCMD.Flags().StringP("resource", "r", "", "Resource type of the template to copy (e.g. pod, deployment)")
+	_ = cpCMD.RegisterFlagCompletionFunc("resource", completions.ResourceTypes)
+	cpCMD.Flags().StringP("template", "t", "default", "Name of the template to copy (without extension)")
+	_ = cpCMD.RegisterFlagCompletionFunc("template", completions.TemplateNames)
+	cpCMD.Flags().StringVar(&toResource, "to-resource", "", "Resource type to copy the template to (default: the same)")
+	_ = cpCMD.RegisterFlagCompletionFunc("to-resource", completions.ResourceTypes)
+	cpCMD.Flags().StringVar(&schemaFile, "schema", "", "Check the fields of a copy to another resource against a CRD manifest or OpenAPI v3 document")
+	cpCMD.Flags().BoolVar(&force, "force", false, "Overwrite an existing template")
+	cm.addFlags(cpCMD)
+	cpCMD.MarkFlagRequired("resource")
+	return cpCMD
+}
+
+// checkCopiedFields lints data, a template copied to path in the directory
+// of another kind, against the schema of that kind, and warns about what
+// does not lint. Without --schema the cluster's schema is used; a cluster
+// that cannot be reached is noted, not an error.
+func checkCopiedFields(cmd *cobra.Command, path string, data []byte, schemaFile string) error {
+	var schemas schemaSource
+	if schemaFile != "" {
+		file, err := newFileSchemas(schemaFile)
+		if err != nil {
+			return err
+		}
+		schemas = file
+	} else {
+		f := clients.FactoryFromCmd(cmd, cmd.Flag("context").Value.String())
+		dc, err := f.ToDiscoveryClient()
+		if err == nil {
+			_, err = dc.ServerGroups()
+		}
+		if err == nil {
+			var served *servedSchemas
+			if served, err = newServedSchemas(f); err == nil {
+				schemas = served
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "NOTE %s: cannot reach the cluster (%v); the fields it reads were not checked against its new kind, use --schema FILE to check them offline\n", path, err)
+			return nil
+		}
+	}
+	var report bytes.Buffer
+	if lintTemplate(cmd, &report, &report, path, data, schemas) == nil {
+		return nil
+	}
+	// The problems of the copy are warnings: it was copied to be fixed.
+	scanner := bufio.NewScanner(&report)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "  - "):
+			fmt.Fprintf(cmd.ErrOrStderr(), "WARN %s: %s\n", path, strings.TrimPrefix(line, "  - "))
+		case strings.HasPrefix(line, "WARN "), strings.HasPrefix(line, "NOTE "):
+			fmt.Fprintln(cmd.ErrOrStderr(), line)
+		}
+	}
+	return nil
+}
+
+func NewCmdMove() *cobra.Command {
+	var cm configMapOptions
+	mvCMD := &cobra.Command{
+		Use:     "mv <new-name>",
+		Aliases: []string{"rename"},
+		Short:   "Rename a template",
+		Long: `Rename the template -t of resource -r to <new-name>. Its history moves with
+it, and the marketplace.lock pins of the template are renamed, so that
+marketplace list still reports where it came from.
+
+With --configmap, the template's key in the ConfigMap (--name,
+--cm-namespace) is renamed too.`,
+		Example: `  # Rename a pod template
+  kubectl cwide template mv -r pod -t debug triage
+
+  # Rename it in the team's ConfigMap too
+  kubectl cwide template mv -r pod -t debug triage --configmap`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			absPath, err := utils.ResolveTemplatePath(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to resolve template path: %w", err)
+			}
+			name := cmd.Flag("template").Value.String()
+			newName := args[0]
+			dir, err := findResourceDir(absPath, cmd.Flag("resource").Value.String())
+			if err != nil {
+				return err
+			}
+			path, ext, err := findTemplateFile(absPath, dir, name)
+			if err != nil {
+				return err
+			}
+			if err := checkNewTemplate(absPath, dir, newName); err != nil {
+				return err
+			}
+			newPath := filepath.Join(absPath, dir, newName+ext)
+			if err := os.Rename(path, newPath); err != nil {
+				return fmt.Errorf("failed to rename template: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Renamed template: %s -> %s\n", path, newPath)
+			warnHistory(cmd, newPath, utils.MoveTemplateHistory(absPath, dir, name, dir, newName))
+
+			// Best-effort, as in marketplace install.
+			if lf, err := marketplace.LoadLockFile(); err == nil && lf.Rename(dir, name, newName) > 0 {
+				if err := lf.Save(); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "WARN %s: failed to rename its marketplace pin: %v\n", newPath, err)
+				}
+			}
+			return cm.update(cmd, func(cmData map[string]string) (string, bool) {
+				value, ok := cmData[configMapKey(dir, name)]
+				if !ok {
+					return fmt.Sprintf("no key %s to rename", configMapKey(dir, name)), false
+				}
+				delete(cmData, configMapKey(dir, name))
+				cmData[configMapKey(dir, newName)] = value
+				return fmt.Sprintf("renamed %s to %s", configMapKey(dir, name), configMapKey(dir, newName)), true
+			})
+		},
+	}
+	mvCMD.Flags().StringP("resource", "r", "", "Resource type of the template to rename (e.g. pod, deployment)")
+	_ = mvCMD.RegisterFlagCompletionFunc("resource", completions.ResourceTypes)
+	mvCMD.Flags().StringP("template", "t", "", "Name of the template to rename (without extension)")
+	_ = mvCMD.RegisterFlagCompletionFunc("template", completions.TemplateNames)
+	cm.addFlags(mvCMD)
+	mvCMD.MarkFlagRequired("resource")
+	mvCMD.MarkFlagRequired("template")
+	return mvCMD
+}
+
+func NewCmdRemove() *cobra.Command {
+	var cm configMapOptions
+	rmCMD := &cobra.Command{
+		Use:     "rm",
+		Aliases: []string{"remove", "delete"},
+		Short:   "Remove a template",
+		Long: `Remove the template -t of resource -r. Its content is kept in its history,
+so that rollback can restore it, and its marketplace.lock pins are
+removed.
+
+With --configmap, the template's key in the ConfigMap (--name,
+--cm-namespace) is removed too.`,
+		Example: `  # Remove a pod template
+  kubectl cwide template rm -r pod -t debug
+
+  # Restore it
+  kubectl cwide template rollback -r pod -t debug`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			absPath, err := utils.ResolveTemplatePath(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to resolve template path: %w", err)
+			}
+			name := cmd.Flag("template").Value.String()
+			dir, err := findResourceDir(absPath, cmd.Flag("resource").Value.String())
+			if err != nil {
+				return err
+			}
+			path, ext, err := findTemplateFile(absPath, dir, name)
+			if err != nil {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			// Unlike the writes that follow it, a removal whose content
+			// cannot be kept is not done.
+			h, err := utils.LoadTemplateHistory(absPath, dir, name)
+			if err != nil {
+				return fmt.Errorf("failed to keep the history of %s: %w", path, err)
+			}
+			if _, err := h.Add(data, ext, "before rm"); err != nil {
+				return fmt.Errorf("failed to keep the history of %s: %w", path, err)
+			}
+			if err := h.AddDeleted("rm"); err != nil {
+				return fmt.Errorf("failed to keep the history of %s: %w", path, err)
+			}
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove template: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed template: %s\n", path)
+
+			if lf, err := marketplace.LoadLockFile(); err == nil && lf.Remove(dir, name) > 0 {
+				if err := lf.Save(); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "WARN %s: failed to remove its marketplace pin: %v\n", path, err)
+				}
+			}
+			return cm.update(cmd, func(cmData map[string]string) (string, bool) {
+				if _, ok := cmData[configMapKey(dir, name)]; !ok {
+					return fmt.Sprintf("no key %s to remove", configMapKey(dir, name)), false
+				}
+				delete(cmData, configMapKey(dir, name))
+				return fmt.Sprintf("removed %s", configMapKey(dir, name)), true
+			})
+		},
+	}
+	rmCMD.Flags().StringP("resource", "r", "", "Resource type of the template to remove (e.g. pod, deployment)")
+	_ = rmCMD.RegisterFlagCompletionFunc("resource", completions.ResourceTypes)
+	rmCMD.Flags().StringP("template", "t", "", "Name of the template to remove (without extension)")
+	_ = rmCMD.RegisterFlagCompletionFunc("template", completions.TemplateNames)
+	cm.addFlags(rmCMD)
+	rmCMD.MarkFlagRequired("resource")
+	rmCMD.MarkFlagRequired("template")
+	return rmCMD
+}
+
+func NewCmdHistory() *cobra.Command {
+	historyCMD := &cobra.Command{
+		Use:   "history",
+		Short: "List the versions of a template",
+		Long: `List the versions of the template -t of resource -r that edit, cp,
+configmap sync, marketplace install, rm and rollback kept, oldest first,
+with what wrote them. The content a write replaced is kept too, as "before <action>",
+when it is not the latest version already.
+
+Versions are kept locally, in ~/.kubectl-cwide/history, for each template
+root.`,
+		Example: `  # List the versions of the default pod template
+  kubectl cwide template history -r pod`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			absPath, err := utils.ResolveTemplatePath(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to resolve template path: %w", err)
+			}
+			name := cmd.Flag("template").Value.String()
+			dir, err := findResourceDir(absPath, cmd.Flag("resource").Value.String())
+			if err != nil {
+				return err
+			}
+			h, err := utils.LoadTemplateHistory(absPath, dir, name)
+			if err != nil {
+				return err
+			}
+			if len(h.Versions) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "No history for template %q of %s.\n", name, dir)
+				return nil
+			}
+			return writeHistory(cmd.OutOrStdout(), h)
+		},
+	}
+	historyCMD.Flags().StringP("resource", "r", "", "Resource type of the template (e.g. pod, deployment)")
+	_ = historyCMD.RegisterFlagCompletionFunc("resource", completions.ResourceTypes)
+	historyCMD.Flags().StringP("template", "t", "default", "Name of the template (without extension)")
+	_ = historyCMD.RegisterFlagCompletionFunc("template", completions.TemplateNames)
+	historyCMD.MarkFlagRequired("resource")
+	return historyCMD
+}
+
+// writeHistory writes the versions of h as a table.
+func writeHistory(out io.Writer, h *utils.TemplateHistory) error {
+	w := utils.NewTabWriter(out)
+	fmt.Fprintln(w, "VERSION\tTIME\tACTION\tFORMAT\tSIZE")
+	for _, v := range h.Versions {
+		format, size := strings.TrimPrefix(v.Ext, "."), "-"
+		if v.Deleted {
+			format = "-"
+		} else if info, err := os.Stat(filepath.Join(h.Dir, strconv.Itoa(v.Version)+v.Ext)); err == nil {
+			size = strconv.FormatInt(info.Size(), 10)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", v.Version, v.Time.Local().Format("2006-01-02 15:04:05"), v.Action, format, size)
+	}
+	return w.Flush()
+}
+
+func NewCmdRollback() *cobra.Command {
+	var to int
+	rollbackCMD := &cobra.Command{
+		Use:   "rollback",
+		Short: "Restore a version of a template",
+		Long: `Write version --to of the template -t of resource -r, as listed by
+history, back to the template. Without --to, the version before the latest
+is restored: the template as it was before the last edit, sync or install,
+or a removed template.
+
+The rollback is a version of its own, so it can be rolled back too; the
+content it replaces is kept first, if it is not a version already.`,
+		Example: `  # Undo the last configmap sync of the default pod template
+  kubectl cwide template rollback -r pod
+
+  # Restore version 3
+  kubectl cwide template rollback -r pod -t default --to 3`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			absPath, err := utils.ResolveTemplatePath(cmd)
+			if err != nil {
+				return fmt.Errorf("failed to resolve template path: %w", err)
+			}
+			name := cmd.Flag("template").Value.String()
+			dir, err := findResourceDir(absPath, cmd.Flag("resource").Value.String())
+			if err != nil {
+				return err
+			}
+			h, err := utils.LoadTemplateHistory(absPath, dir, name)
+			if err != nil {
+				return err
+			}
+			v, err := rollbackVersion(h, to)
+			if err != nil {
+				return fmt.Errorf("template %q of %s: %w", name, dir, err)
+			}
+			data, err := h.Content(v)
+			if err != nil {
+				return err
+			}
+			action := fmt.Sprintf("rollback to %d", v.Version)
+			// A version in the other format replaces the current file.
+			if path, ext, err := findTemplateFile(absPath, dir, name); err == nil && ext != v.Ext {
+				current, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				if _, err := h.Add(current, ext, "before "+action); err != nil {
+					return fmt.Errorf("failed to keep the history of %s: %w", path, err)
+				}
+				if err := os.Remove(path); err != nil {
+					return err
+				}
+			}
+			historyErr, err := utils.WriteTemplateVersioned(absPath, dir, name, v.Ext, data, action)
+			path := filepath.Join(absPath, dir, name+v.Ext)
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+			warnHistory(cmd, path, historyErr)
+			fmt.Fprintf(cmd.OutOrStdout(), "Rolled back template %s to version %d (%s).\n", path, v.Version, v.Action)
+			return nil
+		},
+	}
+	rollbackCMD.Flags().StringP("resource", "r", "", "Resource type of the template (e.g. pod, deployment)")
+	_ = rollbackCMD.RegisterFlagCompletionFunc("resource", completions.ResourceTypes)
+	rollbackCMD.Flags().StringP("template", "t", "default", "Name of the template (without extension)")
+	_ = rollbackCMD.RegisterFlagCompletionFunc("template", completions.TemplateNames)
+	rollbackCMD.Flags().IntVar(&to, "to", 0, "Version to restore, as listed by history (default: the one before the latest)")
+	rollbackCMD.MarkFlagRequired("resource")
+	return rollbackCMD
+}
+
+// rollbackVersion returns the version of h to restore: version to, or,
+// when to is 0, the last version with content before the latest.
+func rollbackVersion(h *utils.TemplateHistory, to int) (utils.TemplateVersion, error) {
+	if to != 0 {
+		v, ok := h.Find(to)
+		if !ok {
+			return v, fmt.Errorf("no version %d; see template history", to)
+		}
+		if v.Deleted {
+			return v, fmt.Errorf("version %d is the removal of the template", to)
+		}
+		return v, nil
+	}
+	for i := len(h.Versions) - 2; i >= 0; i-- {
+		if !h.Versions[i].Deleted {
+			return h.Versions[i], nil
+		}
+	}
+	return utils.TemplateVersion{}, fmt.Errorf("no earlier version to roll back to")
+}
@@ -0,0 +1,132 @@
+package template
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+func TestTemplateLifecycle(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	dir := filepath.Join(root, "pod--v1")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	original := "columns:\n  - header: NAME\n    fieldSpec: .metadata.name\n"
+	if err := os.WriteFile(filepath.Join(dir, "default.yaml"), []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(newCmd func() *cobra.Command, args ...string) (string, error) {
+		var out bytes.Buffer
+		cmd := newCmd()
+		cmd.Flags().String("template-path", "", "")
+		cmd.SetArgs(append(args, "--template-path", root))
+		cmd.SetOut(&out)
+		cmd.SetErr(io.Discard)
+		cmd.SilenceUsage = true
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	if _, err := run(NewCmdCopy, "-r", "pod", "wide"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(NewCmdCopy, "-r", "pod", "wide"); err == nil {
+		t.Error("cp overwrote an existing template without --force")
+	}
+	// A forced copy over an existing template can be rolled back.
+	if err := os.WriteFile(filepath.Join(dir, "wide.yaml"), []byte("columns: [wide]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(NewCmdCopy, "-r", "pod", "wide", "--force"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "wide.yaml")); string(got) != original {
+		t.Fatalf("cp --force did not overwrite wide.yaml:\n%s", got)
+	}
+	if _, err := run(NewCmdRollback, "-r", "pod", "-t", "wide"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "wide.yaml")); string(got) != "columns: [wide]\n" {
+		t.Fatalf("rollback did not restore the template cp --force overwrote:\n%s", got)
+	}
+	// And the rollback itself.
+	if _, err := run(NewCmdRollback, "-r", "pod", "-t", "wide"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "wide.yaml")); string(got) != original {
+		t.Fatalf("rollback did not undo the rollback:\n%s", got)
+	}
+	if err := os.RemoveAll(filepath.Join(os.Getenv("HOME"), ".kubectl-cwide", "history")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := run(NewCmdMove, "-r", "pod", "-t", "wide", "narrow"); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(filepath.Join(dir, "wide.yaml")) || !fileExists(filepath.Join(dir, "narrow.yaml")) {
+		t.Fatal("mv did not rename wide.yaml to narrow.yaml")
+	}
+
+	// A sync overwrites the template, then it is removed.
+	if _, err := utils.WriteTemplateVersioned(root, "pod--v1", "narrow", ".yaml", []byte("columns: []\n"), "sync"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := run(NewCmdRemove, "-r", "pod", "-t", "narrow"); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(filepath.Join(dir, "narrow.yaml")) {
+		t.Fatal("rm did not remove narrow.yaml")
+	}
+	out, err := run(NewCmdHistory, "-r", "pod", "-t", "narrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"before sync", "sync", "rm"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("history has no %q:\n%s", want, out)
+		}
+	}
+
+	// The removed template is restored, then the sync is undone.
+	if _, err := run(NewCmdRollback, "-r", "pod", "-t", "narrow"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "narrow.yaml")); string(got) != "columns: []\n" {
+		t.Errorf("rollback did not restore the removed template:\n%s", got)
+	}
+	if _, err := run(NewCmdRollback, "-r", "pod", "-t", "narrow", "--to", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "narrow.yaml")); string(got) != original {
+		t.Errorf("rollback --to 1 did not undo the sync:\n%s", got)
+	}
+	if _, err := run(NewCmdRollback, "-r", "pod", "-t", "narrow", "--to", "3"); err == nil {
+		t.Error("rolled back to the removal of the template")
+	}
+}
+
+func TestRollbackVersion(t *testing.T) {
+	h := &utils.TemplateHistory{Versions: []utils.TemplateVersion{
+		{Version: 1, Ext: ".yaml"},
+		{Version: 2, Deleted: true},
+		{Version: 3, Ext: ".yaml"},
+	}}
+	if v, err := rollbackVersion(h, 0); err != nil || v.Version != 1 {
+		t.Errorf("default: got %d, %v; want 1", v.Version, err)
+	}
+	if _, err := rollbackVersion(h, 9); err == nil {
+		t.Error("rolled back to a version that does not exist")
+	}
+	if _, err := rollbackVersion(&utils.TemplateHistory{Versions: h.Versions[:1]}, 0); err == nil {
+		t.Error("rolled back without an earlier version")
+	}
+}
@@ -2,89 +2,317 @@ package template
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
+	"text/template/parse"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 	"k8s.io/client-go/util/jsonpath"
 
+	"github.com/kubectl-cwide/pkg/clients"
+	"github.com/kubectl-cwide/pkg/cmd/get"
+	"github.com/kubectl-cwide/pkg/common"
 	"github.com/kubectl-cwide/pkg/models"
+	"github.com/kubectl-cwide/pkg/parser"
+	"github.com/kubectl-cwide/pkg/utils"
 )
 
 func NewCmdLint() *cobra.Command {
-	return &cobra.Command{
+	var checkServed bool
+	var schemaFile string
+	var kubeContext string
+	cmd := &cobra.Command{
 		Use:   "lint <template-file>",
 		Short: "Statically validate a column template file",
 		Long: `Parse a .yaml or .tpl template and check that:
-  - the file is syntactically valid
+  - the file is syntactically valid; a .tpl has as many field specs as headers
   - every JSONPath field spec parses cleanly
-  - every text/template body parses cleanly (best-effort — no execution)
+  - every text/template body (columns, helpers, funcs) parses with the cwide
+    functions, and every {{template "name"}} it calls is defined, in the file
+    or in the _shared helpers
 
-Does NOT contact the cluster or resolve schema against a live API.`,
+With a schema, the fields each column reads are also checked: the segments
+of JSONPath field specs and the .field accesses of Go templates must exist
+in the schema (lint suggests the closest field when one does not), and
+reading a field of an array without an index, filter or range is warned
+about. The kind is taken from the template's directory
+(<kind>-<group>[-<version>]), and the schema from:
+  --check-served   the cluster: the OpenAPI v3 schema of the version it
+                   serves, which may differ from the template's version
+  --schema FILE    a file, offline: CustomResourceDefinition manifests, or
+                   an OpenAPI v3 document saved from the API server
+                   (kubectl get --raw /openapi/v3/apis/<group>/<version>)
+
+Without one, lint does not contact the cluster or check fields.`,
 		Example: `  # Lint one template
   kubectl cwide template lint ~/.kubectl-cwide/templates/pod--v1/default.yaml
 
   # Lint every template under a directory
-  find ~/.kubectl-cwide/templates -name '*.yaml' -exec kubectl cwide template lint {} \;`,
+  find ~/.kubectl-cwide/templates -name '*.yaml' -exec kubectl cwide template lint {} \;
+
+  # Check that the fields a v2beta2 template reads exist in the version the cluster serves
+  kubectl cwide template lint --check-served ~/.kubectl-cwide/templates/horizontalpodautoscaler-autoscaling-v2beta2/default.yaml
+
+  # Check a CRD's template against the CRD, offline
+  kubectl cwide template lint --schema config/crd/widgets.yaml ~/.kubectl-cwide/templates/widget-example.com-v1/default.yaml`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return lintOne(cmd, args[0])
+			var schemas schemaSource
+			switch {
+			case checkServed && schemaFile != "":
+				return fmt.Errorf("--check-served and --schema cannot be used together")
+			case checkServed:
+				served, err := newServedSchemas(clients.FactoryFromCmd(cmd, kubeContext))
+				if err != nil {
+					return err
+				}
+				schemas = served
+			case schemaFile != "":
+				file, err := newFileSchemas(schemaFile)
+				if err != nil {
+					return err
+				}
+				schemas = file
+			}
+			return lintOne(cmd, args[0], schemas)
 		},
 	}
+	cmd.Flags().BoolVar(&checkServed, "check-served", false, "Check fields against the schema of the version the cluster serves")
+	cmd.Flags().StringVar(&schemaFile, "schema", "", "Check fields against the schema in a CRD manifest or OpenAPI v3 document")
+	cmd.Flags().StringVar(&kubeContext, "context", "", "The name of the kubeconfig context to use with --check-served")
+	return cmd
 }
 
-func lintOne(cmd *cobra.Command, path string) error {
+// lintColumn is a column as lint checks it: by a JSONPath fieldSpec or a
+// Go template, read from source in the template file.
+type lintColumn struct {
+	header    string
+	fieldSpec string
+	template  string
+	source    models.ScalarPos
+}
+
+// lintOne lints the template at path. With schemas, the fields the columns
+// read are also checked against the schema of the template's kind.
+func lintOne(cmd *cobra.Command, path string, schemas schemaSource) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("read %s: %w", path, err)
 	}
+	return lintTemplate(cmd, cmd.OutOrStdout(), cmd.ErrOrStderr(), path, data, schemas)
+}
 
+// lintTemplate lints data, the template at path, and reports to out and
+// errOut. cmd resolves the shared helpers.
+func lintTemplate(cmd *cobra.Command, out, errOut io.Writer, path string, data []byte, schemas schemaSource) error {
 	var problems []string
+	var columns []lintColumn
+	var helpers string
+	var funcs map[string]string
+	sources := models.TemplateSources{}
 	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
 	case ".yaml", ".yml":
-		var tmpl models.YAMLTemplate
-		if err := yaml.Unmarshal(data, &tmpl); err != nil {
+		tmpl, pos, err := models.ParseYAMLTemplate(path, data)
+		if err != nil {
 			return fmt.Errorf("yaml parse: %w", err)
 		}
 		if len(tmpl.Columns) == 0 {
 			problems = append(problems, "template has no columns")
 		}
 		for i, c := range tmpl.Columns {
+			var colPos models.YAMLColumnPositions
+			if i < len(pos.Columns) {
+				colPos = pos.Columns[i]
+			}
+			at := func(msg string) string { return located(colPos.Item, msg) }
 			if c.Header == "" {
-				problems = append(problems, fmt.Sprintf("column[%d]: missing header", i))
+				problems = append(problems, at(fmt.Sprintf("column[%d]: missing header", i)))
 			}
 			if c.FieldSpec == "" && c.Template == "" {
-				problems = append(problems, fmt.Sprintf("column[%d] (%s): needs fieldSpec or template", i, c.Header))
+				problems = append(problems, at(fmt.Sprintf("column[%d] (%s): needs fieldSpec or template", i, c.Header)))
 			}
-			if c.FieldSpec != "" {
-				if err := parseJSONPath(c.FieldSpec); err != nil {
-					problems = append(problems, fmt.Sprintf("column[%d] (%s): bad fieldSpec: %v", i, c.Header, err))
-				}
+			if err := parser.ValidateFormat(c.Format); err != nil {
+				problems = append(problems, at(fmt.Sprintf("column[%d] (%s): %v", i, c.Header, err)))
+			}
+			if err := (parser.ListOptions{MaxItems: c.MaxItems}).Validate(); err != nil {
+				problems = append(problems, at(fmt.Sprintf("column[%d] (%s): %v", i, c.Header, err)))
+			}
+			if err := parser.ValidateType(c.Type); err != nil {
+				problems = append(problems, at(fmt.Sprintf("column[%d] (%s): %v", i, c.Header, err)))
 			}
+			// get renders the template of a column that has both.
+			col := lintColumn{header: c.Header, template: c.Template, source: colPos.Template}
+			if c.Template == "" && c.FieldSpec != common.DefaultPrinterField {
+				col.fieldSpec, col.source = c.FieldSpec, colPos.FieldSpec
+			}
+			columns = append(columns, col)
+		}
+		// As in get, the shared helpers are only added to templates
+		// without helpers of their own.
+		helpers, funcs = tmpl.Helpers, tmpl.Funcs
+		sources["local"] = pos.Helpers
+		for name, p := range pos.Funcs {
+			sources["__func_"+name] = p
+		}
+		if helpers == "" {
+			helpers = sharedHelpers(cmd, path)
+			delete(sources, "local")
 		}
 	case ".tpl":
-		lines := strings.Split(string(data), "\n")
+		lines := strings.SplitN(string(data), "\n", 3)
 		if len(lines) < 2 {
 			problems = append(problems, "tpl needs at least a header line and a spec line")
+			break
+		}
+		headers := get.SplitIgnoringTemplateSpaces(lines[0])
+		specs := get.SplitIgnoringTemplateSpaces(lines[1])
+		if len(headers) != len(specs) {
+			problems = append(problems, fmt.Sprintf("%d header(s) but %d field spec(s)", len(headers), len(specs)))
+		}
+		for i := 0; i < len(headers) && i < len(specs); i++ {
+			col := lintColumn{header: headers[i], fieldSpec: specs[i]}
+			if parser.IsTemplate(specs[i]) {
+				col.fieldSpec, col.template = "", specs[i]
+			}
+			columns = append(columns, col)
+		}
+		helpers = sharedHelpers(cmd, path)
+		if len(lines) == 3 {
+			helpers += "\n" + lines[2]
 		}
 	default:
 		return fmt.Errorf("unsupported extension %q (want .yaml, .yml, or .tpl)", ext)
 	}
 
+	var ks *kindSchema
+	if schemas != nil {
+		var note string
+		var err error
+		if ks, note, err = schemas.forDir(filepath.Base(filepath.Dir(path))); err != nil {
+			problems = append(problems, err.Error())
+		} else if note != "" {
+			fmt.Fprintf(errOut, "NOTE %s: %s\n", path, note)
+		}
+	}
+	colProblems, warnings := lintColumns(columns, helpers, funcs, sources, ks)
+	problems = append(problems, colProblems...)
+
+	for _, w := range warnings {
+		fmt.Fprintf(errOut, "WARN %s: %s\n", path, w)
+	}
 	if len(problems) == 0 {
-		fmt.Fprintf(cmd.OutOrStdout(), "OK  %s\n", path)
+		fmt.Fprintf(out, "OK  %s\n", path)
 		return nil
 	}
-	fmt.Fprintf(cmd.ErrOrStderr(), "FAIL %s\n", path)
+	fmt.Fprintf(errOut, "FAIL %s\n", path)
 	for _, p := range problems {
-		fmt.Fprintf(cmd.ErrOrStderr(), "  - %s\n", p)
+		fmt.Fprintf(errOut, "  - %s\n", p)
 	}
 	return fmt.Errorf("%d issue(s)", len(problems))
 }
 
+// lintColumns parses the fieldSpecs and Go templates of columns, with the
+// helpers and funcs of their file, and checks the templates they call. With
+// ks, the fields they read are checked against it: missing fields are
+// problems, arrays read without an index are warnings. Problems in the
+// templates of sources are reported at their position in the file.
+func lintColumns(columns []lintColumn, helpers string, funcs map[string]string, sources models.TemplateSources, ks *kindSchema) (problems, warnings []string) {
+	// at returns the file position of node of t, if known.
+	at := func(t *template.Template, node parse.Node) models.SourcePos {
+		location, _ := t.Tree.ErrorContext(node)
+		pos, _ := sources.At(location)
+		return pos
+	}
+	report := func(pos models.SourcePos, prefix string, issue *fieldIssue, advice string) {
+		msg := located(pos, prefix+": "+issue.describe(ks, advice))
+		if issue.array {
+			warnings = append(warnings, msg)
+		} else {
+			problems = append(problems, msg)
+		}
+	}
+
+	tmpls, err := newLintTemplates(helpers, funcs, sources)
+	if err != nil {
+		return []string{err.Error()}, nil
+	}
+	for _, t := range tmpls.helpers() {
+		_, undefined := tmpls.check(t, nil)
+		for _, n := range undefined {
+			problems = append(problems, located(at(t, n), fmt.Sprintf("%s: template %q is not defined", t.Name(), n.Name)))
+		}
+	}
+	for i, c := range columns {
+		prefix := fmt.Sprintf("column[%d] (%s)", i, c.header)
+		switch {
+		case c.template != "":
+			sources[fmt.Sprintf("column%d", i)] = c.source
+			t, err := tmpls.column(i, c.template)
+			if err != nil {
+				problems = append(problems, sources.Wrap(err, prefix+": bad template").Error())
+				continue
+			}
+			issues, undefined := tmpls.check(t, ks)
+			for _, n := range undefined {
+				problems = append(problems, located(at(t, n), fmt.Sprintf("%s: template %q is not defined", prefix, n.Name)))
+			}
+			for _, issue := range issues {
+				report(at(t, issue.node), prefix+": template", issue, "read its items with range or index")
+			}
+		case c.fieldSpec != "":
+			spec, err := get.RelaxedJSONPathExpression(c.fieldSpec)
+			if err == nil {
+				err = parseJSONPath(spec)
+			}
+			if err != nil {
+				problems = append(problems, located(c.source.Start, fmt.Sprintf("%s: bad fieldSpec: %v", prefix, err)))
+				continue
+			}
+			if ks == nil {
+				continue
+			}
+			if issue, err := ks.fieldSpecIssue(spec); err == nil && issue != nil {
+				report(c.source.At(1, segmentOffset(c.fieldSpec, issue.path)), prefix+": fieldSpec "+c.fieldSpec, issue, "read its items with [*], an index or a filter")
+			}
+		}
+	}
+	return problems, warnings
+}
+
+// segmentOffset returns the offset in fieldSpec of the last field of path,
+// or -1.
+func segmentOffset(fieldSpec, path string) int {
+	name := path[strings.LastIndex(path, ".")+1:]
+	if i := strings.LastIndex(fieldSpec, "."+name); i >= 0 {
+		return i + 1
+	}
+	return -1
+}
+
+// located prefixes msg with pos, if known.
+func located(pos models.SourcePos, msg string) string {
+	if !pos.IsKnown() {
+		return msg
+	}
+	return pos.String() + ": " + msg
+}
+
+// sharedHelpers returns the _shared helpers get would add to the template
+// at path: those of its own template root first, then those of the
+// template search path.
+func sharedHelpers(cmd *cobra.Command, path string) string {
+	root := filepath.Dir(filepath.Dir(path))
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	layers := append(utils.TemplateSearchPath{utils.DirLayer(utils.LayerUser, root)}, utils.ResolveTemplateSearchPath(cmd, nil)...)
+	return layers.SharedHelpers()
+}
+
 func parseJSONPath(expr string) error {
 	// Accept either bare `.foo.bar` or `{.foo.bar}` form; normalize to braces.
 	e := expr
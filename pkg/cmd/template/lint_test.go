@@ -1,8 +1,11 @@
 package template
 
 import (
+	"bytes"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -21,7 +24,7 @@ func TestLintGoodYAML(t *testing.T) {
 		t.Fatal(err)
 	}
 	cmd := &cobra.Command{}
-	if err := lintOne(cmd, good); err != nil {
+	if err := lintOne(cmd, good, nil); err != nil {
 		t.Fatalf("good template errored: %v", err)
 	}
 }
@@ -36,7 +39,7 @@ func TestLintMissingHeader(t *testing.T) {
 		t.Fatal(err)
 	}
 	cmd := &cobra.Command{}
-	if err := lintOne(cmd, bad); err == nil {
+	if err := lintOne(cmd, bad, nil); err == nil {
 		t.Fatal("missing header should have failed lint")
 	}
 }
@@ -48,7 +51,189 @@ func TestLintUnsupportedExt(t *testing.T) {
 		t.Fatal(err)
 	}
 	cmd := &cobra.Command{}
-	if err := lintOne(cmd, bad); err == nil {
+	if err := lintOne(cmd, bad, nil); err == nil {
 		t.Fatal("wrong extension should have failed")
 	}
 }
+
+// lintFile writes body to dir/name under a temporary template root, lints
+// it and returns the error and everything written to stderr.
+func lintFile(t *testing.T, dir, name, body string, schemas schemaSource) (error, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(&stderr)
+	err := lintOne(cmd, path, schemas)
+	return err, stderr.String()
+}
+
+func TestLintTPL(t *testing.T) {
+	good := "NAME OWNER\n.metadata.name {{ template \"owner\" . }}\n{{ define \"owner\" }}{{ .metadata.namespace }}{{ end }}\n"
+	if err, out := lintFile(t, "pod--v1", "good.tpl", good, nil); err != nil {
+		t.Fatalf("good tpl errored: %v\n%s", err, out)
+	}
+
+	cases := map[string]string{
+		"NAME STATUS\n.metadata.name\n":                                           "2 header(s) but 1 field spec(s)",
+		"NAME OWNER\n.metadata.name {{ template \"owner\" . }}\n":                 `column[1] (OWNER): template "owner" is not defined`,
+		"NAME AGE\n.metadata.name {{ nosuchfunc .metadata.creationTimestamp }}\n": `function "nosuchfunc" not defined`,
+		"NAME\n.metadata.name\n{{ define \"x\" }}{{ template \"y\" }}{{ end }}\n": `x: template "y" is not defined`,
+	}
+	for body, want := range cases {
+		err, out := lintFile(t, "pod--v1", "bad.tpl", body, nil)
+		if err == nil || !strings.Contains(out, want) {
+			t.Errorf("%q: got %v\n%s\nwant %q", body, err, out, want)
+		}
+	}
+}
+
+func TestLintUndefinedTemplateYAML(t *testing.T) {
+	body := `helpers: |
+  {{ define "phase" }}{{ .status.phase }}{{ end }}
+columns:
+  - header: PHASE
+    template: '{{ template "phase" . }}'
+  - header: NODE
+    template: '{{ template "node" . }}'
+`
+	err, out := lintFile(t, "pod--v1", "bad.yaml", body, nil)
+	if err == nil || !strings.Contains(out, `column[1] (NODE): template "node" is not defined`) || strings.Contains(out, `"phase"`) {
+		t.Fatalf("got %v\n%s", err, out)
+	}
+}
+
+const widgetCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names: {kind: Widget, plural: widgets}
+  scope: Namespaced
+  versions:
+    - name: v1
+      served: true
+      storage: true
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              properties:
+                replicas: {type: integer}
+                ports:
+                  type: array
+                  items:
+                    type: object
+                    properties:
+                      name: {type: string}
+                      port: {type: integer}
+            status:
+              type: object
+              x-kubernetes-preserve-unknown-fields: true
+`
+
+func TestLintSchemaFile(t *testing.T) {
+	crd := filepath.Join(t.TempDir(), "widgets.yaml")
+	if err := os.WriteFile(crd, []byte("---\n"+widgetCRD), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := newFileSchemas(crd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	good := `columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: REPLICAS
+    fieldSpec: .spec.replicas
+  - header: PORTS
+    template: '{{ range $p := .spec.ports }}{{ $p.name }}:{{ .port }} {{ end }}'
+  - header: FIRST
+    template: '{{ with index .spec.ports 0 }}{{ .name }}{{ end }}'
+  - header: READY
+    template: '{{ .status.anything.goes }}'
+`
+	if err, out := lintFile(t, "widget-example.com-v1", "good.yaml", good, schemas); err != nil {
+		t.Fatalf("good template errored: %v\n%s", err, out)
+	}
+
+	bad := `columns:
+  - header: REPLICAS
+    fieldSpec: .spec.replica
+  - header: PORTS
+    template: '{{ range .spec.ports }}{{ .nmae }}{{ end }}'
+  - header: PORT
+    fieldSpec: .spec.ports.port
+`
+	err, out := lintFile(t, "widget-example.com-v1beta1", "bad.yaml", bad, schemas)
+	if err == nil {
+		t.Fatalf("bad template passed\n%s", out)
+	}
+	for _, want := range []string{
+		"NOTE ", "template is for v1beta1; checked against example.com/v1",
+		"column[0] (REPLICAS): fieldSpec .spec.replica: .spec.replica does not exist in example.com/v1 Widget; did you mean .spec.replicas?",
+		"column[1] (PORTS): template: .spec.ports[*].nmae does not exist in example.com/v1 Widget; did you mean .spec.ports[*].name?",
+		"WARN ", "column[2] (PORT): fieldSpec .spec.ports.port: .spec.ports is an array; read its items with [*], an index or a filter",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "  - column[2]") {
+		t.Errorf("array access should only warn:\n%s", out)
+	}
+}
+
+func TestLintPositions(t *testing.T) {
+	crd := filepath.Join(t.TempDir(), "widgets.yaml")
+	if err := os.WriteFile(crd, []byte(widgetCRD), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := newFileSchemas(crd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: REPLICAS
+    fieldSpec: .spec.replica
+  - header: PORTS
+    template: |
+      {{- range .spec.ports }}
+        {{- .nmae }} {{ template "port" . }}
+      {{- end }}
+  - header: BAD
+    template: '{{ .metadata.name }'
+  - header: STATUS
+    fieldSpec: .status.phase
+    format: fortnights
+`
+	err, out := lintFile(t, "widget-example.com-v1", "bad.yaml", body, schemas)
+	if err == nil {
+		t.Fatalf("bad template passed\n%s", out)
+	}
+	for _, want := range []string{
+		"bad.yaml:5:22: column[1] (REPLICAS): fieldSpec .spec.replica: .spec.replica does not exist",
+		"bad.yaml:9:13: column[2] (PORTS): template: .spec.ports[*].nmae does not exist",
+		`bad.yaml:9:34: column[2] (PORTS): template "port" is not defined`,
+		`bad.yaml:12:16: column[3] (BAD): bad template: unexpected "}" in operand`,
+		`bad.yaml:13:5: column[4] (STATUS): unknown format "fortnights"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
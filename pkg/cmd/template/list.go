@@ -1,66 +1,345 @@
 package template
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"path/filepath"
+	"io"
 	"strings"
 
+	"github.com/kubectl-cwide/pkg/clients"
 	"github.com/kubectl-cwide/pkg/cmd/completions"
+	"github.com/kubectl-cwide/pkg/cmd/marketplace"
+	"github.com/kubectl-cwide/pkg/models"
 	"github.com/kubectl-cwide/pkg/utils"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
 )
 
 func NewCmdTemplateList() *cobra.Command {
+	var output string
 	templateCMD := &cobra.Command{
 		Use:        "list",
 		Aliases:    []string{"ls"},
 		SuggestFor: []string{"show"},
-		Short:      "List available templates for a resource type",
-		Long: `List all column templates available for the specified resource type.
+		Short:      "List available templates",
+		Long: `List the column templates available for a resource type, or the inventory
+of every template.
 
-Templates are discovered from both .yaml and .tpl files in the template
-directory. Duplicates (same name, different extension) are shown once.`,
+Templates are discovered from both .yaml and .tpl files in every layer of
+the template search path: the project's .cwide/templates, the user template
+root, the team roots in config.yaml, the cached copies of the ConfigMaps in
+templateSources and the built-in templates.
+
+With -r, each template of the resource type is listed once, with the layer
+it is used from and the copies in later layers it shadows. Duplicates within
+a layer (same name, different extension) are shown once.
+
+Without -r, or with -o json, the inventory of the templates in effect is
+listed: for each, its directory and kind, name, format, number of columns,
+source, whether it lints, and whether the cluster of the current context
+still serves its kind. The source is the layer (project, user, team,
+configmap, builtin), or marketplace for a user template pinned in
+marketplace.lock. The lint status is ok, warn or fail, as template lint
+reports it without a schema. The served status is yes, no, or unknown when
+the cluster cannot be reached.
+
+-o json prints the inventory, with the lint messages, the marketplace pins
+and the layers searched, for scripts.`,
 		Example: `  # List all templates for pods
   kubectl cwide template list -r pod
 
   # List templates from a specific directory
-  kubectl cwide template list -r deployment --template-path ~/my-templates`,
+  kubectl cwide template list -r deployment --template-path ~/my-templates
+
+  # Inventory of every template
+  kubectl cwide template list
+
+  # Templates that do not lint, or whose kind the cluster no longer serves
+  kubectl cwide template list -o json | jq '.templates[] | select(.lint == "fail" or .served == false)'`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			absPath, err := utils.ResolveTemplatePath(cmd)
-			if err != nil {
-				return fmt.Errorf("failed to resolve template path: %w", err)
+			if output != "" && output != "json" {
+				return fmt.Errorf("unsupported output format %q (want json)", output)
 			}
-
 			resourceType := cmd.Flag("resource").Value.String()
-
-			// list templates from both .yaml and .tpl files, deduplicating by name
-			seen := make(map[string]bool)
-			for _, ext := range []string{"*.yaml", "*.tpl"} {
-				pattern := filepath.Join(absPath, fmt.Sprintf("%s-*/%s", resourceType, ext))
-				files, err := filepath.Glob(pattern)
-				if err != nil {
-					return fmt.Errorf("failed to search for templates: %w", err)
-				}
-				for _, file := range files {
-					name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
-					if !seen[name] {
-						seen[name] = true
-						fmt.Println(name)
-					}
-				}
+			// ConfigMap sources are listed from their cache, as last read by get.
+			var configMaps utils.ConfigMapLayerFunc
+			restConfig, restErr := clients.FactoryFromCmd(cmd, "").ToRESTConfig()
+			if restErr == nil {
+				configMaps = utils.ConfigMapCacheLayers(restConfig.Host)
 			}
-
-			if len(seen) == 0 {
+			layers := utils.ResolveTemplateSearchPath(cmd, configMaps)
+			pattern := "*"
+			if resourceType != "" {
+				pattern = resourceType + "-*"
+			}
+			files, err := layers.Glob(pattern)
+			if err != nil {
+				return fmt.Errorf("failed to search for templates: %w", err)
+			}
+			if len(files) == 0 {
+				if resourceType == "" {
+					return fmt.Errorf("no templates found")
+				}
 				return fmt.Errorf("no templates found for resource type: %s", resourceType)
 			}
+			if resourceType != "" && output == "" {
+				return writeTemplateList(cmd.OutOrStdout(), files)
+			}
 
-			return nil
+			lock, err := marketplace.LoadLockFile()
+			if err != nil {
+				return fmt.Errorf("failed to read marketplace.lock: %w", err)
+			}
+			served := func(schema.GroupVersionKind) *bool { return nil }
+			if restErr == nil {
+				if kinds, err := servedKinds(clients.FactoryFromCmd(cmd, "")); err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "WARN cannot tell which kinds the cluster serves: %v\n", err)
+				} else {
+					served = kinds.serves
+				}
+			}
+			lint := func(f utils.TemplateFile) (string, []string) {
+				return lintStatus(cmd, f)
+			}
+			inv := buildInventory(files, lock.Pins, served, lint)
+			if output == "json" {
+				return writeInventoryJSON(cmd.OutOrStdout(), layers, inv)
+			}
+			return writeInventory(cmd.OutOrStdout(), inv)
 		},
 	}
 
 	templateCMD.Flags().StringP("resource", "r", "", "Resource type to list templates for (e.g. pod, deployment)")
 	_ = templateCMD.RegisterFlagCompletionFunc("resource", completions.ResourceTypes)
-	templateCMD.MarkFlagRequired("resource")
+	templateCMD.Flags().StringVarP(&output, "output", "o", "", "Output format: json for the inventory")
 
 	return templateCMD
 }
+
+// writeTemplateList writes one row per template: the copy in effect, and
+// the layers of the copies it shadows. files must be ordered as
+// TemplateSearchPath.Glob orders them.
+func writeTemplateList(out io.Writer, files []utils.TemplateFile) error {
+	w := utils.NewTabWriter(out)
+	fmt.Fprintln(w, "NAME\tLAYER\tPATH\tSHADOWS")
+	for i := 0; i < len(files); {
+		active := files[i]
+		var shadows []string
+		for i++; i < len(files) && files[i].Dir == active.Dir && files[i].Name == active.Name; i++ {
+			shadows = append(shadows, files[i].Layer.Name)
+		}
+		shadowed := "<none>"
+		if len(shadows) > 0 {
+			shadowed = strings.Join(shadows, ",")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", active.Name, active.Layer.Name, active.Path(), shadowed)
+	}
+	return w.Flush()
+}
+
+// inventoryEntry is a template in effect, as the inventory lists it.
+type inventoryEntry struct {
+	Directory string `json:"directory"`
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	// Format is yaml or tpl.
+	Format  string `json:"format"`
+	Columns int    `json:"columns"`
+	// Source is the layer of the template, or marketplace for a pinned
+	// user template.
+	Source string        `json:"source"`
+	Layer  string        `json:"layer"`
+	Path   string        `json:"path"`
+	Pin    *inventoryPin `json:"pin,omitempty"`
+	// Shadows are the layers of the copies the template hides.
+	Shadows []string `json:"shadows,omitempty"`
+	// Lint is ok, warn or fail.
+	Lint         string   `json:"lint"`
+	LintMessages []string `json:"lintMessages,omitempty"`
+	// Served is nil when the cluster could not tell.
+	Served *bool `json:"served"`
+}
+
+// inventoryPin is the marketplace.lock pin of a template.
+type inventoryPin struct {
+	Repo string `json:"repo"`
+	Ref  string `json:"ref,omitempty"`
+}
+
+// buildInventory returns the templates in effect among files, ordered as
+// TemplateSearchPath.Glob orders them. served tells whether the cluster
+// serves a kind, nil if unknown, and lint lints a template.
+func buildInventory(files []utils.TemplateFile, pins []marketplace.MarketplacePin, served func(schema.GroupVersionKind) *bool, lint func(utils.TemplateFile) (string, []string)) []inventoryEntry {
+	var inv []inventoryEntry
+	for i := 0; i < len(files); {
+		f := files[i]
+		e := inventoryEntry{
+			Directory: f.Dir,
+			Name:      f.Name,
+			Format:    strings.TrimPrefix(f.Ext, "."),
+			Source:    f.Layer.Name,
+			Layer:     f.Layer.Name,
+			Path:      f.Path(),
+		}
+		for i++; i < len(files) && files[i].Dir == f.Dir && files[i].Name == f.Name; i++ {
+			e.Shadows = append(e.Shadows, files[i].Layer.Name)
+		}
+		if gvk, ok := utils.ParseTemplateDirName(f.Dir); ok {
+			e.Group, e.Version, e.Kind = gvk.Group, gvk.Version, gvk.Kind
+			e.Served = served(gvk)
+		}
+		if f.Layer.Name == utils.LayerUser {
+			// install matches the resource of a pin to directories the same way.
+			for _, p := range pins {
+				if p.Matches(f.Dir, f.Name) {
+					e.Source, e.Pin = "marketplace", &inventoryPin{Repo: p.Repo, Ref: p.Ref}
+				}
+			}
+		}
+		if data, err := f.Read(); err == nil {
+			e.Columns = templateColumnCount(f.Ext, data)
+		}
+		e.Lint, e.LintMessages = lint(f)
+		inv = append(inv, e)
+	}
+	return inv
+}
+
+// templateColumnCount returns the number of columns of a template; 0 if
+// it does not parse.
+func templateColumnCount(ext string, data []byte) int {
+	if ext == ".tpl" {
+		t, err := parseTPL(data)
+		if err != nil {
+			return 0
+		}
+		return len(t.headers)
+	}
+	tmpl, _, err := models.ParseYAMLTemplate("", data)
+	if err != nil {
+		return 0
+	}
+	return len(tmpl.Columns)
+}
+
+// lintStatus lints template f as template lint does without a schema, and
+// returns ok, warn or fail with the messages lint reports.
+func lintStatus(cmd *cobra.Command, f utils.TemplateFile) (string, []string) {
+	data, err := f.Read()
+	if err != nil {
+		return "fail", []string{err.Error()}
+	}
+	var report bytes.Buffer
+	err = lintTemplate(cmd, io.Discard, &report, f.Path(), data, nil)
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSpace(report.String()), "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "- ")
+		if line != "" && !strings.HasPrefix(line, "FAIL ") {
+			messages = append(messages, line)
+		}
+	}
+	switch {
+	case err != nil:
+		if len(messages) == 0 {
+			messages = []string{err.Error()}
+		}
+		return "fail", messages
+	case len(messages) > 0:
+		return "warn", messages
+	}
+	return "ok", nil
+}
+
+// servedKindSet is the kinds a cluster serves, by lowercase kind, group
+// and version.
+type servedKindSet map[schema.GroupVersionKind]bool
+
+// servedKinds lists the kinds the cluster serves by discovery.
+func servedKinds(f cmdutil.Factory) (servedKindSet, error) {
+	dc, err := f.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	// Groups that fail discovery are left out; the others are still listed.
+	_, lists, err := dc.ServerGroupsAndResources()
+	if len(lists) == 0 && err != nil {
+		return nil, err
+	}
+	kinds := servedKindSet{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") {
+				continue
+			}
+			kind := strings.ToLower(r.Kind)
+			kinds[gv.WithKind(kind)] = true
+			// A version-less template directory is for every version.
+			kinds[schema.GroupVersionKind{Group: gv.Group, Kind: kind}] = true
+		}
+	}
+	return kinds, nil
+}
+
+// serves reports whether the cluster serves gvk, a kind of a template
+// directory; any version if gvk has none.
+func (s servedKindSet) serves(gvk schema.GroupVersionKind) *bool {
+	served := s[schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: strings.ToLower(gvk.Kind)}]
+	return &served
+}
+
+// writeInventory writes the inventory as a table.
+func writeInventory(out io.Writer, inv []inventoryEntry) error {
+	w := utils.NewTabWriter(out)
+	fmt.Fprintln(w, "DIRECTORY\tKIND\tNAME\tFORMAT\tCOLUMNS\tSOURCE\tLINT\tSERVED")
+	for _, e := range inv {
+		// Kind.version.group, as --kind takes it; kind.group for every version.
+		kind := "<unknown>"
+		switch {
+		case e.Kind == "":
+		case e.Version == "":
+			kind = schema.GroupKind{Group: e.Group, Kind: e.Kind}.String()
+		case e.Group == "":
+			kind = e.Kind + "." + e.Version
+		default:
+			kind = e.Kind + "." + e.Version + "." + e.Group
+		}
+		served := "unknown"
+		if e.Served != nil {
+			served = map[bool]string{true: "yes", false: "no"}[*e.Served]
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n", e.Directory, kind, e.Name, e.Format, e.Columns, e.Source, e.Lint, served)
+	}
+	return w.Flush()
+}
+
+// inventoryLayer is a layer of the search path, as -o json lists it.
+type inventoryLayer struct {
+	Name string `json:"name"`
+	Root string `json:"root,omitempty"`
+}
+
+// writeInventoryJSON writes the inventory, with the layers searched, as
+// JSON.
+func writeInventoryJSON(out io.Writer, layers utils.TemplateSearchPath, inv []inventoryEntry) error {
+	doc := struct {
+		Layers    []inventoryLayer `json:"layers"`
+		Templates []inventoryEntry `json:"templates"`
+	}{Layers: []inventoryLayer{}, Templates: inv}
+	for _, l := range layers {
+		doc.Layers = append(doc.Layers, inventoryLayer{Name: l.Name, Root: l.Root})
+	}
+	if doc.Templates == nil {
+		doc.Templates = []inventoryEntry{}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,76 @@
+package template
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/kubectl-cwide/pkg/cmd/marketplace"
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+func TestWriteTemplateList(t *testing.T) {
+	project := utils.TemplateLayer{Name: utils.LayerProject, Root: "/repo/.cwide/templates"}
+	user := utils.TemplateLayer{Name: utils.LayerUser, Root: "/home/me/tpl"}
+	builtin := utils.TemplateLayer{Name: utils.LayerBuiltin}
+	files := []utils.TemplateFile{
+		{Layer: project, Dir: "pod--v1", Name: "default", Ext: ".yaml"},
+		{Layer: user, Dir: "pod--v1", Name: "default", Ext: ".tpl"},
+		{Layer: builtin, Dir: "pod--v1", Name: "default", Ext: ".yaml"},
+		{Layer: builtin, Dir: "pod--v1", Name: "images", Ext: ".yaml"},
+	}
+	var buf bytes.Buffer
+	if err := writeTemplateList(&buf, files); err != nil {
+		t.Fatal(err)
+	}
+	want := "NAME      LAYER     PATH                                          SHADOWS\n" +
+		"default   project   /repo/.cwide/templates/pod--v1/default.yaml   user,builtin\n" +
+		"images    builtin   builtin:pod--v1/images.yaml                   <none>\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestBuildInventory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	user := utils.TemplateLayer{Name: utils.LayerUser, Root: "/home/me/tpl", FS: fstest.MapFS{
+		"pod--v1/default.yaml":              {Data: []byte("columns:\n  - header: NAME\n    fieldSpec: .metadata.name\n  - header: IP\n    fieldSpec: .status.podIP\n")},
+		"pod--v1/debug.yaml":                {Data: []byte("columns:\n  - header: BAD\n    fieldSpec: .status[\n")},
+		"widget-example.com-v1/default.tpl": {Data: []byte("NAME SIZE\n.metadata.name .spec.size\n")},
+	}}
+	builtin := utils.TemplateLayer{Name: utils.LayerBuiltin, FS: fstest.MapFS{
+		"pod--v1/default.yaml": {Data: []byte("columns:\n  - header: NAME\n    fieldSpec: .metadata.name\n")},
+	}}
+	files, err := utils.TemplateSearchPath{user, builtin}.Glob("*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins := []marketplace.MarketplacePin{{Repo: "org/templates", Resource: "pod", Template: "debug", Ref: "v1.2.0"}}
+	served := func(gvk schema.GroupVersionKind) *bool {
+		ok := gvk.Group == ""
+		return &ok
+	}
+	cmd := &cobra.Command{}
+	inv := buildInventory(files, pins, served, func(f utils.TemplateFile) (string, []string) { return lintStatus(cmd, f) })
+
+	var buf bytes.Buffer
+	if err := writeInventory(&buf, inv); err != nil {
+		t.Fatal(err)
+	}
+	want := "DIRECTORY               KIND                    NAME      FORMAT   COLUMNS   SOURCE        LINT   SERVED\n" +
+		"pod--v1                 pod.v1                  debug     yaml     1         marketplace   fail   yes\n" +
+		"pod--v1                 pod.v1                  default   yaml     2         user          ok     yes\n" +
+		"widget-example.com-v1   widget.v1.example.com   default   tpl      2         user          ok     no\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if inv[0].Pin == nil || inv[0].Pin.Ref != "v1.2.0" || len(inv[0].LintMessages) == 0 {
+		t.Errorf("debug: pin %+v, lint messages %v", inv[0].Pin, inv[0].LintMessages)
+	}
+	if len(inv[1].Shadows) != 1 || inv[1].Shadows[0] != utils.LayerBuiltin {
+		t.Errorf("default shadows %v, want [builtin]", inv[1].Shadows)
+	}
+}
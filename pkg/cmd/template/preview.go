@@ -0,0 +1,200 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubectl/pkg/scheme"
+
+	"github.com/kubectl-cwide/pkg/cmd/get"
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+// previewDebounce is how long the preview waits after a change to the
+// template before rendering it: editors write a file in several steps.
+const previewDebounce = 150 * time.Millisecond
+
+// preview renders one template, as get would, every time it is saved.
+type preview struct {
+	// path is the template file, in directory dir of template root root,
+	// and name its name.
+	path, root, dir, name string
+	objs                  []runtime.Object
+	restConfig            *rest.Config
+	sink                  *previewSink
+
+	mu sync.Mutex
+}
+
+// render renders the template for the objects of the preview. A template
+// that does not parse, or fails to render, renders as its error.
+func (p *preview) render() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s at %s, %d object(s)\n\n", p.path, time.Now().Format("15:04:05"), len(p.objs))
+	gvk, ok := utils.ParseTemplateDirName(p.dir)
+	if !ok {
+		fmt.Fprintf(&b, "ERROR: directory %q is not named <kind>-<group>[-<version>]\n", p.dir)
+		return b.Bytes()
+	}
+	// Only the root being edited, so that no other layer shadows it.
+	layers := utils.TemplateSearchPath{utils.DirLayer(utils.LayerUser, p.root)}
+	decoder := scheme.Codecs.UniversalDecoder(scheme.Scheme.PrioritizedVersionsAllGroups()...)
+	var warnings bytes.Buffer
+	printer, err := get.ResolveTemplatePrinter(layers, gvk, p.name, decoder, p.restConfig, &warnings)
+	if err != nil {
+		fmt.Fprintf(&b, "ERROR: %v\n", err)
+		return b.Bytes()
+	}
+	b.Write(warnings.Bytes())
+	var table bytes.Buffer
+	if err := get.Render(&table, printer, p.objs, "table"); err != nil {
+		fmt.Fprintf(&b, "ERROR: %v\n", err)
+		return b.Bytes()
+	}
+	b.Write(table.Bytes())
+	return b.Bytes()
+}
+
+// update renders the template and writes it to the sink.
+func (p *preview) update() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.sink.write(p.render()); err != nil {
+		fmt.Fprintf(os.Stderr, "WARN preview: %v\n", err)
+	}
+}
+
+// watch updates the preview every time path changes, until stop is
+// closed. The directory of path is watched, as editors often save a file
+// by renaming another over it.
+func (p *preview) watch(path string, stop <-chan struct{}) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		select {
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(ev.Name) != path || !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Rename) {
+				continue
+			}
+			if timer == nil {
+				timer = time.AfterFunc(previewDebounce, p.update)
+			} else {
+				timer.Reset(previewDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "WARN preview: %v\n", err)
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// previewSink is where a preview is written: a terminal, cleared before
+// each render, or a file, rewritten.
+type previewSink struct {
+	path     string
+	terminal bool
+}
+
+// newPreviewSink returns the sink of path; a new temporary file if path is
+// empty.
+func newPreviewSink(path string) (*previewSink, error) {
+	if path == "" {
+		f, err := os.CreateTemp("", "cwide-preview-*.txt")
+		if err != nil {
+			return nil, err
+		}
+		path = f.Name()
+		if err := f.Close(); err != nil {
+			return nil, err
+		}
+		return &previewSink{path: path}, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return &previewSink{path: path, terminal: err == nil && info.Mode()&os.ModeCharDevice != 0}, nil
+}
+
+func (s *previewSink) write(data []byte) error {
+	if !s.terminal {
+		return os.WriteFile(s.path, data, 0644)
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	// Move home and clear the screen, then the render.
+	if _, err := f.Write(append([]byte("\x1b[H\x1b[2J"), data...)); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// previewObjects returns the objects of a preview: those of file, or
+// those the cluster lists for resource.
+func previewObjects(file string, list func() ([]map[string]interface{}, error), limit int) ([]runtime.Object, error) {
+	var raw []map[string]interface{}
+	var err error
+	if file != "" {
+		raw, err = readObjectsFile(file)
+	} else {
+		raw, err = list()
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw = sampleObjects(raw, limit)
+	objs := make([]runtime.Object, 0, len(raw))
+	for i, o := range raw {
+		obj, err := toUnstructured(o)
+		if err != nil {
+			return nil, fmt.Errorf("object %d: %w", i, err)
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+// previewResource returns the resource kubectl lists the objects of kind
+// directory dir by: kind[.version].group.
+func previewResource(dir string) (string, error) {
+	gvk, ok := utils.ParseTemplateDirName(dir)
+	if !ok {
+		return "", fmt.Errorf("directory %q is not named <kind>-<group>[-<version>]", dir)
+	}
+	switch {
+	case gvk.Group == "":
+		return gvk.Kind, nil
+	case gvk.Version == "":
+		return gvk.Kind + "." + gvk.Group, nil
+	}
+	return gvk.Kind + "." + gvk.Version + "." + gvk.Group, nil
+}
@@ -0,0 +1,90 @@
+package template
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPreviewRender(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "pod--v1", "default.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var objs []runtime.Object
+	for _, o := range []map[string]interface{}{
+		{"apiVersion": "v1", "kind": "Pod", "metadata": map[string]interface{}{"name": "a"}, "status": map[string]interface{}{"phase": "Running"}},
+		{"apiVersion": "v1", "kind": "Pod", "metadata": map[string]interface{}{"name": "b"}, "status": map[string]interface{}{"phase": "Pending"}},
+	} {
+		obj, err := toUnstructured(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objs = append(objs, obj)
+	}
+	p := &preview{path: path, root: root, dir: "pod--v1", name: "default", objs: objs}
+
+	good := "columns:\n  - header: NAME\n    fieldSpec: .metadata.name\n  - header: PHASE\n    fieldSpec: .status.phase\n"
+	if err := os.WriteFile(path, []byte(good), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := string(p.render())
+	if !strings.HasPrefix(out, "# "+path+" at ") || !strings.Contains(out, "NAME   PHASE\na      Running\nb      Pending\n") {
+		t.Errorf("unexpected preview:\n%s", out)
+	}
+
+	bad := "columns:\n  - header: BAD\n    template: '{{ .status.phase '\n"
+	if err := os.WriteFile(path, []byte(bad), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out := string(p.render()); !strings.Contains(out, "ERROR: "+path+":3:") {
+		t.Errorf("parse error not shown at its position:\n%s", out)
+	}
+}
+
+func TestKeepIfLints(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "pod--v1", "default.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	original := []byte("columns:\n  - header: NAME\n    fieldSpec: .metadata.name\n")
+	edited := []byte("columns:\n  - header: NAME\n")
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+
+	if err := os.WriteFile(path, edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := keepIfLints(cmd, path, original, false)
+	if err == nil {
+		t.Fatal("an edit that does not lint was kept")
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, original) {
+		t.Errorf("previous version not restored:\n%s", got)
+	}
+	saved := err.Error()[strings.Index(err.Error(), "saved in ")+len("saved in "):]
+	saved = saved[:strings.Index(saved, ";")]
+	defer os.Remove(saved)
+	if got, _ := os.ReadFile(saved); !bytes.Equal(got, edited) {
+		t.Errorf("edit not saved in %s:\n%s", saved, got)
+	}
+
+	if err := os.WriteFile(path, edited, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := keepIfLints(cmd, path, original, true); err != nil {
+		t.Fatalf("--force: %v", err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, edited) {
+		t.Errorf("--force did not keep the edit:\n%s", got)
+	}
+}
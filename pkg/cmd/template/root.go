@@ -22,6 +22,17 @@ the config file created by 'init').`,
 	templateCMD.AddCommand(NewCmdEdit())
 	templateCMD.AddCommand(NewCmdLint())
 	templateCMD.AddCommand(NewCmdScaffold())
+	templateCMD.AddCommand(NewCmdTest())
+	templateCMD.AddCommand(NewCmdConvert())
+	templateCMD.AddCommand(NewCmdFmt())
+	templateCMD.AddCommand(NewCmdImport())
+	templateCMD.AddCommand(NewCmdExport())
+	templateCMD.AddCommand(NewCmdInfer())
+	templateCMD.AddCommand(NewCmdCopy())
+	templateCMD.AddCommand(NewCmdMove())
+	templateCMD.AddCommand(NewCmdRemove())
+	templateCMD.AddCommand(NewCmdHistory())
+	templateCMD.AddCommand(NewCmdRollback())
 
 	return templateCMD
 }
@@ -1,38 +1,425 @@
 package template
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
+	"unicode"
 
-	"github.com/kubectl-cwide/pkg/cmd/completions"
 	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsclientset "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/kubectl-cwide/pkg/clients"
+	"github.com/kubectl-cwide/pkg/cmd/completions"
+	"github.com/kubectl-cwide/pkg/models"
+	"github.com/kubectl-cwide/pkg/utils"
 )
 
-// NewCmdScaffold creates a stub YAML template with common columns filled in
-// and the rest commented out for the user to enable.
+// conditionsTemplate lists the types of the conditions that are True.
+const conditionsTemplate = `{{- $true := list -}}{{- range .status.conditions -}}{{- if eq .status "True" -}}{{- $true = append $true .type -}}{{- end -}}{{- end -}}{{ join "," $true }}`
+
+// NewCmdScaffold creates a YAML template for a resource from its schema,
+// with the common columns filled in and every other field commented out
+// for the user to enable.
 func NewCmdScaffold() *cobra.Command {
-	return &cobra.Command{
+	var depth int
+	var schemaFile, kubeContext string
+	cmd := &cobra.Command{
 		Use:   "scaffold <resource>",
 		Short: "Print a starter template for a resource type",
-		Long: `Emit a YAML template body with a handful of common columns (name, namespace,
-age) filled in, plus a commented-out set of frequently-useful JSONPath
-expressions you can uncomment.
+		Long: `Emit a YAML template for a resource, built from its OpenAPI v3 schema.
+
+The resource is resolved by discovery, and its schema is that of the version
+the cluster serves. With --schema FILE, both come from a file instead,
+offline: CustomResourceDefinition manifests, or an OpenAPI v3 document saved
+from the API server (kubectl get --raw /openapi/v3/apis/<group>/<version>).
 
-The output is written to stdout — pipe it to the destination path yourself.
-This is intended as a "first draft" — cwide's 'init' auto-generates a more
-complete template by inspecting the CRD schema.`,
+Enabled columns are the namespace (for namespaced kinds) and name, the
+additionalPrinterColumns of a CRD, the conditions that are True when the
+kind has status conditions, and the age. Every other field, down to --depth
+levels, follows as a commented-out column with its type and description;
+objects at the last level are listed whole. apiVersion, kind and metadata
+are left out.
+
+Without a cluster or --schema, a generic template with the common columns
+is printed, with a warning.
+
+The output is written to stdout — pipe it to the destination path yourself.`,
 		Example: `  # Pipe to a template file
-  kubectl cwide template scaffold pod > ~/.kubectl-cwide/templates/pod--v1/starter.yaml`,
+  kubectl cwide template scaffold pod > ~/.kubectl-cwide/templates/pod--v1/starter.yaml
+
+  # List fields deeper in the schema
+  kubectl cwide template scaffold deployments.apps --depth 5
+
+  # Scaffold a CRD's template from its manifest, without a cluster
+  kubectl cwide template scaffold widgets --schema config/crd/widgets.yaml`,
 		Args:              cobra.ExactArgs(1),
 		ValidArgsFunction: completions.ResourceTypes,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			body := scaffoldFor(args[0])
-			_, err := fmt.Fprint(cmd.OutOrStdout(), body)
+			if depth < 1 {
+				return fmt.Errorf("--depth must be at least 1")
+			}
+			var target *scaffoldTarget
+			if schemaFile != "" {
+				file, err := newFileSchemas(schemaFile)
+				if err != nil {
+					return err
+				}
+				ks, crd, err := file.forResource(args[0])
+				if err != nil {
+					return err
+				}
+				target = crdScaffoldTarget(ks, crd)
+			} else {
+				f := clients.FactoryFromCmd(cmd, kubeContext)
+				dc, err := f.ToDiscoveryClient()
+				if err == nil {
+					_, err = dc.ServerGroups()
+				}
+				if err != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "WARN %s: cannot reach the cluster (%v); printing a generic template, use --schema FILE to scaffold offline\n", args[0], err)
+					_, err := fmt.Fprint(cmd.OutOrStdout(), scaffoldFor(args[0]))
+					return err
+				}
+				if target, err = servedScaffoldTarget(cmd.Context(), f, utils.ResolveAliasString(args[0]), cmd.ErrOrStderr()); err != nil {
+					return err
+				}
+			}
+			body, err := target.scaffold(depth)
+			if err != nil {
+				return err
+			}
+			_, err = cmd.OutOrStdout().Write(body)
 			return err
 		},
 	}
+	cmd.Flags().IntVar(&depth, "depth", 3, "Levels of fields to list below the root of the object")
+	cmd.Flags().StringVar(&schemaFile, "schema", "", "Scaffold offline from CRD manifests or an OpenAPI v3 document")
+	cmd.Flags().StringVar(&kubeContext, "context", "", "The name of the kubeconfig context to discover the resource with")
+	return cmd
+}
+
+// scaffoldTarget is what a template is scaffolded from.
+type scaffoldTarget struct {
+	ks         *kindSchema
+	namespaced bool
+	// printerColumns are the additionalPrinterColumns of a CRD.
+	printerColumns []apiextensionsv1.CustomResourceColumnDefinition
+}
+
+// crdScaffoldTarget returns the target of ks, a kind that crd defines, if
+// not nil. Kinds without a CRD are taken to be namespaced.
+func crdScaffoldTarget(ks *kindSchema, crd *apiextensionsv1.CustomResourceDefinition) *scaffoldTarget {
+	target := &scaffoldTarget{ks: ks, namespaced: true}
+	if crd == nil {
+		return target
+	}
+	target.namespaced = crd.Spec.Scope != apiextensionsv1.ClusterScoped
+	for _, v := range crd.Spec.Versions {
+		if v.Name == ks.gvk.Version {
+			target.printerColumns = v.AdditionalPrinterColumns
+		}
+	}
+	return target
+}
+
+// servedScaffoldTarget resolves resource by discovery, to the version the
+// cluster serves, and fetches its CRD, if it has one.
+func servedScaffoldTarget(ctx context.Context, f cmdutil.Factory, resource string, errOut io.Writer) (*scaffoldTarget, error) {
+	mapper, err := f.ToRESTMapper()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get REST mapper: %w", err)
+	}
+	gvk, err := resolveResource(mapper, resource)
+	if err != nil {
+		return nil, fmt.Errorf("cluster does not serve %q: %w", resource, err)
+	}
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	served, err := newServedSchemas(f)
+	if err != nil {
+		return nil, err
+	}
+	ks, err := served.forKind(gvk)
+	if err != nil {
+		return nil, err
+	}
+	target := &scaffoldTarget{ks: ks, namespaced: mapping.Scope.Name() == meta.RESTScopeNameNamespace}
+	if gvk.Group == "" {
+		return target, nil
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	cs, err := apiextensionsclientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create apiextensions client: %w", err)
+	}
+	crdName := mapping.Resource.Resource + "." + gvk.Group
+	crd, err := cs.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, crdName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		// Built into the API server or served by an aggregated API.
+	case err != nil:
+		fmt.Fprintf(errOut, "WARN %s: cannot read CRD %s, its printer columns are left out: %v\n", resource, crdName, err)
+	default:
+		target = crdScaffoldTarget(ks, crd)
+	}
+	return target, nil
+}
+
+// scaffold returns the template of the target, with the fields down to
+// depth commented out.
+func (t *scaffoldTarget) scaffold(depth int) ([]byte, error) {
+	var tmpl models.YAMLTemplate
+	if t.namespaced {
+		tmpl.Columns = append(tmpl.Columns, models.YAMLColumn{Header: "NAMESPACE", FieldSpec: ".metadata.namespace"})
+	}
+	tmpl.Columns = append(tmpl.Columns, models.YAMLColumn{Header: "NAME", FieldSpec: ".metadata.name"})
+	for _, col := range t.printerColumns {
+		if col.JSONPath == ".metadata.creationTimestamp" {
+			continue
+		}
+		c := models.YAMLColumn{Header: strings.ToUpper(strings.ReplaceAll(col.Name, " ", "_")), FieldSpec: col.JSONPath}
+		// kubectl renders "date" printer columns as an age.
+		if col.Type == "date" {
+			c.Format = "age"
+		} else if col.Type != "string" {
+			c.Type = col.Type
+		}
+		tmpl.Columns = append(tmpl.Columns, c)
+	}
+	if t.ks.hasConditions() {
+		tmpl.Columns = append(tmpl.Columns, models.YAMLColumn{Header: "CONDITIONS", Template: conditionsTemplate})
+	}
+	tmpl.Columns = append(tmpl.Columns, models.YAMLColumn{Header: "AGE", FieldSpec: ".metadata.creationTimestamp", Format: "age"})
+
+	body, err := marshalYAMLTemplate(&tmpl)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Template for %s %s, scaffolded from its schema.\n", t.ks.gvk.GroupVersion(), t.ks.gvk.Kind)
+	b.Write(body)
+
+	enabled := map[string]bool{}
+	var headers []string
+	for _, c := range tmpl.Columns {
+		enabled[c.FieldSpec] = true
+		headers = append(headers, c.Header)
+	}
+	var fields []scaffoldField
+	for _, f := range t.ks.fields(depth) {
+		if !enabled[f.path] {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return []byte(b.String()), nil
+	}
+	fmt.Fprintf(&b, "\n  # --- fields down to depth %d; uncomment to include ---\n", depth)
+	for i, header := range scaffoldHeaders(fields, headers) {
+		f := fields[i]
+		fmt.Fprintf(&b, "  # %s (%s)", f.path, f.typ)
+		if f.description != "" {
+			fmt.Fprintf(&b, ": %s", f.description)
+		}
+		fmt.Fprintf(&b, "\n  # - header: %s\n  #   fieldSpec: %s\n", header, f.path)
+		switch {
+		case f.date:
+			b.WriteString("  #   format: age\n")
+		case f.typ == "integer", f.typ == "number", f.typ == "boolean":
+			fmt.Fprintf(&b, "  #   type: %s\n", f.typ)
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// scaffoldField is a field that scaffold lists as a column.
+type scaffoldField struct {
+	// path is the field spec of the field, with the items of arrays as
+	// [*].
+	path        string
+	typ         string
+	date        bool
+	description string
+}
+
+// fields returns the leaves of the schema down to depth fields below the
+// root, and the objects at depth, in the order of the schema's field
+// names. apiVersion, kind and metadata are left out.
+func (k *kindSchema) fields(depth int) []scaffoldField {
+	var out []scaffoldField
+	var walk func(raw *spec.Schema, path string, level int)
+	walk = func(raw *spec.Schema, path string, level int) {
+		s := k.resolve(raw)
+		if s == nil {
+			return
+		}
+		description := raw.Description
+		if description == "" {
+			description = s.Description
+		}
+		f := scaffoldField{path: path, typ: schemaType(s), description: firstSentence(description)}
+		items := k.items(s)
+		switch {
+		case items != nil && len(items.Properties) > 0 && level < depth:
+			for _, name := range propertyNames(items) {
+				p := items.Properties[name]
+				walk(&p, path+"[*]."+name, level+1)
+			}
+			return
+		case items != nil:
+			f.path, f.typ = path+"[*]", schemaType(items)
+		case len(s.Properties) > 0 && level < depth:
+			for _, name := range propertyNames(s) {
+				if level == 0 && (name == "apiVersion" || name == "kind" || name == "metadata") {
+					continue
+				}
+				p := s.Properties[name]
+				walk(&p, path+"."+name, level+1)
+			}
+			return
+		case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+			f.typ = "map[string]" + schemaType(k.resolve(s.AdditionalProperties.Schema))
+		}
+		f.date = f.typ == "string" && s.Format == "date-time"
+		out = append(out, f)
+	}
+	walk(k.root, "", 0)
+	return out
+}
+
+// hasConditions reports whether the kind has status conditions with a type
+// and a status.
+func (k *kindSchema) hasConditions() bool {
+	s := k.resolve(k.root)
+	for _, name := range []string{"status", "conditions"} {
+		if s == nil {
+			return false
+		}
+		p, ok := s.Properties[name]
+		if !ok {
+			return false
+		}
+		s = k.resolve(&p)
+	}
+	items := k.items(s)
+	if items == nil {
+		return false
+	}
+	_, hasType := items.Properties["type"]
+	_, hasStatus := items.Properties["status"]
+	return hasType && hasStatus
+}
+
+func propertyNames(s *spec.Schema) []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaType names the type of s as scaffold lists it.
+func schemaType(s *spec.Schema) string {
+	switch {
+	case s == nil:
+		return "any"
+	case s.Items != nil:
+		return "array"
+	case len(s.Type) > 0:
+		return s.Type[0]
+	case len(s.Properties) > 0:
+		return "object"
+	}
+	if intOrString, _ := s.Extensions["x-kubernetes-int-or-string"].(bool); intOrString {
+		return "int-or-string"
+	}
+	return "any"
+}
+
+// firstSentence returns the first sentence of a schema description on one
+// line, cut to a comment's length.
+func firstSentence(description string) string {
+	s := strings.Join(strings.Fields(description), " ")
+	if i := strings.Index(s, ". "); i >= 0 {
+		s = s[:i+1]
+	}
+	if r := []rune(s); len(r) > 100 {
+		s = strings.TrimSpace(string(r[:97])) + "..."
+	}
+	return s
+}
+
+// scaffoldHeaders returns the headers of fields: the name of the last field
+// of each path, with the fields before it added until the headers are
+// unique, and unlike those of taken.
+func scaffoldHeaders(fields []scaffoldField, taken []string) []string {
+	segments := make([][]string, len(fields))
+	n := make([]int, len(fields))
+	for i, f := range fields {
+		for _, seg := range strings.Split(strings.TrimPrefix(f.path, "."), ".") {
+			segments[i] = append(segments[i], headerWord(strings.TrimSuffix(seg, "[*]")))
+		}
+		n[i] = 1
+	}
+	header := func(i int) string {
+		return strings.Join(segments[i][len(segments[i])-n[i]:], "_")
+	}
+	for {
+		count := map[string]int{}
+		for _, h := range taken {
+			count[h]++
+		}
+		for i := range fields {
+			count[header(i)]++
+		}
+		grown := false
+		for i := range fields {
+			if count[header(i)] > 1 && n[i] < len(segments[i]) {
+				n[i]++
+				grown = true
+			}
+		}
+		if !grown {
+			break
+		}
+	}
+	headers := make([]string, len(fields))
+	for i := range fields {
+		headers[i] = header(i)
+	}
+	return headers
+}
+
+// headerWord turns a field name into a header: readyReplicas becomes
+// READY_REPLICAS.
+func headerWord(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
 }
 
+// scaffoldFor is the generic template, for when no schema can be had.
 func scaffoldFor(kind string) string {
 	kind = strings.ToLower(kind)
 	var b strings.Builder
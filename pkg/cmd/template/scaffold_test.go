@@ -0,0 +1,139 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const gadgetCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gadgets.example.com
+spec:
+  group: example.com
+  names: {kind: Gadget, plural: gadgets, singular: gadget, shortNames: [gd]}
+  scope: Cluster
+  versions:
+    - name: v1
+      served: true
+      storage: true
+      additionalPrinterColumns:
+        - {name: Size, type: integer, jsonPath: .spec.size}
+        - {name: Age, type: date, jsonPath: .metadata.creationTimestamp}
+      schema:
+        openAPIV3Schema:
+          type: object
+          properties:
+            spec:
+              type: object
+              properties:
+                size: {type: integer, description: "Number of parts. Must be positive."}
+                owner:
+                  type: object
+                  properties:
+                    name: {type: string}
+                    team:
+                      type: object
+                      properties:
+                        name: {type: string}
+                labels:
+                  type: object
+                  additionalProperties: {type: string}
+                tags: {type: array, items: {type: string}}
+            status:
+              type: object
+              properties:
+                lastSeen: {type: string, format: date-time, description: When the gadget last reported.}
+                conditions:
+                  type: array
+                  items:
+                    type: object
+                    properties:
+                      type: {type: string}
+                      status: {type: string}
+`
+
+func TestScaffoldFromCRD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gadgets.yaml")
+	if err := os.WriteFile(path, []byte(gadgetCRD), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := newFileSchemas(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, resource := range []string{"gadgets", "gadget", "Gadget", "gd", "gadgets.example.com"} {
+		if _, _, err := schemas.forResource(resource); err != nil {
+			t.Errorf("forResource(%q): %v", resource, err)
+		}
+	}
+	if _, _, err := schemas.forResource("gadgets.other.io"); err == nil {
+		t.Error("forResource matched a CRD of another group")
+	}
+
+	ks, crd, err := schemas.forResource("gd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := crdScaffoldTarget(ks, crd).scaffold(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Template for example.com/v1 Gadget, scaffolded from its schema.
+columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: SIZE
+    fieldSpec: .spec.size
+    type: integer
+  - header: CONDITIONS
+    template: '` + conditionsTemplate + `'
+  - header: AGE
+    fieldSpec: .metadata.creationTimestamp
+    format: age
+
+  # --- fields down to depth 2; uncomment to include ---
+  # .spec.labels (map[string]string)
+  # - header: LABELS
+  #   fieldSpec: .spec.labels
+  # .spec.owner (object)
+  # - header: OWNER
+  #   fieldSpec: .spec.owner
+  # .spec.tags[*] (string)
+  # - header: TAGS
+  #   fieldSpec: .spec.tags[*]
+  # .status.conditions[*] (object)
+  # - header: STATUS_CONDITIONS
+  #   fieldSpec: .status.conditions[*]
+  # .status.lastSeen (string): When the gadget last reported.
+  # - header: LAST_SEEN
+  #   fieldSpec: .status.lastSeen
+  #   format: age
+`
+	if string(out) != want {
+		t.Errorf("got\n%s\nwant\n%s", out, want)
+	}
+	if _, err := formatYAMLTemplate(out); err != nil {
+		t.Errorf("scaffolded template does not parse: %v", err)
+	}
+
+	deeper, err := crdScaffoldTarget(ks, crd).scaffold(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"fieldSpec: .spec.owner.name", "header: OWNER_NAME", "fieldSpec: .spec.owner.team", "fieldSpec: .status.conditions[*].type"} {
+		if !strings.Contains(string(deeper), field) {
+			t.Errorf("depth 3 lacks %q:\n%s", field, deeper)
+		}
+	}
+}
+
+func TestHeaderWord(t *testing.T) {
+	for name, want := range map[string]string{"replicas": "REPLICAS", "readyReplicas": "READY_REPLICAS", "podIP": "POD_IP", "ipv4Address": "IPV4_ADDRESS"} {
+		if got := headerWord(name); got != want {
+			t.Errorf("headerWord(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
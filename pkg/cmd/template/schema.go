@@ -0,0 +1,494 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template/parse"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/openapi3"
+	"k8s.io/client-go/util/jsonpath"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	cmdutil "k8s.io/kubectl/pkg/cmd/util"
+
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+// kindSchema is the OpenAPI v3 schema of one kind, with the components of
+// the document it came from to resolve references.
+type kindSchema struct {
+	gvk        schema.GroupVersionKind
+	root       *spec.Schema
+	components map[string]*spec.Schema
+}
+
+// findKindSchema returns the schema of kind gvk in doc, found by its
+// x-kubernetes-group-version-kind extension. An empty version matches any.
+func findKindSchema(doc *spec3.OpenAPI, gvk schema.GroupVersionKind) (*kindSchema, error) {
+	if doc == nil || doc.Components == nil {
+		return nil, fmt.Errorf("no schemas for %s", gvk.GroupVersion())
+	}
+	names := make([]string, 0, len(doc.Components.Schemas))
+	for name := range doc.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if s := doc.Components.Schemas[name]; s != nil {
+			if found, ok := matchGVK(s, gvk); ok {
+				return &kindSchema{gvk: found, root: s, components: doc.Components.Schemas}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no schema for %s %s", gvk.GroupVersion(), gvk.Kind)
+}
+
+// matchGVK returns the kind of s that gvk names. The kind is matched
+// case-insensitively, as template directories store it in lowercase.
+func matchGVK(s *spec.Schema, gvk schema.GroupVersionKind) (schema.GroupVersionKind, bool) {
+	kinds, _ := s.Extensions["x-kubernetes-group-version-kind"].([]interface{})
+	for _, k := range kinds {
+		m, _ := k.(map[string]interface{})
+		group, _ := m["group"].(string)
+		version, _ := m["version"].(string)
+		kind, _ := m["kind"].(string)
+		if group == gvk.Group && (gvk.Version == "" || version == gvk.Version) && strings.EqualFold(kind, gvk.Kind) {
+			return schema.GroupVersionKind{Group: group, Version: version, Kind: kind}, true
+		}
+	}
+	return schema.GroupVersionKind{}, false
+}
+
+// resolve follows $ref, and the single-element allOf the API server uses
+// to attach defaults to a reference.
+func (k *kindSchema) resolve(s *spec.Schema) *spec.Schema {
+	for i := 0; s != nil && i < 32; i++ {
+		if ref := s.Ref.String(); ref != "" {
+			s = k.components[strings.TrimPrefix(ref, "#/components/schemas/")]
+			continue
+		}
+		if len(s.AllOf) == 1 && len(s.Properties) == 0 {
+			s = &s.AllOf[0]
+			continue
+		}
+		return s
+	}
+	return s
+}
+
+// fieldIssue is a field a column reads that the schema rules out.
+type fieldIssue struct {
+	// path leads to the field, with the items of arrays as [*].
+	path string
+	// suggestion is the path with the closest existing field, if any.
+	suggestion string
+	// array is set when path is an array read as an object, without an
+	// index or filter.
+	array bool
+	// node is the node of a Go template that reads the field.
+	node parse.Node
+}
+
+// describe explains the issue. advice tells how to read the items of an
+// array in the language of the column.
+func (i *fieldIssue) describe(ks *kindSchema, advice string) string {
+	if i.array {
+		return fmt.Sprintf("%s is an array; %s", i.path, advice)
+	}
+	msg := fmt.Sprintf("%s does not exist in %s %s", i.path, ks.gvk.GroupVersion(), ks.gvk.Kind)
+	if i.suggestion != "" {
+		msg += fmt.Sprintf("; did you mean %s?", i.suggestion)
+	}
+	return msg
+}
+
+// fieldSpecIssue returns the first field of the JSONPath expr that the
+// schema rules out. It returns nil when every field exists, or when the
+// schema cannot tell (maps of arbitrary keys, fields preserved without a
+// schema, recursive descent).
+func (k *kindSchema) fieldSpecIssue(expr string) (*fieldIssue, error) {
+	e := expr
+	if !strings.HasPrefix(e, "{") {
+		e = "{" + e + "}"
+	}
+	p, err := jsonpath.Parse("lint", e)
+	if err != nil {
+		return nil, err
+	}
+	// Blocks inside {range}...{end} are relative to the ranged elements.
+	type scope struct {
+		s    *spec.Schema
+		path string
+	}
+	scopes := []scope{{s: k.resolve(k.root)}}
+	for _, n := range p.Root.Nodes {
+		list, ok := n.(*jsonpath.ListNode)
+		if !ok || len(list.Nodes) == 0 {
+			continue
+		}
+		cur := scopes[len(scopes)-1]
+		if id, ok := list.Nodes[0].(*jsonpath.IdentifierNode); ok {
+			switch id.Name {
+			case "range":
+				next, path, issue := k.walk(cur.s, cur.path, list.Nodes[1:])
+				if issue != nil {
+					return issue, nil
+				}
+				// The elements of a ranged array are its items.
+				if items := k.items(next); items != nil {
+					next, path = items, path+"[*]"
+				}
+				scopes = append(scopes, scope{s: next, path: path})
+			case "end":
+				if len(scopes) > 1 {
+					scopes = scopes[:len(scopes)-1]
+				}
+			}
+			continue
+		}
+		if _, _, issue := k.walk(cur.s, cur.path, list.Nodes); issue != nil {
+			return issue, nil
+		}
+	}
+	return nil, nil
+}
+
+// walk follows nodes from schema s at path. It returns the schema they
+// lead to, nil if it cannot be known, its path and the first issue.
+func (k *kindSchema) walk(s *spec.Schema, path string, nodes []jsonpath.Node) (*spec.Schema, string, *fieldIssue) {
+	for _, n := range nodes {
+		if s == nil {
+			return nil, path, nil
+		}
+		switch n := n.(type) {
+		case *jsonpath.FieldNode:
+			if n.Value == "" {
+				continue
+			}
+			next, issue := k.field(s, path, n.Value)
+			if issue != nil {
+				return nil, path, issue
+			}
+			s, path = next, path+"."+n.Value
+		case *jsonpath.ArrayNode, *jsonpath.FilterNode:
+			items := k.items(s)
+			if items == nil {
+				return nil, path, nil
+			}
+			s, path = items, path+"[*]"
+		case *jsonpath.WildcardNode:
+			switch {
+			case k.items(s) != nil:
+				s = k.items(s)
+			case s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil:
+				s = k.resolve(s.AdditionalProperties.Schema)
+			default:
+				return nil, path, nil
+			}
+			path += "[*]"
+		default:
+			// Recursive descent, unions and literals: nothing more to check.
+			return nil, path, nil
+		}
+	}
+	return s, path, nil
+}
+
+// items returns the schema of the items of array s, or nil.
+func (k *kindSchema) items(s *spec.Schema) *spec.Schema {
+	if s == nil || s.Items == nil || s.Items.Schema == nil {
+		return nil
+	}
+	return k.resolve(s.Items.Schema)
+}
+
+// field returns the schema of field name of s, at path. A nil schema
+// without an issue means the rest of the path cannot be checked. Reading a
+// field of an array, or one that s lists no property for, is an issue.
+func (k *kindSchema) field(s *spec.Schema, path, name string) (*spec.Schema, *fieldIssue) {
+	if k.items(s) != nil || s.Type.Contains("array") {
+		return nil, &fieldIssue{path: path, array: true}
+	}
+	if p, ok := s.Properties[name]; ok {
+		return k.resolve(&p), nil
+	}
+	if s.AdditionalProperties != nil {
+		if s.AdditionalProperties.Schema != nil {
+			return k.resolve(s.AdditionalProperties.Schema), nil
+		}
+		if s.AdditionalProperties.Allows {
+			return nil, nil
+		}
+	} else if preserve, _ := s.Extensions["x-kubernetes-preserve-unknown-fields"].(bool); preserve || len(s.Properties) == 0 {
+		return nil, nil
+	}
+	issue := &fieldIssue{path: path + "." + name}
+	if closest := closestField(s, name); closest != "" {
+		issue.suggestion = path + "." + closest
+	}
+	return nil, issue
+}
+
+// closestField returns the property of s closest to name, if it is close
+// enough to be what was meant: the same but for case, or a few edits away.
+func closestField(s *spec.Schema, name string) string {
+	best, bestDistance := "", max(2, len(name)/3)+1
+	for p := range s.Properties {
+		d := editDistance(strings.ToLower(name), strings.ToLower(p))
+		if d < bestDistance || d == bestDistance && p < best {
+			best, bestDistance = p, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// servedSchemas looks up the schema of the version a cluster serves for
+// the kind of a template directory.
+type servedSchemas struct {
+	mapper meta.RESTMapper
+	root   openapi3.Root
+	docs   map[schema.GroupVersion]*spec3.OpenAPI
+}
+
+func newServedSchemas(f cmdutil.Factory) (*servedSchemas, error) {
+	mapper, err := f.ToRESTMapper()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get REST mapper: %w", err)
+	}
+	dc, err := f.ToDiscoveryClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get discovery client: %w", err)
+	}
+	return &servedSchemas{mapper: mapper, root: openapi3.NewRoot(dc.OpenAPIV3()), docs: map[schema.GroupVersion]*spec3.OpenAPI{}}, nil
+}
+
+// forDir returns the schema of the served version of the kind of template
+// directory dir, and a note when that is not the version of the directory.
+func (s *servedSchemas) forDir(dir string) (*kindSchema, string, error) {
+	dirGVK, ok := utils.ParseTemplateDirName(dir)
+	if !ok {
+		return nil, "", fmt.Errorf("directory %q is not named <kind>-<group>[-<version>]", dir)
+	}
+	served, err := s.mapper.KindFor(schema.GroupVersionResource{Group: dirGVK.Group, Resource: dirGVK.Kind})
+	if err != nil {
+		return nil, "", fmt.Errorf("cluster does not serve %s: %w", dir, err)
+	}
+	var note string
+	if dirGVK.Version != served.Version {
+		written := "every version"
+		if dirGVK.Version != "" {
+			written = dirGVK.Version
+		}
+		note = fmt.Sprintf("template is for %s; checked against the served %s", written, served.GroupVersion())
+	}
+	ks, err := s.forKind(served)
+	return ks, note, err
+}
+
+// forKind returns the schema of kind gvk as the cluster serves it.
+func (s *servedSchemas) forKind(gvk schema.GroupVersionKind) (*kindSchema, error) {
+	doc, ok := s.docs[gvk.GroupVersion()]
+	if !ok {
+		var err error
+		doc, err = s.root.GVSpec(gvk.GroupVersion())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OpenAPI v3 schema for %s: %w", gvk.GroupVersion(), err)
+		}
+		s.docs[gvk.GroupVersion()] = doc
+	}
+	return findKindSchema(doc, gvk)
+}
+
+// schemaSource finds the schema of the kind of a template directory, with
+// a note when it is not the schema of the directory's version.
+type schemaSource interface {
+	forDir(dir string) (*kindSchema, string, error)
+}
+
+// fileSchemas reads schemas offline from a file: CustomResourceDefinition
+// manifests, or an OpenAPI v3 document as the API server serves it at
+// /openapi/v3/apis/<group>/<version>.
+type fileSchemas struct {
+	path string
+	crds []apiextensionsv1.CustomResourceDefinition
+	doc  *spec3.OpenAPI
+}
+
+func newFileSchemas(path string) (*fileSchemas, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	s := &fileSchemas{path: path}
+	dec := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+	for {
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case obj["openapi"] != nil:
+			s.doc = &spec3.OpenAPI{}
+			if err := s.doc.UnmarshalJSON(raw); err != nil {
+				return nil, fmt.Errorf("parse %s: %w", path, err)
+			}
+		case obj["kind"] == "CustomResourceDefinition":
+			var crd apiextensionsv1.CustomResourceDefinition
+			if err := json.Unmarshal(raw, &crd); err != nil {
+				return nil, fmt.Errorf("parse %s: %w", path, err)
+			}
+			s.crds = append(s.crds, crd)
+		}
+	}
+	if s.doc == nil && len(s.crds) == 0 {
+		return nil, fmt.Errorf("%s holds neither a CustomResourceDefinition nor an OpenAPI v3 document", path)
+	}
+	return s, nil
+}
+
+// forDir returns the schema of the kind of template directory dir: of the
+// directory's version if the file has it, of another version otherwise.
+func (s *fileSchemas) forDir(dir string) (*kindSchema, string, error) {
+	dirGVK, ok := utils.ParseTemplateDirName(dir)
+	if !ok {
+		return nil, "", fmt.Errorf("directory %q is not named <kind>-<group>[-<version>]", dir)
+	}
+	var ks *kindSchema
+	var err error
+	if s.doc != nil {
+		if ks, err = findKindSchema(s.doc, dirGVK); err != nil && dirGVK.Version != "" {
+			ks, err = findKindSchema(s.doc, dirGVK.GroupKind().WithVersion(""))
+		}
+	} else {
+		ks, err = s.crdSchema(dirGVK)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", s.path, err)
+	}
+	var note string
+	if dirGVK.Version != "" && dirGVK.Version != ks.gvk.Version {
+		note = fmt.Sprintf("template is for %s; checked against %s in %s", dirGVK.Version, ks.gvk.GroupVersion(), s.path)
+	}
+	return ks, note, nil
+}
+
+// crdSchema returns the schema of gvk from the CRDs: of its version, or of
+// the storage version.
+func (s *fileSchemas) crdSchema(gvk schema.GroupVersionKind) (*kindSchema, error) {
+	for _, crd := range s.crds {
+		if crd.Spec.Group != gvk.Group || !strings.EqualFold(crd.Spec.Names.Kind, gvk.Kind) {
+			continue
+		}
+		var version *apiextensionsv1.CustomResourceDefinitionVersion
+		for i, v := range crd.Spec.Versions {
+			if v.Name == gvk.Version || version == nil && v.Storage {
+				version = &crd.Spec.Versions[i]
+			}
+		}
+		if version == nil || version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+			return nil, fmt.Errorf("CRD %s has no schema for %s", crd.Name, gvk.Kind)
+		}
+		raw, err := json.Marshal(version.Schema.OpenAPIV3Schema)
+		if err != nil {
+			return nil, err
+		}
+		root := &spec.Schema{}
+		if err := root.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("CRD %s: %w", crd.Name, err)
+		}
+		// CRD schemas may leave out the fields every object has.
+		if len(root.Properties) > 0 {
+			for _, f := range []string{"apiVersion", "kind", "metadata"} {
+				if _, ok := root.Properties[f]; !ok {
+					root.Properties[f] = spec.Schema{}
+				}
+			}
+		}
+		return &kindSchema{
+			gvk:  schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind},
+			root: root,
+		}, nil
+	}
+	return nil, fmt.Errorf("no CRD for %s", gvk.GroupKind())
+}
+
+// forResource returns the schema of resource, named as kubectl names it
+// (widgets, widget, Widget, a short name, or any of these followed by
+// .<group>), and the CRD that defines it, if any. Of a CRD, the schema is
+// that of the storage version.
+func (s *fileSchemas) forResource(resource string) (*kindSchema, *apiextensionsv1.CustomResourceDefinition, error) {
+	name, group, grouped := strings.Cut(strings.ToLower(resource), ".")
+	for i, crd := range s.crds {
+		if grouped && crd.Spec.Group != group {
+			continue
+		}
+		names := append([]string{crd.Spec.Names.Plural, crd.Spec.Names.Singular, strings.ToLower(crd.Spec.Names.Kind)}, crd.Spec.Names.ShortNames...)
+		for _, n := range names {
+			if n == name {
+				ks, err := s.crdSchema(schema.GroupVersionKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind})
+				if err != nil {
+					return nil, nil, fmt.Errorf("%s: %w", s.path, err)
+				}
+				return ks, &s.crds[i], nil
+			}
+		}
+	}
+	if s.doc != nil && s.doc.Components != nil {
+		names := make([]string, 0, len(s.doc.Components.Schemas))
+		for n := range s.doc.Components.Schemas {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			kinds, _ := s.doc.Components.Schemas[n].Extensions["x-kubernetes-group-version-kind"].([]interface{})
+			for _, k := range kinds {
+				m, _ := k.(map[string]interface{})
+				g, _ := m["group"].(string)
+				kind, _ := m["kind"].(string)
+				kind = strings.ToLower(kind)
+				if (!grouped || g == group) && (name == kind || name == guessPlural(kind)) {
+					ks, err := findKindSchema(s.doc, schema.GroupVersionKind{Group: g, Kind: kind})
+					return ks, nil, err
+				}
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("%s has no schema for %q", s.path, resource)
+}
@@ -0,0 +1,123 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/spec3"
+)
+
+const hpaSchema = `{
+  "openapi": "3.0.0",
+  "info": {"title": "Kubernetes", "version": "v1.30"},
+  "paths": {},
+  "components": {"schemas": {
+    "io.k8s.api.autoscaling.v2.HorizontalPodAutoscaler": {
+      "type": "object",
+      "properties": {
+        "metadata": {"allOf": [{"$ref": "#/components/schemas/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}], "default": {}},
+        "spec": {"allOf": [{"$ref": "#/components/schemas/io.k8s.api.autoscaling.v2.HorizontalPodAutoscalerSpec"}]}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "autoscaling", "kind": "HorizontalPodAutoscaler", "version": "v2"}]
+    },
+    "io.k8s.api.autoscaling.v2.HorizontalPodAutoscalerSpec": {
+      "type": "object",
+      "properties": {
+        "maxReplicas": {"type": "integer"},
+        "metrics": {"type": "array", "items": {"allOf": [{"$ref": "#/components/schemas/io.k8s.api.autoscaling.v2.MetricSpec"}]}}
+      }
+    },
+    "io.k8s.api.autoscaling.v2.MetricSpec": {
+      "type": "object",
+      "properties": {"type": {"type": "string"}, "resource": {"type": "object", "properties": {"name": {"type": "string"}}}}
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "labels": {"type": "object", "additionalProperties": {"type": "string"}},
+        "annotations": {"type": "object", "additionalProperties": {"type": "string"}}
+      }
+    }
+  }}
+}`
+
+func TestKindSchemaMissingField(t *testing.T) {
+	var doc spec3.OpenAPI
+	if err := doc.UnmarshalJSON([]byte(hpaSchema)); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := findKindSchema(&doc, schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "horizontalpodautoscaler"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each expression maps to the issue's path and suggestion, or "".
+	cases := map[string]string{
+		".metadata.name":                                     "",
+		"{.metadata.labels.app}":                             "",
+		".spec.metrics[*].resource.name":                     "",
+		".spec.metrics[?(@.type==\"Resource\")].type":        "",
+		"{range .spec.metrics[*]}{.type}{end}":               "",
+		"{range .spec.metrics[*]}{.typ}{end}":                ".spec.metrics[*].typ .spec.metrics[*].type",
+		".spec.targetCPUUtilizationPercentage":               ".spec.targetCPUUtilizationPercentage",
+		".spec.metrics[0].resource.targetAverageUtilization": ".spec.metrics[*].resource.targetAverageUtilization",
+		".spec.maxReplica":                                   ".spec.maxReplica .spec.maxReplicas",
+		".metadata.Name":                                     ".metadata.Name .metadata.name",
+		"..name":                                             "",
+	}
+	for expr, want := range cases {
+		issue, err := ks.fieldSpecIssue(expr)
+		if err != nil {
+			t.Errorf("%s: %v", expr, err)
+			continue
+		}
+		got := ""
+		if issue != nil {
+			got = strings.TrimSpace(issue.path + " " + issue.suggestion)
+		}
+		if got != want {
+			t.Errorf("%s: got %q, want %q", expr, got, want)
+		}
+	}
+
+	issue, err := ks.fieldSpecIssue(".spec.metrics.type")
+	if err != nil || issue == nil || !issue.array || issue.path != ".spec.metrics" {
+		t.Errorf("field of an array: got %+v, %v", issue, err)
+	}
+
+	if _, err := findKindSchema(&doc, schema.GroupVersionKind{Group: "autoscaling", Version: "v2beta2", Kind: "HorizontalPodAutoscaler"}); err == nil {
+		t.Error("found a schema for a version the document does not have")
+	}
+}
+
+func TestFileSchemasOpenAPI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "autoscaling-v2.json")
+	if err := os.WriteFile(path, []byte(hpaSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	schemas, err := newFileSchemas(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, note, err := schemas.forDir("horizontalpodautoscaler-autoscaling-v2")
+	if err != nil || note != "" || ks.gvk.Kind != "HorizontalPodAutoscaler" {
+		t.Fatalf("v2: got %v, %q, %v", ks, note, err)
+	}
+	ks, note, err = schemas.forDir("horizontalpodautoscaler-autoscaling-v2beta2")
+	if err != nil || ks.gvk.Version != "v2" || !strings.Contains(note, "template is for v2beta2; checked against autoscaling/v2") {
+		t.Fatalf("v2beta2: got %v, %q, %v", ks, note, err)
+	}
+	if _, _, err := schemas.forDir("deployment-apps-v1"); err == nil {
+		t.Error("found a schema for a kind the document does not have")
+	}
+
+	if err := os.WriteFile(path, []byte("kind: ConfigMap\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newFileSchemas(path); err == nil {
+		t.Error("accepted a file without schemas")
+	}
+}
@@ -0,0 +1,402 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/kubectl/pkg/scheme"
+
+	"github.com/kubectl-cwide/pkg/cmd/get"
+	"github.com/kubectl-cwide/pkg/parser/funcs"
+	"github.com/kubectl-cwide/pkg/utils"
+)
+
+// defaultTestNow is the frozen clock of cases that do not set one.
+var defaultTestNow = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Golden files of a case, and the output format each one is compared with.
+var testGoldens = []struct {
+	file   string
+	format string
+}{
+	{"expected.txt", "table"},
+	{"expected.json", "template-json"},
+}
+
+// templateTestInput is the input.yaml of a test case.
+type templateTestInput struct {
+	// Template names the template under test; "default" if empty.
+	Template string `yaml:"template"`
+	// Now is the RFC3339 time ages are computed from.
+	Now     string                   `yaml:"now"`
+	Objects []map[string]interface{} `yaml:"objects"`
+
+	Lookups        []lookupStub        `yaml:"lookups"`
+	LookupsByLabel []lookupByLabelStub `yaml:"lookupsByLabel"`
+	ProbeChecks    []probeCheckStub    `yaml:"probeChecks"`
+	ServiceChecks  []serviceCheckStub  `yaml:"serviceChecks"`
+}
+
+// lookupStub is the object lookup returns for its arguments. Without an
+// object, lookup returns an empty map, as for a missing object.
+type lookupStub struct {
+	APIVersion string                 `yaml:"apiVersion"`
+	Kind       string                 `yaml:"kind"`
+	Namespace  string                 `yaml:"namespace"`
+	Name       string                 `yaml:"name"`
+	Object     map[string]interface{} `yaml:"object"`
+}
+
+// lookupByLabelStub is the items lookupByLabel returns for its arguments.
+type lookupByLabelStub struct {
+	APIVersion string                   `yaml:"apiVersion"`
+	Kind       string                   `yaml:"kind"`
+	Namespace  string                   `yaml:"namespace"`
+	Selector   string                   `yaml:"selector"`
+	Items      []map[string]interface{} `yaml:"items"`
+}
+
+// probeCheckStub is the result probeCheck returns for a pod's probe. An
+// empty container matches calls without one.
+type probeCheckStub struct {
+	Namespace string `yaml:"namespace"`
+	Pod       string `yaml:"pod"`
+	Probe     string `yaml:"probe"`
+	Container string `yaml:"container"`
+	Result    string `yaml:"result"`
+}
+
+// serviceCheckStub is the result serviceCheck returns for a service.
+// Empty port and path match calls without them.
+type serviceCheckStub struct {
+	Namespace string `yaml:"namespace"`
+	Service   string `yaml:"service"`
+	Port      string `yaml:"port"`
+	Path      string `yaml:"path"`
+	Result    string `yaml:"result"`
+}
+
+func NewCmdTest() *cobra.Command {
+	var update bool
+	cmd := &cobra.Command{
+		Use:   "test <path>...",
+		Short: "Render template test cases offline and compare them with golden files",
+		Long: `Find the test cases under each path and render them offline.
+
+A test case is a directory tests/<case>/ next to the templates of one
+resource directory:
+
+  pod--v1/
+    default.yaml
+    tests/
+      crashloop/
+        input.yaml      objects, template, clock and stubs
+        expected.txt    table output
+        expected.json   template-json output
+
+input.yaml lists the objects to render, the template (default: "default"),
+the frozen time ages are computed from (now, RFC3339; default
+2024-01-01T00:00:00Z) and the responses of lookup, lookupByLabel,
+probeCheck and serviceCheck. Nothing contacts a cluster: calls without a
+stub render as a missing object or "ERR (no stub)".
+
+Each golden file present is compared with the output in its format; a case
+without golden files fails. --update writes the current output to the
+golden files instead (expected.txt when there are none). The command exits
+non-zero if any case fails.`,
+		Example: `  # Run every test case under the template root
+  kubectl cwide template test ~/.kubectl-cwide/templates
+
+  # Accept the current output of the pod cases
+  kubectl cwide template test ~/.kubectl-cwide/templates/pod--v1 --update`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			funcs.SetColorDisabled(true)
+			var cases []string
+			for _, arg := range args {
+				found, err := findTestCases(arg)
+				if err != nil {
+					return err
+				}
+				cases = append(cases, found...)
+			}
+			if len(cases) == 0 {
+				return fmt.Errorf("no test cases (tests/<case>/input.yaml) found under %s", strings.Join(args, ", "))
+			}
+			failed := 0
+			for _, dir := range cases {
+				if err := runTestCase(cmd.OutOrStdout(), dir, update); err != nil {
+					failed++
+					fmt.Fprintf(cmd.OutOrStdout(), "FAIL %s\n%s\n", dir, indent(err.Error()))
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%d passed, %d failed\n", len(cases)-failed, failed)
+			if failed > 0 {
+				return fmt.Errorf("%d template test(s) failed", failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&update, "update", false, "Write the current output to the golden files")
+	return cmd
+}
+
+// findTestCases returns the case directories under root, sorted.
+func findTestCases(root string) ([]string, error) {
+	var cases []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != "input.yaml" {
+			return nil
+		}
+		dir := filepath.Dir(path)
+		if filepath.Base(filepath.Dir(dir)) == "tests" {
+			cases = append(cases, dir)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s for test cases: %w", root, err)
+	}
+	sort.Strings(cases)
+	return cases, nil
+}
+
+// runTestCase renders the case in dir and compares, or with update
+// writes, its golden files.
+func runTestCase(out io.Writer, dir string, update bool) error {
+	data, err := os.ReadFile(filepath.Join(dir, "input.yaml"))
+	if err != nil {
+		return err
+	}
+	var in templateTestInput
+	if err := yaml.Unmarshal(data, &in); err != nil {
+		return fmt.Errorf("input.yaml: %w", err)
+	}
+
+	var goldens []int
+	for i, g := range testGoldens {
+		if utils.CheckFileExists(filepath.Join(dir, g.file)) {
+			goldens = append(goldens, i)
+		}
+	}
+	if len(goldens) == 0 {
+		if !update {
+			return fmt.Errorf("no golden file (%s or %s); run with --update to create one", testGoldens[0].file, testGoldens[1].file)
+		}
+		goldens = []int{0}
+	}
+
+	var problems []string
+	for _, i := range goldens {
+		g := testGoldens[i]
+		got, err := renderTestCase(filepath.Dir(filepath.Dir(dir)), in, g.format)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, g.file)
+		if update {
+			if err := os.WriteFile(path, got, 0644); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "updated %s\n", path)
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got, want) {
+			problems = append(problems, g.file+" differs:\n"+lineDiff(string(want), string(got)))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "\n"))
+	}
+	if !update {
+		fmt.Fprintf(out, "ok   %s\n", dir)
+	}
+	return nil
+}
+
+// renderTestCase renders in with its template from templateDir.
+func renderTestCase(templateDir string, in templateTestInput, format string) ([]byte, error) {
+	name := in.Template
+	if name == "" {
+		name = "default"
+	}
+	root := filepath.Dir(templateDir)
+	layers := utils.TemplateSearchPath{utils.DirLayer(utils.LayerUser, root)}
+	file, ok := layers.Find(filepath.Base(templateDir), name)
+	if !ok {
+		return nil, fmt.Errorf("template %q not found in %s", name, templateDir)
+	}
+	decoder := scheme.Codecs.UniversalDecoder(scheme.Scheme.PrioritizedVersionsAllGroups()...)
+	printer, err := get.NewCustomColumnsPrinterFromFile(layers, file, decoder, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file.Path(), err)
+	}
+
+	now := defaultTestNow
+	if in.Now != "" {
+		if now, err = time.Parse(time.RFC3339, in.Now); err != nil {
+			return nil, fmt.Errorf("input.yaml: now: %w", err)
+		}
+	}
+	printer.SetClock(func() time.Time { return now })
+	printer.SetFuncs(in.stubs())
+
+	objs := make([]runtime.Object, 0, len(in.Objects))
+	for i, o := range in.Objects {
+		obj, err := toUnstructured(o)
+		if err != nil {
+			return nil, fmt.Errorf("input.yaml: objects[%d]: %w", i, err)
+		}
+		objs = append(objs, obj)
+	}
+	var buf bytes.Buffer
+	if err := get.Render(&buf, printer, objs, format); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// stubs returns lookup, lookupByLabel, probeCheck and serviceCheck
+// answering from the stubs of the case.
+func (in templateTestInput) stubs() template.FuncMap {
+	return template.FuncMap{
+		"lookup": func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+			for _, s := range in.Lookups {
+				if s.APIVersion == apiVersion && strings.EqualFold(s.Kind, kind) && s.Namespace == namespace && s.Name == name {
+					return normalize(s.Object)
+				}
+			}
+			return map[string]interface{}{}, nil
+		},
+		"lookupByLabel": func(apiVersion, kind, namespace, selector string) (map[string]interface{}, error) {
+			for _, s := range in.LookupsByLabel {
+				if s.APIVersion == apiVersion && strings.EqualFold(s.Kind, kind) && s.Namespace == namespace && s.Selector == selector {
+					items := make([]interface{}, len(s.Items))
+					for i, item := range s.Items {
+						items[i] = item
+					}
+					return normalize(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items})
+				}
+			}
+			return map[string]interface{}{}, nil
+		},
+		"probeCheck": func(obj interface{}, probe string, container ...string) string {
+			namespace, name := identity(obj)
+			var only string
+			if len(container) > 0 {
+				only = container[0]
+			}
+			for _, s := range in.ProbeChecks {
+				if s.Namespace == namespace && s.Pod == name && s.Probe == probe && s.Container == only {
+					return s.Result
+				}
+			}
+			return "ERR (no stub)"
+		},
+		"serviceCheck": func(obj interface{}, args ...string) string {
+			namespace, name := identity(obj)
+			var port, path string
+			if len(args) > 0 {
+				port = args[0]
+			}
+			if len(args) > 1 {
+				path = args[1]
+			}
+			for _, s := range in.ServiceChecks {
+				if s.Namespace == namespace && s.Service == name && s.Port == port && s.Path == path {
+					return s.Result
+				}
+			}
+			return "ERR (no stub)"
+		},
+	}
+}
+
+// toUnstructured converts an object decoded from YAML, whose numbers are
+// ints, to the unstructured object the API would return.
+func toUnstructured(obj map[string]interface{}) (*unstructured.Unstructured, error) {
+	m, err := normalize(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: m}, nil
+}
+
+// normalize converts a map decoded from YAML to one decoded from JSON by
+// apimachinery, with int64 and float64 numbers.
+func normalize(obj map[string]interface{}) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	if obj == nil {
+		return m, nil
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if err := utiljson.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// identity returns the namespace and name of an object as passed to
+// template functions.
+func identity(obj interface{}) (string, string) {
+	m, _ := obj.(map[string]interface{})
+	meta, _ := m["metadata"].(map[string]interface{})
+	namespace, _ := meta["namespace"].(string)
+	name, _ := meta["name"].(string)
+	return namespace, name
+}
+
+// lineDiff shows the lines of want and got that differ, by line number.
+func lineDiff(want, got string) string {
+	wl := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gl := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	var b strings.Builder
+	for i := 0; i < len(wl) || i < len(gl); i++ {
+		var w, g string
+		if i < len(wl) {
+			w = wl[i]
+		}
+		if i < len(gl) {
+			g = gl[i]
+		}
+		if w == g {
+			continue
+		}
+		fmt.Fprintf(&b, "line %d:\n", i+1)
+		if i < len(wl) {
+			fmt.Fprintf(&b, "- %s\n", w)
+		}
+		if i < len(gl) {
+			fmt.Fprintf(&b, "+ %s\n", g)
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ")
+}
@@ -0,0 +1,106 @@
+package template
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunTestCase(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "pod--v1", "checks.yaml"), `columns:
+  - header: NAME
+    fieldSpec: .metadata.name
+  - header: READY
+    template: '{{ probeCheck . "readiness" }}'
+  - header: OWNER
+    template: '{{ (lookup "v1" "ConfigMap" .metadata.namespace "owners").data.team }}'
+  - header: AGE
+    template: '{{ age .metadata.creationTimestamp }}'
+`)
+	dir := filepath.Join(root, "pod--v1", "tests", "stubbed")
+	writeFile(t, filepath.Join(dir, "input.yaml"), `template: checks
+now: "2024-05-01T12:00:00Z"
+objects:
+  - apiVersion: v1
+    kind: Pod
+    metadata: {name: web, namespace: shop, creationTimestamp: "2024-05-01T10:00:00Z"}
+  - apiVersion: v1
+    kind: Pod
+    metadata: {name: db, namespace: shop, creationTimestamp: "2024-04-30T12:00:00Z"}
+lookups:
+  - apiVersion: v1
+    kind: ConfigMap
+    namespace: shop
+    name: owners
+    object: {data: {team: payments}}
+probeChecks:
+  - {namespace: shop, pod: web, probe: readiness, result: "OK (200)"}
+`)
+
+	cases, err := findTestCases(root)
+	if err != nil || len(cases) != 1 || cases[0] != dir {
+		t.Fatalf("findTestCases: got (%v, %v), want [%s]", cases, err, dir)
+	}
+	var out bytes.Buffer
+	if err := runTestCase(&out, dir, false); err == nil || !strings.Contains(err.Error(), "no golden file") {
+		t.Fatalf("without goldens: got %v", err)
+	}
+	if err := runTestCase(&out, dir, true); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "expected.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "NAME   READY           OWNER      AGE\n" +
+		"web    OK (200)        payments   120m\n" +
+		"db     ERR (no stub)   payments   24h\n"
+	if string(got) != want {
+		t.Fatalf("golden:\n%s\nwant:\n%s", got, want)
+	}
+	if err := runTestCase(&out, dir, false); err != nil {
+		t.Fatalf("unchanged: %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "expected.txt"), strings.Replace(want, "payments", "platform", 1))
+	err = runTestCase(&out, dir, false)
+	if err == nil || !strings.Contains(err.Error(), "- web    OK (200)        platform   120m") {
+		t.Fatalf("changed output: got %v", err)
+	}
+}
+
+// TestNativeTemplates runs the test cases bundled with the built-in
+// templates, as template test does, so that their golden files cannot rot.
+func TestNativeTemplates(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := filepath.Join("..", "..", "..", "templates", "native")
+	cases, err := findTestCases(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) == 0 {
+		t.Fatalf("no test cases under %s", root)
+	}
+	for _, dir := range cases {
+		rel, _ := filepath.Rel(root, dir)
+		t.Run(rel, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := runTestCase(&out, dir, false); err != nil {
+				t.Errorf("%v\n%s", err, out.String())
+			}
+		})
+	}
+}
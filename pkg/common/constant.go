@@ -2,6 +2,8 @@ package common
 
 const (
 	ConfigPath          = ".kubectl-cwide/config.yaml"
+	CachePath           = ".kubectl-cwide/cache"
+	HistoryPath         = ".kubectl-cwide/history"
 	DefaultPrinterField = "$_defaultPrinterField"
 
 	DefaultConfigMapName      = "cwide-templates"
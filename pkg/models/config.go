@@ -1,6 +1,9 @@
 package models
 
 import (
+	"regexp"
+	"strings"
+
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 )
 
@@ -9,6 +12,9 @@ type Config struct {
 	TemplatePath    string            `json:"templatePath" yaml:"templatePath"`
 	TemplateSources []string          `json:"templateSources,omitempty" yaml:"templateSources,omitempty"`
 	Aliases         map[string]string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// TeamTemplatePaths are shared template roots searched after
+	// TemplatePath and before the built-in templates, in order.
+	TeamTemplatePaths []string `json:"teamTemplatePaths,omitempty" yaml:"teamTemplatePaths,omitempty"`
 
 	// DefaultTemplateContext overrides the "default" template name per
 	// kubeconfig context (e.g. {"prod": "compact", "dev": "verbose"}).
@@ -16,6 +22,132 @@ type Config struct {
 	// DefaultTemplateNamespace overrides per namespace. Namespace overrides
 	// context if both match.
 	DefaultTemplateNamespace map[string]string `json:"defaultTemplateNamespace,omitempty" yaml:"defaultTemplateNamespace,omitempty"`
+	// TemplateRules choose the template per object. The first matching rule
+	// wins over the namespace and context defaults.
+	TemplateRules []TemplateRule `json:"templateRules,omitempty" yaml:"templateRules,omitempty"`
+}
+
+// TemplateRule selects Template for the objects it matches. Every condition
+// that is set must match; a rule without conditions matches everything.
+// Values and namespaces are glob patterns (see globMatch).
+type TemplateRule struct {
+	Template string `json:"template" yaml:"template"`
+	// Kinds limits the rule to these kinds or resource names, e.g. "Pod" or
+	// "deployments" (case-insensitive).
+	Kinds []string `json:"kinds,omitempty" yaml:"kinds,omitempty"`
+	// Labels must all be present with matching values.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Annotations must all be present with matching values.
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	// Namespaces matches objects in any of these namespaces.
+	Namespaces []string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+}
+
+// RuleTarget is what a TemplateRule is matched against.
+type RuleTarget struct {
+	Kind        string
+	Resource    string
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Matches reports whether the rule applies to t.
+func (r TemplateRule) Matches(t RuleTarget) bool {
+	if len(r.Kinds) > 0 && !anyEqualFold(r.Kinds, t.Kind, t.Resource) {
+		return false
+	}
+	if len(r.Namespaces) > 0 && !anyGlob(r.Namespaces, t.Namespace) {
+		return false
+	}
+	return globsMatch(r.Labels, t.Labels) && globsMatch(r.Annotations, t.Annotations)
+}
+
+// ResolveRuleTemplate returns the template of the first rule matching t.
+func (c *Config) ResolveRuleTemplate(t RuleTarget) (string, bool) {
+	for _, r := range c.TemplateRules {
+		if r.Template != "" && r.Matches(t) {
+			return r.Template, true
+		}
+	}
+	return "", false
+}
+
+func anyEqualFold(names []string, values ...string) bool {
+	for _, n := range names {
+		for _, v := range values {
+			if v != "" && strings.EqualFold(n, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyGlob(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if globMatch(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// globsMatch reports whether every key in want is in got with a value
+// matching the pattern.
+func globsMatch(want, got map[string]string) bool {
+	for k, pattern := range want {
+		v, ok := got[k]
+		if !ok {
+			return false
+		}
+		if !globMatch(pattern, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// globMatch reports whether value matches the glob pattern. '*' matches
+// any run of characters, '/' included (annotation values such as URLs and
+// image references have them), '?' any one character, and [...] a character
+// class as in path.Match; '\\' escapes the next character. A malformed
+// pattern matches nothing.
+func globMatch(pattern, value string) bool {
+	var re strings.Builder
+	re.WriteString(`^(?s:`)
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			re.WriteString(`.*`)
+		case '?':
+			re.WriteString(`.`)
+		case '\\':
+			i++
+			if i == len(pattern) {
+				return false
+			}
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return false
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "^") {
+				class = "^" + regexp.QuoteMeta(class[1:])
+			} else {
+				class = regexp.QuoteMeta(class)
+			}
+			re.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	re.WriteString(`)$`)
+	matcher, err := regexp.Compile(re.String())
+	return err == nil && matcher.MatchString(value)
 }
 
 // ResolveDefaultTemplate picks the effective default template name for the
@@ -0,0 +1,238 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourcePos is a position in a template file. Line and Column count from
+// 1; a zero Column is unknown. The zero SourcePos is unknown.
+type SourcePos struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// IsKnown reports whether p is a position.
+func (p SourcePos) IsKnown() bool {
+	return p.Line > 0
+}
+
+// String returns p as "file:line:col", the form editors jump to.
+func (p SourcePos) String() string {
+	s := strconv.Itoa(p.Line)
+	if p.Column > 0 {
+		s += ":" + strconv.Itoa(p.Column)
+	}
+	if p.File != "" {
+		s = p.File + ":" + s
+	}
+	return s
+}
+
+// ScalarPos is where a string of a YAML template, such as a column
+// template, is in the file.
+type ScalarPos struct {
+	// Start is the position of the first character of the string.
+	Start SourcePos
+	// Exact is set when the lines and columns of the string are those of
+	// the file from Start: literal block scalars (|) and single-line
+	// scalars without escapes. Positions in other strings map to Start.
+	Exact bool
+}
+
+// At returns the file position of line (from 1) and byte offset (from 0,
+// -1 if unknown) within the string.
+func (s ScalarPos) At(line, offset int) SourcePos {
+	if !s.Exact || line < 1 {
+		return s.Start
+	}
+	p := SourcePos{File: s.Start.File, Line: s.Start.Line + line - 1, Column: s.Start.Column}
+	if offset > 0 {
+		p.Column += offset
+	}
+	return p
+}
+
+// YAMLTemplatePositions are the positions of the strings of a YAML
+// template.
+type YAMLTemplatePositions struct {
+	Helpers ScalarPos
+	Funcs   map[string]ScalarPos
+	Columns []YAMLColumnPositions
+}
+
+// YAMLColumnPositions are the positions of one column of a YAML template.
+type YAMLColumnPositions struct {
+	// Item is the position of the column's entry in columns.
+	Item      SourcePos
+	Header    ScalarPos
+	FieldSpec ScalarPos
+	Template  ScalarPos
+}
+
+// yamlErrorLine matches the line yaml.v3 gives in syntax errors.
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// ParseYAMLTemplate decodes the YAML template data, read from file, with
+// the positions of its strings.
+func ParseYAMLTemplate(file string, data []byte) (*YAMLTemplate, *YAMLTemplatePositions, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil && file != "" {
+			line, _ := strconv.Atoi(m[1])
+			return nil, nil, &SourceError{Pos: SourcePos{File: file, Line: line}, Msg: m[2], Err: err}
+		}
+		return nil, nil, err
+	}
+	tmpl := &YAMLTemplate{}
+	pos := &YAMLTemplatePositions{Funcs: map[string]ScalarPos{}}
+	if len(doc.Content) == 0 {
+		return tmpl, pos, nil
+	}
+	if err := doc.Decode(tmpl); err != nil {
+		return nil, nil, err
+	}
+
+	lines := strings.Split(string(data), "\n")
+	scalar := func(n *yaml.Node) ScalarPos {
+		return scalarPos(file, lines, n)
+	}
+	root := doc.Content[0]
+	pos.Helpers = scalar(MappingValue(root, "helpers"))
+	if funcs := MappingValue(root, "funcs"); funcs != nil && funcs.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(funcs.Content); i += 2 {
+			pos.Funcs[funcs.Content[i].Value] = scalar(funcs.Content[i+1])
+		}
+	}
+	if columns := MappingValue(root, "columns"); columns != nil && columns.Kind == yaml.SequenceNode {
+		for _, item := range columns.Content {
+			pos.Columns = append(pos.Columns, YAMLColumnPositions{
+				Item:      SourcePos{File: file, Line: item.Line, Column: item.Column},
+				Header:    scalar(MappingValue(item, "header")),
+				FieldSpec: scalar(MappingValue(item, "fieldSpec")),
+				Template:  scalar(MappingValue(item, "template")),
+			})
+		}
+	}
+	return tmpl, pos, nil
+}
+
+// MappingValue returns the value of key in mapping n, or nil.
+func MappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarPos returns the position of the string of scalar n. The lines of a
+// block scalar start on the line after its indicator, at the indentation
+// of its first non-blank line.
+func scalarPos(file string, lines []string, n *yaml.Node) ScalarPos {
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ScalarPos{}
+	}
+	if n.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		p := ScalarPos{Start: SourcePos{File: file, Line: n.Line + 1, Column: 1}, Exact: n.Style&yaml.LiteralStyle != 0}
+		for i := n.Line; i < len(lines); i++ {
+			if text := strings.TrimLeft(lines[i], " "); text != "" {
+				p.Start.Column = len(lines[i]) - len(text) + 1
+				break
+			}
+		}
+		return p
+	}
+	p := ScalarPos{Start: SourcePos{File: file, Line: n.Line, Column: n.Column}}
+	if n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+		p.Start.Column++
+	}
+	if n.Line <= len(lines) && p.Start.Column-1 <= len(lines[n.Line-1]) {
+		p.Exact = !strings.Contains(n.Value, "\n") && strings.HasPrefix(lines[n.Line-1][p.Start.Column-1:], n.Value)
+	}
+	return p
+}
+
+// TemplateSources are the strings a set of text/template templates was
+// parsed from, by template name, to report the positions text/template
+// gives in errors ("template: NAME:LINE[:COL]: ...") and in
+// Tree.ErrorContext ("NAME:LINE:COL") at their position in the file.
+type TemplateSources map[string]ScalarPos
+
+var (
+	templateErrorPrefix = regexp.MustCompile(`^template: ([^:\s]+):(\d+):(?:(\d+):)? ?`)
+	templateLocation    = regexp.MustCompile(`^([^:\s]+):(\d+):(\d+)$`)
+)
+
+// At returns the file position of a location returned by
+// Tree.ErrorContext, if it is in one of the templates of s.
+func (s TemplateSources) At(location string) (SourcePos, bool) {
+	m := templateLocation.FindStringSubmatch(location)
+	if m == nil {
+		return SourcePos{}, false
+	}
+	return s.at(m[1], m[2], m[3])
+}
+
+func (s TemplateSources) at(name, line, offset string) (SourcePos, bool) {
+	src, ok := s[name]
+	if !ok || !src.Start.IsKnown() {
+		return SourcePos{}, false
+	}
+	l, _ := strconv.Atoi(line)
+	o := -1
+	if offset != "" {
+		o, _ = strconv.Atoi(offset)
+	}
+	return src.At(l, o), true
+}
+
+// Wrap returns err, a text/template error, as a SourceError at its
+// position in the file when it is in one of the templates of s. The
+// message is prefixed by what, if not empty. Other errors are prefixed by
+// what only.
+func (s TemplateSources) Wrap(err error, what string) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if m := templateErrorPrefix.FindStringSubmatch(msg); m != nil {
+		if pos, ok := s.at(m[1], m[2], m[3]); ok {
+			msg = msg[len(m[0]):]
+			if what != "" {
+				msg = what + ": " + msg
+			}
+			return &SourceError{Pos: pos, Msg: msg, Err: err}
+		}
+	}
+	if what == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", what, err)
+}
+
+// SourceError is an error at a position in a template file.
+type SourceError struct {
+	Pos SourcePos
+	Msg string
+	// Err is the error as reported without the position, e.g. by
+	// text/template.
+	Err error
+}
+
+func (e *SourceError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
@@ -13,4 +13,23 @@ type YAMLColumn struct {
 	Header    string `yaml:"header"`
 	FieldSpec string `yaml:"fieldSpec,omitempty"`
 	Template  string `yaml:"template,omitempty"`
+	// Format renders the value as a timestamp or duration: age, relative,
+	// rfc3339, local, unix or duration.
+	Format string `yaml:"format,omitempty"`
+	// Default replaces a missing value (JSONPath columns default to "<none>").
+	Default string `yaml:"default,omitempty"`
+	// Separator joins multiple values (default ","); "\n" gives a
+	// multi-line cell.
+	Separator string `yaml:"separator,omitempty"`
+	// MaxItems keeps the first N values and adds "+M more" for the rest.
+	MaxItems int `yaml:"maxItems,omitempty"`
+	// Unique drops repeated values.
+	Unique bool `yaml:"unique,omitempty"`
+	// Type declares how the value is encoded in structured output: string,
+	// integer, number, boolean, date, array or object. Empty infers it.
+	Type string `yaml:"type,omitempty"`
+	// Volatile marks a value that changes while the object does not, such
+	// as an age or countdown rendered by the template. --timeline does not
+	// log its changes.
+	Volatile bool `yaml:"volatile,omitempty"`
 }
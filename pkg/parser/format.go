@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kubectl-cwide/pkg/parser/funcs"
+)
+
+// ValueKind tells how the typed value behind a formatted cell compares.
+type ValueKind int
+
+const (
+	// ValueText means the cell has no typed value; it compares as text.
+	ValueText ValueKind = iota
+	// ValueTime is a timestamp rendered in an absolute format (rfc3339,
+	// local, unix); it compares chronologically.
+	ValueTime
+	// ValueAge is a timestamp rendered as an age (age, relative); it
+	// compares by time elapsed since the timestamp, negative for the future.
+	ValueAge
+	// ValueDuration is a duration; it compares by length.
+	ValueDuration
+)
+
+// CellValue is the typed value behind a formatted cell, so that sorting and
+// filtering can use the underlying time rather than the rendered "3d".
+type CellValue struct {
+	Kind ValueKind
+	// Time is set for ValueTime and ValueAge.
+	Time time.Time
+	// Duration is the elapsed time for ValueAge and the length for
+	// ValueDuration.
+	Duration time.Duration
+	// Data is the cell's value for structured output: the JSONPath result
+	// with its original type (a list for several results, nil for none), the
+	// decoded template output, or the rendered text for formatted columns,
+	// converted to the column's declared type if it has one.
+	Data interface{}
+}
+
+// Key returns a number that orders values of the same kind.
+func (v CellValue) Key() float64 {
+	switch v.Kind {
+	case ValueTime:
+		return float64(v.Time.UnixNano())
+	case ValueAge, ValueDuration:
+		return float64(v.Duration)
+	}
+	return 0
+}
+
+// ParseKey parses a filter operand into a Key for values of kind k:
+// a timestamp for ValueTime, an age ("2h", "in 3d") for ValueAge and a
+// duration for ValueDuration.
+func (k ValueKind) ParseKey(s string) (float64, error) {
+	switch k {
+	case ValueTime:
+		t, ok := funcs.ParseUnixTimestamp(s)
+		if !ok {
+			return 0, fmt.Errorf("invalid timestamp %q (expected RFC3339, YYYY-MM-DD or unix seconds)", s)
+		}
+		return float64(t.UnixNano()), nil
+	case ValueAge:
+		d, err := funcs.ParseAge(s)
+		if err != nil {
+			return 0, err
+		}
+		return float64(d), nil
+	case ValueDuration:
+		d, ok := funcs.ParseDuration(s)
+		if !ok {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return float64(d), nil
+	}
+	return 0, fmt.Errorf("column has no typed value")
+}
+
+// ValidateFormat returns an error if format is not a known column format.
+// The empty string (no formatting) is valid.
+func ValidateFormat(format string) error {
+	if format == "" || funcs.IsTimeFormat(format) {
+		return nil
+	}
+	return fmt.Errorf("unknown format %q (expected one of %s)", format, strings.Join(funcs.TimeFormats, ", "))
+}
+
+// formatCell applies format to a raw cell. Cells that don't parse as the
+// expected type (e.g. "<none>") are returned unchanged with no typed value.
+func formatCell(raw, format string, now time.Time) (string, CellValue) {
+	if format == funcs.TimeFormatDuration {
+		d, ok := funcs.ParseDuration(raw)
+		if !ok {
+			return raw, CellValue{}
+		}
+		return funcs.FormatDuration(d), CellValue{Kind: ValueDuration, Duration: d}
+	}
+	parse := funcs.ParseTimestamp
+	if format == funcs.TimeFormatUnix {
+		parse = funcs.ParseUnixTimestamp
+	}
+	t, ok := parse(raw)
+	if !ok {
+		return raw, CellValue{}
+	}
+	v := CellValue{Kind: ValueTime, Time: t}
+	if format == funcs.TimeFormatAge || format == funcs.TimeFormatRelative {
+		v.Kind = ValueAge
+		v.Duration = now.Sub(t)
+	}
+	return funcs.FormatTimestamp(t, format, now), v
+}
@@ -7,8 +7,6 @@ import (
 	"strconv"
 	"sync/atomic"
 	"time"
-
-	"k8s.io/apimachinery/pkg/util/duration"
 )
 
 // colorDisabled overrides the NO_COLOR env var when set to true.
@@ -59,17 +57,29 @@ func HumanBytes(v interface{}) string {
 }
 
 // Age formats an RFC3339 timestamp string as a human-readable duration since
-// then (e.g. "3d", "5h12m"). Empty or unparseable input returns "".
+// then (e.g. "3d", "5h12m"), or until then for a future timestamp ("in 3d").
+// Empty or unparseable input returns "".
 func Age(v interface{}) string {
+	return ageAt(v, time.Now())
+}
+
+// NewAgeFunction returns Age measured against the clock now.
+func NewAgeFunction(now func() time.Time) func(v interface{}) string {
+	return func(v interface{}) string {
+		return ageAt(v, now())
+	}
+}
+
+func ageAt(v interface{}, now time.Time) string {
 	s, ok := v.(string)
 	if !ok || s == "" {
 		return ""
 	}
-	t, err := time.Parse(time.RFC3339, s)
-	if err != nil {
+	t, ok := ParseTimestamp(s)
+	if !ok {
 		return ""
 	}
-	return duration.HumanDuration(time.Since(t))
+	return FormatTimestamp(t, TimeFormatAge, now)
 }
 
 // Truncate cuts a string at n runes and appends "…" if it was truncated.
@@ -3,6 +3,7 @@ package funcs
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestHumanBytes(t *testing.T) {
@@ -87,3 +88,47 @@ func TestSafeIndex(t *testing.T) {
 		t.Errorf("safeIndex OOB = %v", got)
 	}
 }
+
+func TestAgeFuture(t *testing.T) {
+	future := time.Now().Add(49 * time.Hour).UTC().Format(time.RFC3339)
+	if got := Age(future); got != "in 2d" {
+		t.Errorf("Age(future) = %q", got)
+	}
+	past := time.Now().Add(-90 * time.Minute).UTC().Format(time.RFC3339)
+	if got := Age(past); got != "90m" {
+		t.Errorf("Age(past) = %q", got)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"90":    90 * time.Second,
+		"1h30m": 90 * time.Minute,
+		"2d":    48 * time.Hour,
+		"1d12h": 36 * time.Hour,
+		"-3d":   -72 * time.Hour,
+		"1.5":   1500 * time.Millisecond,
+	}
+	for in, want := range cases {
+		got, ok := ParseDuration(in)
+		if !ok || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", in, got, ok, want)
+		}
+	}
+	if _, ok := ParseDuration("soon"); ok {
+		t.Error("ParseDuration(soon) should fail")
+	}
+}
+
+func TestAgeIgnoresIntegers(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := ageAt("8080", now); got != "" {
+		t.Errorf("age of a port = %q, want empty", got)
+	}
+	if _, ok := ParseTimestamp("3"); ok {
+		t.Error("ParseTimestamp accepted a replica count")
+	}
+	if got, ok := ParseUnixTimestamp("1704067200"); !ok || !got.Equal(now) {
+		t.Errorf("ParseUnixTimestamp = %v, %v", got, ok)
+	}
+}
@@ -0,0 +1,235 @@
+package funcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForwardProtocolV1 is the subprotocol spoken on pods/portforward.
+const portForwardProtocolV1 = "portforward.k8s.io"
+
+// grpcServing is the grpc.health.v1 status a healthy server reports.
+const grpcServing = "SERVING"
+
+// grpcHealthStatuses maps grpc.health.v1.HealthCheckResponse.ServingStatus
+// values to their names.
+var grpcHealthStatuses = map[uint64]string{
+	0: "UNKNOWN",
+	1: grpcServing,
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// portForward is the default forwardFunc. It dials pods/portforward and
+// opens a single data stream to port, the same way `kubectl port-forward`
+// does for each accepted local connection, without a local listener.
+func (c *ProbeChecker) portForward(ctx context.Context, namespace, pod string, port int) (net.Conn, error) {
+	if c.config == nil {
+		return nil, errors.New("no cluster config")
+	}
+	clientset, err := kubernetes.NewForConfig(c.config)
+	if err != nil {
+		return nil, err
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod).SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.config)
+	if err != nil {
+		return nil, err
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+
+	type dialResult struct {
+		conn httpstream.Connection
+		err  error
+	}
+	dialed := make(chan dialResult, 1)
+	go func() {
+		conn, _, err := dialer.Dial(portForwardProtocolV1)
+		dialed <- dialResult{conn, err}
+	}()
+	var conn httpstream.Connection
+	select {
+	case r := <-dialed:
+		if r.err != nil {
+			return nil, r.err
+		}
+		conn = r.conn
+	case <-ctx.Done():
+		go func() {
+			if r := <-dialed; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(port))
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+	errorStream, err := conn.CreateStream(headers)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// We never write to the error stream; the server reports failures to
+	// reach the port on it, which also surface as a reset data stream.
+	errorStream.Close()
+	go io.Copy(io.Discard, errorStream)
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := conn.CreateStream(headers)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &streamConn{Stream: dataStream, conn: conn}, nil
+}
+
+// streamConn adapts a port-forward data stream to net.Conn so an HTTP/2
+// client can run on top of it. Deadlines are not supported; callers bound
+// the exchange with a context instead.
+type streamConn struct {
+	httpstream.Stream
+	conn httpstream.Connection
+}
+
+func (s *streamConn) Close() error {
+	s.Stream.Reset()
+	return s.conn.Close()
+}
+
+func (s *streamConn) LocalAddr() net.Addr                { return streamAddr{} }
+func (s *streamConn) RemoteAddr() net.Addr               { return streamAddr{} }
+func (s *streamConn) SetDeadline(t time.Time) error      { return nil }
+func (s *streamConn) SetReadDeadline(t time.Time) error  { return nil }
+func (s *streamConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type streamAddr struct{}
+
+func (streamAddr) Network() string { return "portforward" }
+func (streamAddr) String() string  { return "portforward" }
+
+// grpcHealthCheck issues grpc.health.v1.Health/Check for service over conn
+// using plaintext HTTP/2 and returns the serving status name. The request
+// and response messages are small enough to encode by hand, which keeps a
+// full gRPC client out of the dependency tree.
+func grpcHealthCheck(ctx context.Context, conn net.Conn, service string) (string, error) {
+	cc, err := (&http2.Transport{AllowHTTP: true}).NewClientConn(conn)
+	if err != nil {
+		return "", err
+	}
+	defer cc.Close()
+
+	// HealthCheckRequest{ string service = 1; }
+	var msg []byte
+	if service != "" {
+		msg = protowire.AppendTag(msg, 1, protowire.BytesType)
+		msg = protowire.AppendString(msg, service)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://localhost/grpc.health.v1.Health/Check", bytes.NewReader(grpcFrame(msg)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+
+	resp, err := cc.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	// A failed call may come back as a trailers-only response, in which case
+	// grpc-status is in the headers.
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	if status != "" && status != "0" {
+		if m := resp.Trailer.Get("Grpc-Message"); m != "" {
+			return "", fmt.Errorf("grpc-status %s: %s", status, m)
+		}
+		return "", fmt.Errorf("grpc-status %s", status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("http %d", resp.StatusCode)
+	}
+
+	payload, err := grpcUnframe(body)
+	if err != nil {
+		return "", err
+	}
+	// HealthCheckResponse{ ServingStatus status = 1; }
+	var code uint64
+	for len(payload) > 0 {
+		num, typ, n := protowire.ConsumeTag(payload)
+		if n < 0 {
+			return "", protowire.ParseError(n)
+		}
+		payload = payload[n:]
+		if num == 1 && typ == protowire.VarintType {
+			v, n := protowire.ConsumeVarint(payload)
+			if n < 0 {
+				return "", protowire.ParseError(n)
+			}
+			code = v
+			payload = payload[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, payload)
+		if n < 0 {
+			return "", protowire.ParseError(n)
+		}
+		payload = payload[n:]
+	}
+	if name, ok := grpcHealthStatuses[code]; ok {
+		return name, nil
+	}
+	return fmt.Sprintf("STATUS_%d", code), nil
+}
+
+// grpcFrame wraps msg in the gRPC length-prefixed message framing
+// (uncompressed flag + big-endian length).
+func grpcFrame(msg []byte) []byte {
+	frame := make([]byte, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(msg)))
+	copy(frame[5:], msg)
+	return frame
+}
+
+// grpcUnframe returns the first message in a gRPC response body.
+func grpcUnframe(body []byte) ([]byte, error) {
+	if len(body) < 5 {
+		return nil, errors.New("short grpc response")
+	}
+	if body[0] != 0 {
+		return nil, errors.New("compressed grpc response not supported")
+	}
+	n := binary.BigEndian.Uint32(body[1:5])
+	if uint32(len(body)-5) < n {
+		return nil, errors.New("truncated grpc response")
+	}
+	return body[5 : 5+n], nil
+}
@@ -33,11 +33,16 @@ import (
 type lookupFunc = func(apiversion string, resource string, namespace string, name string) (map[string]interface{}, error)
 type lookupByLabelFunc = func(apiversion string, resource string, namespace string, labelSelector string) (map[string]interface{}, error)
 
+// ContextFunc returns the context a template function call should run
+// under. It is consulted on every call so that callers can swap in a
+// per-cell context with its own deadline.
+type ContextFunc func() context.Context
+
 // NewLookupFunction returns a function for looking up objects in the cluster.
 //
 // If the resource does not exist, no error is raised.
-func NewLookupFunction(config *rest.Config) lookupFunc {
-	return newLookupFunction(clientProviderFromConfig{config: config})
+func NewLookupFunction(ctx ContextFunc, config *rest.Config) lookupFunc {
+	return newLookupFunction(ctx, clientProviderFromConfig{config: config})
 }
 
 type ClientProvider interface {
@@ -56,11 +61,11 @@ func (c clientProviderFromConfig) GetClientFor(apiVersion, kind string) (dynamic
 }
 
 // NewLookupByLabelFunction returns a function for listing objects by label selector.
-func NewLookupByLabelFunction(config *rest.Config) lookupByLabelFunc {
-	return newLookupByLabelFunction(clientProviderFromConfig{config: config})
+func NewLookupByLabelFunction(ctx ContextFunc, config *rest.Config) lookupByLabelFunc {
+	return newLookupByLabelFunction(ctx, clientProviderFromConfig{config: config})
 }
 
-func newLookupByLabelFunction(clientProvider ClientProvider) lookupByLabelFunc {
+func newLookupByLabelFunction(ctx ContextFunc, clientProvider ClientProvider) lookupByLabelFunc {
 	return func(apiversion string, kind string, namespace string, labelSelector string) (map[string]interface{}, error) {
 		var client dynamic.ResourceInterface
 		c, namespaced, err := clientProvider.GetClientFor(apiversion, kind)
@@ -72,7 +77,7 @@ func newLookupByLabelFunction(clientProvider ClientProvider) lookupByLabelFunc {
 		} else {
 			client = c
 		}
-		obj, err := client.List(context.Background(), metav1.ListOptions{
+		obj, err := client.List(ctx(), metav1.ListOptions{
 			LabelSelector: labelSelector,
 		})
 		if err != nil {
@@ -85,7 +90,7 @@ func newLookupByLabelFunction(clientProvider ClientProvider) lookupByLabelFunc {
 	}
 }
 
-func newLookupFunction(clientProvider ClientProvider) lookupFunc {
+func newLookupFunction(ctx ContextFunc, clientProvider ClientProvider) lookupFunc {
 	return func(apiversion string, kind string, namespace string, name string) (map[string]interface{}, error) {
 		var client dynamic.ResourceInterface
 		c, namespaced, err := clientProvider.GetClientFor(apiversion, kind)
@@ -99,7 +104,7 @@ func newLookupFunction(clientProvider ClientProvider) lookupFunc {
 		}
 		if name != "" {
 			// this will return a single object
-			obj, err := client.Get(context.Background(), name, metav1.GetOptions{})
+			obj, err := client.Get(ctx(), name, metav1.GetOptions{})
 			if err != nil {
 				if apierrors.IsNotFound(err) {
 					// Just return an empty interface when the object was not found.
@@ -112,7 +117,7 @@ func newLookupFunction(clientProvider ClientProvider) lookupFunc {
 			return obj.UnstructuredContent(), nil
 		}
 		// this will return a list
-		obj, err := client.List(context.Background(), metav1.ListOptions{})
+		obj, err := client.List(ctx(), metav1.ListOptions{})
 		if err != nil {
 			if apierrors.IsNotFound(err) {
 				// Just return an empty interface when the object was not found.
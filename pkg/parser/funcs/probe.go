@@ -2,93 +2,358 @@ package funcs
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
 )
 
+// defaultProbeConcurrency bounds how many probe checks run at once across
+// all rows when ProbeChecker.Concurrency is unset.
+const defaultProbeConcurrency = 8
+
+// defaultProbeTimeout bounds a single probe or service check when
+// ProbeChecker.Timeout is unset.
+const defaultProbeTimeout = 5 * time.Second
+
+// execFunc runs command in a container and returns its exit code.
+type execFunc func(ctx context.Context, namespace, pod, container string, command []string) (int, error)
+
+// forwardFunc opens a port-forward stream to a pod port.
+type forwardFunc func(ctx context.Context, namespace, pod string, port int) (net.Conn, error)
+
+// ProbeChecker runs pod probe checks and service checks on behalf of the
+// probeCheck and serviceCheck template functions.
+//
+// HTTP and TCP probes go through the API server's pod proxy, exec probes
+// through the pods/exec subresource (only when AllowExec is set), gRPC
+// probes over a port-forward stream, and service checks through the
+// services proxy. Results are cached per pod, container and probe type for
+// the lifetime of the checker, and checks are bounded by Concurrency across
+// rows; Prefetch starts them ahead of rendering so rows don't wait on each
+// other. The cache key includes the pod's resourceVersion, so a watch
+// re-checks a pod whenever it changes.
+type ProbeChecker struct {
+	// AllowExec enables exec probes. It's off by default because it runs
+	// the probe command inside the workload's container.
+	AllowExec bool
+	// Concurrency bounds how many checks run at once. Zero means
+	// defaultProbeConcurrency.
+	Concurrency int
+	// Timeout bounds each check on its own, whatever the context of its
+	// caller allows, so checks that hang (a blackholed pod IP, a stuck
+	// exec or port-forward) cannot hold every Concurrency slot until the
+	// render deadline. Zero means defaultProbeTimeout.
+	Timeout time.Duration
+
+	ctx    ContextFunc
+	config *rest.Config
+
+	// exec, forward and httpClient are swappable for tests.
+	exec       execFunc
+	forward    forwardFunc
+	httpClient *http.Client
+
+	initOnce sync.Once
+	initErr  error
+	sem      chan struct{}
+
+	mu    sync.Mutex
+	cache map[string]*probeEntry
+}
+
+type probeEntry struct {
+	done   chan struct{}
+	result string
+	// abandoned is set, before done is closed, when the check was cut
+	// short by its caller's context and result is not to be reused.
+	abandoned bool
+}
+
+// NewProbeChecker returns a ProbeChecker whose checks run under ctx().
+func NewProbeChecker(ctx ContextFunc, config *rest.Config) *ProbeChecker {
+	c := &ProbeChecker{ctx: ctx, config: config, cache: map[string]*probeEntry{}}
+	c.exec = c.execInPod
+	c.forward = c.portForward
+	return c
+}
+
 // NewProbeCheckFunction returns a template function that pings a pod's probe endpoint.
 //
 // Usage in templates:
 //
 //	{{ probeCheck . "readiness" }}
 //	{{ probeCheck . "liveness" }}
-//	{{ probeCheck . "startup" }}
-func NewProbeCheckFunction(config *rest.Config) func(obj interface{}, probeType string) string {
-	return func(obj interface{}, probeType string) string {
-		m, ok := obj.(map[string]interface{})
-		if !ok {
-			return "ERR (not a map)"
-		}
+//	{{ probeCheck . "startup" "sidecar" }}
+//
+// Each check runs under ctx(), so its deadline and cancellation come from
+// the cell being rendered rather than a fixed client timeout.
+func NewProbeCheckFunction(ctx ContextFunc, config *rest.Config) func(obj interface{}, probeType string, container ...string) string {
+	return NewProbeChecker(ctx, config).Check
+}
 
-		namespace := nestedString(m, "metadata", "namespace")
-		podName := nestedString(m, "metadata", "name")
-		if namespace == "" || podName == "" {
-			return "ERR (no pod identity)"
+// Check implements probeCheck. With a container name only that container's
+// probe is checked; otherwise every container defining the probe is, and
+// results are prefixed with the container name when there is more than one.
+func (c *ProbeChecker) Check(obj interface{}, probeType string, container ...string) string {
+	return c.CheckContext(c.context(), obj, probeType, container...)
+}
+
+// CheckContext is Check, run under ctx instead of the checker's context.
+func (c *ProbeChecker) CheckContext(ctx context.Context, obj interface{}, probeType string, container ...string) string {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return "ERR (not a map)"
+	}
+	namespace := nestedString(m, "metadata", "namespace")
+	podName := nestedString(m, "metadata", "name")
+	if namespace == "" || podName == "" {
+		return "ERR (no pod identity)"
+	}
+
+	var only string
+	if len(container) > 0 {
+		only = container[0]
+		if findContainer(m, only) == nil {
+			return fmt.Sprintf("ERR (no container %q)", only)
 		}
+	}
+
+	probed := probedContainers(m, probeType, only)
+	if len(probed) == 0 {
+		return "N/A"
+	}
+
+	if len(probed) == 1 {
+		return c.cached(ctx, probeKey(m, probed[0].name, probeType), func(ctx context.Context) string {
+			return c.checkProbe(ctx, namespace, podName, probed[0])
+		})
+	}
+	parts := make([]string, len(probed))
+	for i, pc := range probed {
+		pc := pc
+		res := c.cached(ctx, probeKey(m, pc.name, probeType), func(ctx context.Context) string {
+			return c.checkProbe(ctx, namespace, podName, pc)
+		})
+		parts[i] = pc.name + ": " + res
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ServiceCheck implements serviceCheck: a GET through the services proxy.
+//
+//	{{ serviceCheck . }}                 first service port, path "/"
+//	{{ serviceCheck . "http" "/healthz" }}
+//
+// The port may be a port name or number, optionally prefixed with "https:".
+func (c *ProbeChecker) ServiceCheck(obj interface{}, args ...string) string {
+	return c.ServiceCheckContext(c.context(), obj, args...)
+}
+
+// ServiceCheckContext is ServiceCheck, run under ctx instead of the
+// checker's context.
+func (c *ProbeChecker) ServiceCheckContext(ctx context.Context, obj interface{}, args ...string) string {
+	m, ok := obj.(map[string]interface{})
+	if !ok {
+		return "ERR (not a map)"
+	}
+	namespace := nestedString(m, "metadata", "namespace")
+	name := nestedString(m, "metadata", "name")
+	if namespace == "" || name == "" {
+		return "ERR (no service identity)"
+	}
 
-		probe := extractProbe(m, probeType)
-		if probe == nil {
+	var port, path string
+	if len(args) > 0 {
+		port = args[0]
+	}
+	if len(args) > 1 {
+		path = args[1]
+	}
+	if port == "" {
+		ports, _ := nestedSlice(m, "spec", "ports")
+		if len(ports) == 0 {
 			return "N/A"
 		}
+		first, _ := ports[0].(map[string]interface{})
+		port = fmt.Sprintf("%v", first["port"])
+	}
+	scheme := ""
+	if p, ok := strings.CutPrefix(port, "https:"); ok {
+		scheme, port = "https:", p
+	}
+	if path == "" {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
 
-		if _, ok := probe["exec"]; ok {
-			return "N/A (exec)"
+	proxyPath := fmt.Sprintf("/api/v1/namespaces/%s/services/%s%s:%s/proxy%s", namespace, scheme, name, port, path)
+	key := strings.Join([]string{"svc", namespace, name, scheme + port, path}, "/")
+	return c.cached(ctx, key, func(ctx context.Context) string {
+		return c.getStatus(ctx, proxyPath)
+	})
+}
+
+// Prefetch starts checks for the given pods and probe types in the
+// background, bounded by Concurrency, so Check finds them cached (or in
+// flight) when rows are rendered. It returns immediately.
+func (c *ProbeChecker) Prefetch(ctx context.Context, pods []map[string]interface{}, probeTypes []string) {
+	for _, pod := range pods {
+		namespace := nestedString(pod, "metadata", "namespace")
+		podName := nestedString(pod, "metadata", "name")
+		if namespace == "" || podName == "" {
+			continue
 		}
-		if _, ok := probe["grpc"]; ok {
-			return "N/A (grpc)"
+		for _, probeType := range probeTypes {
+			for _, pc := range probedContainers(pod, probeType, "") {
+				pc, key := pc, probeKey(pod, pc.name, probeType)
+				go c.cached(ctx, key, func(ctx context.Context) string {
+					return c.checkProbe(ctx, namespace, podName, pc)
+				})
+			}
 		}
+	}
+}
 
-		if httpGet, ok := probe["httpGet"].(map[string]interface{}); ok {
-			return checkHTTPProbe(config, namespace, podName, httpGet)
+func (c *ProbeChecker) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx()
+}
+
+// cached returns the result for key, running fn (bounded by the checker's
+// concurrency limit) if no other caller has started it yet. A result cut
+// short by the context of the caller that ran it (its cell or render
+// timing out, waiting for the semaphore or during fn) is not cached: the
+// callers waiting for it run the check again under their own contexts.
+func (c *ProbeChecker) cached(ctx context.Context, key string, fn func(ctx context.Context) string) string {
+	for {
+		c.mu.Lock()
+		e, ok := c.cache[key]
+		if !ok {
+			e = &probeEntry{done: make(chan struct{})}
+			c.cache[key] = e
+			c.mu.Unlock()
+			return c.run(ctx, key, e, fn)
+		}
+		c.mu.Unlock()
+		select {
+		case <-e.done:
+			if !e.abandoned {
+				return e.result
+			}
+		case <-ctx.Done():
+			return fmt.Sprintf("FAIL (%s)", truncateError(ctx.Err()))
 		}
+	}
+}
 
-		if tcpSocket, ok := probe["tcpSocket"].(map[string]interface{}); ok {
-			return checkTCPProbe(config, namespace, podName, tcpSocket)
+// run runs fn for entry e of key, bounded by the checker's Timeout, and
+// records its result or, if ctx ended first, drops e from the cache. The
+// semaphore slot is released and the waiters on e woken even if fn panics.
+func (c *ProbeChecker) run(ctx context.Context, key string, e *probeEntry, fn func(ctx context.Context) string) string {
+	select {
+	case c.semaphore() <- struct{}{}:
+	case <-ctx.Done():
+		c.abandon(key, e)
+		return fmt.Sprintf("FAIL (%s)", truncateError(ctx.Err()))
+	}
+	finished := false
+	defer func() {
+		<-c.sem
+		if finished {
+			close(e.done)
+		} else {
+			c.abandon(key, e)
 		}
+	}()
 
-		return "N/A (unknown)"
+	checkCtx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	result := fn(checkCtx)
+	if ctx.Err() != nil {
+		return result
 	}
+	e.result = result
+	finished = true
+	return result
 }
 
-// extractProbe navigates the unstructured pod to find the probe spec.
-func extractProbe(pod map[string]interface{}, probeType string) map[string]interface{} {
-	var probeField string
-	switch strings.ToLower(probeType) {
-	case "readiness":
-		probeField = "readinessProbe"
-	case "liveness":
-		probeField = "livenessProbe"
-	case "startup":
-		probeField = "startupProbe"
-	default:
-		return nil
+func (c *ProbeChecker) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
 	}
+	return defaultProbeTimeout
+}
 
-	containers, ok := nestedSlice(pod, "spec", "containers")
-	if !ok || len(containers) == 0 {
-		return nil
+// abandon drops entry e of key from the cache and wakes its waiters to
+// retry.
+func (c *ProbeChecker) abandon(key string, e *probeEntry) {
+	c.mu.Lock()
+	if c.cache[key] == e {
+		delete(c.cache, key)
 	}
+	c.mu.Unlock()
+	e.abandoned = true
+	close(e.done)
+}
 
-	// Check the first container
-	container, ok := containers[0].(map[string]interface{})
-	if !ok {
-		return nil
+func (c *ProbeChecker) semaphore() chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sem == nil {
+		n := c.Concurrency
+		if n <= 0 {
+			n = defaultProbeConcurrency
+		}
+		c.sem = make(chan struct{}, n)
 	}
+	return c.sem
+}
 
-	probe, ok := container[probeField].(map[string]interface{})
-	if !ok {
-		return nil
+// probedContainer is a container that defines the probe being checked.
+type probedContainer struct {
+	name      string
+	container map[string]interface{}
+	probe     map[string]interface{}
+}
+
+func (c *ProbeChecker) checkProbe(ctx context.Context, namespace, podName string, pc probedContainer) string {
+	probe := pc.probe
+	if execSpec, ok := probe["exec"].(map[string]interface{}); ok {
+		if !c.AllowExec {
+			return "N/A (exec disabled)"
+		}
+		return c.checkExecProbe(ctx, namespace, podName, pc.name, execSpec)
+	}
+	if grpc, ok := probe["grpc"].(map[string]interface{}); ok {
+		return c.checkGRPCProbe(ctx, namespace, podName, pc.container, grpc)
+	}
+	if httpGet, ok := probe["httpGet"].(map[string]interface{}); ok {
+		return c.checkHTTPProbe(ctx, namespace, podName, pc.container, httpGet)
 	}
-	return probe
+	if tcpSocket, ok := probe["tcpSocket"].(map[string]interface{}); ok {
+		return c.checkTCPProbe(ctx, namespace, podName, pc.container, tcpSocket)
+	}
+	return "N/A (unknown)"
 }
 
 // checkHTTPProbe uses the Kubernetes API server pod proxy to reach an httpGet probe.
-func checkHTTPProbe(config *rest.Config, namespace, podName string, httpGet map[string]interface{}) string {
+func (c *ProbeChecker) checkHTTPProbe(ctx context.Context, namespace, podName string, container, httpGet map[string]interface{}) string {
 	path, _ := httpGet["path"].(string)
 	if path == "" {
 		path = "/"
@@ -97,7 +362,6 @@ func checkHTTPProbe(config *rest.Config, namespace, podName string, httpGet map[
 		path = "/" + path
 	}
 
-	port := httpGet["port"]
 	scheme := strings.ToLower(fmt.Sprintf("%v", httpGet["scheme"]))
 	if scheme == "" || scheme == "<nil>" {
 		scheme = "http"
@@ -105,31 +369,79 @@ func checkHTTPProbe(config *rest.Config, namespace, podName string, httpGet map[
 
 	// Build the API server proxy URL:
 	// /api/v1/namespaces/{ns}/pods/{scheme}:{name}:{port}/proxy{path}
-	proxyPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s:%s:%v/proxy%s",
-		namespace, scheme, podName, port, path)
+	proxyPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s:%s:%s/proxy%s",
+		namespace, scheme, podName, resolvePort(container, httpGet["port"]), path)
+	return c.getStatus(ctx, proxyPath)
+}
 
-	transport, err := rest.TransportFor(config)
+// checkTCPProbe uses the Kubernetes API server pod proxy to check TCP connectivity.
+func (c *ProbeChecker) checkTCPProbe(ctx context.Context, namespace, podName string, container, tcpSocket map[string]interface{}) string {
+	// Use the API server proxy — if the port is open, the proxy succeeds.
+	// /api/v1/namespaces/{ns}/pods/{name}:{port}/proxy/
+	proxyPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s:%s/proxy/",
+		namespace, podName, resolvePort(container, tcpSocket["port"]))
+
+	resp, err := c.get(ctx, proxyPath)
 	if err != nil {
-		return fmt.Sprintf("ERR (%s)", err)
+		return fmt.Sprintf("FAIL (%s)", truncateError(err))
 	}
+	defer resp.Body.Close()
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   5 * time.Second,
+	// Any response (even 4xx) means the TCP port is reachable
+	return "OK"
+}
+
+// checkExecProbe runs the probe command in the container via pods/exec.
+func (c *ProbeChecker) checkExecProbe(ctx context.Context, namespace, podName, container string, execSpec map[string]interface{}) string {
+	raw, _ := execSpec["command"].([]interface{})
+	command := make([]string, 0, len(raw))
+	for _, arg := range raw {
+		command = append(command, fmt.Sprintf("%v", arg))
+	}
+	if len(command) == 0 {
+		return "ERR (empty exec command)"
 	}
 
-	host := config.Host
-	if !strings.HasPrefix(host, "http") {
-		host = "https://" + host
+	code, err := c.exec(ctx, namespace, podName, container, command)
+	if err != nil {
+		return fmt.Sprintf("FAIL (%s)", truncateError(err))
+	}
+	if code != 0 {
+		return fmt.Sprintf("FAIL (exit %d)", code)
 	}
-	url := host + proxyPath
+	return "OK"
+}
 
-	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+// checkGRPCProbe runs the standard grpc.health.v1 Check over a
+// port-forward stream to the probe port.
+func (c *ProbeChecker) checkGRPCProbe(ctx context.Context, namespace, podName string, container, grpc map[string]interface{}) string {
+	port, err := strconv.Atoi(resolvePort(container, grpc["port"]))
 	if err != nil {
-		return fmt.Sprintf("ERR (%s)", err)
+		return fmt.Sprintf("ERR (bad grpc port %v)", grpc["port"])
 	}
+	service, _ := grpc["service"].(string)
 
-	resp, err := client.Do(req)
+	conn, err := c.forward(ctx, namespace, podName, port)
+	if err != nil {
+		return fmt.Sprintf("FAIL (%s)", truncateError(err))
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	status, err := grpcHealthCheck(ctx, conn, service)
+	if err != nil {
+		return fmt.Sprintf("FAIL (%s)", truncateError(err))
+	}
+	if status != grpcServing {
+		return fmt.Sprintf("FAIL (%s)", status)
+	}
+	return fmt.Sprintf("OK (%s)", status)
+}
+
+// getStatus GETs an API server path and reports the HTTP status code.
+func (c *ProbeChecker) getStatus(ctx context.Context, path string) string {
+	resp, err := c.get(ctx, path)
 	if err != nil {
 		return fmt.Sprintf("FAIL (%s)", truncateError(err))
 	}
@@ -141,52 +453,156 @@ func checkHTTPProbe(config *rest.Config, namespace, podName string, httpGet map[
 	return fmt.Sprintf("FAIL (%d)", resp.StatusCode)
 }
 
-// checkTCPProbe uses the Kubernetes API server pod proxy to check TCP connectivity.
-func checkTCPProbe(config *rest.Config, namespace, podName string, tcpSocket map[string]interface{}) string {
-	port := tcpSocket["port"]
-
-	// Use the API server proxy — if the port is open, the proxy succeeds.
-	// /api/v1/namespaces/{ns}/pods/{name}:{port}/proxy/
-	proxyPath := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s:%v/proxy/",
-		namespace, podName, port)
+func (c *ProbeChecker) get(ctx context.Context, path string) (*http.Response, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
 
-	transport, err := rest.TransportFor(config)
+	host := c.config.Host
+	if !strings.HasPrefix(host, "http") {
+		host = "https://" + host
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(host, "/")+path, nil)
 	if err != nil {
-		return fmt.Sprintf("ERR (%s)", err)
+		return nil, err
 	}
+	return c.httpClient.Do(req)
+}
 
-	// For TCP checks, we skip TLS verification on the proxy response since
-	// we just care about connectivity.
-	if t, ok := transport.(*http.Transport); ok {
-		if t.TLSClientConfig == nil {
-			t.TLSClientConfig = &tls.Config{}
+// init builds the shared HTTP client once per checker instead of per call.
+func (c *ProbeChecker) init() error {
+	c.initOnce.Do(func() {
+		if c.config == nil {
+			c.initErr = errors.New("no cluster config")
+			return
+		}
+		if c.httpClient != nil {
+			return
+		}
+		transport, err := rest.TransportFor(c.config)
+		if err != nil {
+			c.initErr = err
+			return
 		}
+		c.httpClient = &http.Client{Transport: transport, Timeout: c.timeout()}
+	})
+	return c.initErr
+}
+
+// execInPod is the default execFunc, backed by the pods/exec subresource.
+func (c *ProbeChecker) execInPod(ctx context.Context, namespace, pod, container string, command []string) (int, error) {
+	if c.config == nil {
+		return -1, errors.New("no cluster config")
 	}
+	clientset, err := kubernetes.NewForConfig(c.config)
+	if err != nil {
+		return -1, err
+	}
+	req := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(namespace).Name(pod).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(c.config, http.MethodPost, req.URL())
+	if err != nil {
+		return -1, err
+	}
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: io.Discard, Stderr: io.Discard})
+	if err == nil {
+		return 0, nil
+	}
+	var exitErr interface{ ExitStatus() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+	return -1, err
+}
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   5 * time.Second,
+// probedContainers returns the containers (all, or just `only`) that
+// define the given probe type.
+func probedContainers(pod map[string]interface{}, probeType, only string) []probedContainer {
+	probeField := probeFieldName(probeType)
+	if probeField == "" {
+		return nil
+	}
+	containers, ok := nestedSlice(pod, "spec", "containers")
+	if !ok {
+		return nil
+	}
+	var out []probedContainer
+	for _, raw := range containers {
+		container, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := container["name"].(string)
+		if only != "" && name != only {
+			continue
+		}
+		probe, ok := container[probeField].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		out = append(out, probedContainer{name: name, container: container, probe: probe})
 	}
+	return out
+}
 
-	host := config.Host
-	if !strings.HasPrefix(host, "http") {
-		host = "https://" + host
+func probeFieldName(probeType string) string {
+	switch strings.ToLower(probeType) {
+	case "readiness":
+		return "readinessProbe"
+	case "liveness":
+		return "livenessProbe"
+	case "startup":
+		return "startupProbe"
 	}
-	url := host + proxyPath
+	return ""
+}
 
-	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
-	if err != nil {
-		return fmt.Sprintf("ERR (%s)", err)
+func findContainer(pod map[string]interface{}, name string) map[string]interface{} {
+	containers, _ := nestedSlice(pod, "spec", "containers")
+	for _, raw := range containers {
+		if container, ok := raw.(map[string]interface{}); ok && container["name"] == name {
+			return container
+		}
 	}
+	return nil
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Sprintf("FAIL (%s)", truncateError(err))
+// resolvePort turns a probe port (number, numeric string or named port)
+// into a port number string, looking named ports up in the container spec.
+func resolvePort(container map[string]interface{}, port interface{}) string {
+	s := fmt.Sprintf("%v", port)
+	if _, err := strconv.Atoi(s); err == nil {
+		return s
 	}
-	defer resp.Body.Close()
+	if n, ok := toInt64(port); ok {
+		return strconv.FormatInt(n, 10)
+	}
+	ports, _ := nestedSlice(container, "ports")
+	for _, raw := range ports {
+		if p, ok := raw.(map[string]interface{}); ok && p["name"] == s {
+			return fmt.Sprintf("%v", p["containerPort"])
+		}
+	}
+	return s
+}
 
-	// Any response (even 4xx) means the TCP port is reachable
-	return "OK"
+func probeKey(pod map[string]interface{}, container, probeType string) string {
+	return strings.Join([]string{
+		"pod",
+		nestedString(pod, "metadata", "namespace"),
+		nestedString(pod, "metadata", "name"),
+		nestedString(pod, "metadata", "uid"),
+		nestedString(pod, "metadata", "resourceVersion"),
+		container,
+		strings.ToLower(probeType),
+	}, "/")
 }
 
 // nestedString safely extracts a string from a nested map.
@@ -0,0 +1,301 @@
+package funcs
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"k8s.io/client-go/rest"
+)
+
+// fakeAPIServer answers pod and service proxy requests: paths containing
+// "healthy" return 200, everything else 503. It counts requests per path.
+func fakeAPIServer(t *testing.T) (*httptest.Server, *sync.Map) {
+	t.Helper()
+	hits := &sync.Map{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := hits.LoadOrStore(r.URL.Path, new(int32))
+		atomic.AddInt32(n.(*int32), 1)
+		if strings.Contains(r.URL.Path, "healthy") {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, hits
+}
+
+func testPod(containers ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web", "namespace": "default", "uid": "u1"},
+		"spec":     map[string]interface{}{"containers": containers},
+	}
+}
+
+func httpContainer(name, path string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":  name,
+		"ports": []interface{}{map[string]interface{}{"name": "http", "containerPort": int64(8080)}},
+		"readinessProbe": map[string]interface{}{
+			"httpGet": map[string]interface{}{"path": path, "port": "http"},
+		},
+	}
+}
+
+func TestProbeCheckPerContainer(t *testing.T) {
+	srv, _ := fakeAPIServer(t)
+	c := NewProbeChecker(nil, &rest.Config{Host: srv.URL})
+	pod := testPod(httpContainer("app", "/healthy"), httpContainer("sidecar", "/broken"))
+
+	if got := c.Check(pod, "readiness", "app"); got != "OK (200)" {
+		t.Errorf("app = %q", got)
+	}
+	if got := c.Check(pod, "readiness", "sidecar"); got != "FAIL (503)" {
+		t.Errorf("sidecar = %q", got)
+	}
+	if got := c.Check(pod, "readiness"); got != "app: OK (200), sidecar: FAIL (503)" {
+		t.Errorf("all containers = %q", got)
+	}
+	if got := c.Check(pod, "readiness", "nope"); !strings.HasPrefix(got, "ERR") {
+		t.Errorf("unknown container = %q", got)
+	}
+	if got := c.Check(pod, "liveness"); got != "N/A" {
+		t.Errorf("undefined probe = %q", got)
+	}
+}
+
+func TestProbeCheckNamedPortAndCache(t *testing.T) {
+	srv, hits := fakeAPIServer(t)
+	c := NewProbeChecker(nil, &rest.Config{Host: srv.URL})
+	pod := testPod(httpContainer("app", "/healthy"))
+
+	c.Check(pod, "readiness")
+	c.Check(pod, "readiness")
+
+	const path = "/api/v1/namespaces/default/pods/http:web:8080/proxy/healthy"
+	n, ok := hits.Load(path)
+	if !ok {
+		t.Fatalf("named port not resolved; no request to %s", path)
+	}
+	if got := atomic.LoadInt32(n.(*int32)); got != 1 {
+		t.Errorf("want 1 request (cached), got %d", got)
+	}
+}
+
+func TestProbeCheckPrefetch(t *testing.T) {
+	srv, hits := fakeAPIServer(t)
+	c := NewProbeChecker(nil, &rest.Config{Host: srv.URL})
+	c.Concurrency = 2
+
+	var pods []map[string]interface{}
+	for _, name := range []string{"a", "b", "c"} {
+		p := testPod(httpContainer("app", "/healthy"))
+		p["metadata"].(map[string]interface{})["name"] = name
+		pods = append(pods, p)
+	}
+	c.Prefetch(context.Background(), pods, []string{"readiness"})
+	for _, p := range pods {
+		if got := c.Check(p, "readiness"); got != "OK (200)" {
+			t.Errorf("%v = %q", p["metadata"], got)
+		}
+	}
+	count := 0
+	hits.Range(func(_, _ interface{}) bool { count++; return true })
+	if count != 3 {
+		t.Errorf("want one request per pod, got %d paths", count)
+	}
+}
+
+func TestProbeCheckExecOptIn(t *testing.T) {
+	pod := testPod(map[string]interface{}{
+		"name": "app",
+		"livenessProbe": map[string]interface{}{
+			"exec": map[string]interface{}{"command": []interface{}{"cat", "/tmp/healthy"}},
+		},
+	})
+
+	c := NewProbeChecker(nil, &rest.Config{})
+	var ran []string
+	c.exec = func(ctx context.Context, ns, name, container string, command []string) (int, error) {
+		ran = command
+		return 1, nil
+	}
+	if got := c.Check(pod, "liveness"); got != "N/A (exec disabled)" {
+		t.Errorf("exec without opt-in = %q", got)
+	}
+	if ran != nil {
+		t.Fatal("exec probe ran without opt-in")
+	}
+
+	c = NewProbeChecker(nil, &rest.Config{})
+	c.AllowExec = true
+	c.exec = func(ctx context.Context, ns, name, container string, command []string) (int, error) {
+		ran = command
+		return 1, nil
+	}
+	if got := c.Check(pod, "liveness"); got != "FAIL (exit 1)" {
+		t.Errorf("exec = %q", got)
+	}
+	if strings.Join(ran, " ") != "cat /tmp/healthy" {
+		t.Errorf("ran %v", ran)
+	}
+}
+
+func TestProbeCheckGRPC(t *testing.T) {
+	pod := testPod(map[string]interface{}{
+		"name": "app",
+		"readinessProbe": map[string]interface{}{
+			"grpc": map[string]interface{}{"port": int64(9090), "service": "ready"},
+		},
+	})
+
+	c := NewProbeChecker(nil, &rest.Config{})
+	c.forward = func(ctx context.Context, ns, name string, port int) (net.Conn, error) {
+		if port != 9090 {
+			t.Errorf("forwarded port %d", port)
+		}
+		client, server := net.Pipe()
+		go (&http2.Server{}).ServeConn(server, &http2.ServeConnOpts{Handler: http.HandlerFunc(grpcHealthHandler)})
+		return client, nil
+	}
+	if got := c.Check(pod, "readiness"); got != "OK (SERVING)" {
+		t.Errorf("grpc = %q", got)
+	}
+}
+
+// grpcHealthHandler answers grpc.health.v1.Health/Check with SERVING.
+func grpcHealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/grpc.health.v1.Health/Check" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status")
+	w.WriteHeader(http.StatusOK)
+	// HealthCheckResponse{status: SERVING} = field 1, varint 1.
+	w.Write(grpcFrame([]byte{0x08, 0x01}))
+	w.Header().Set("Grpc-Status", "0")
+}
+
+func TestServiceCheck(t *testing.T) {
+	srv, hits := fakeAPIServer(t)
+	c := NewProbeChecker(nil, &rest.Config{Host: srv.URL})
+	svc := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "healthy-svc", "namespace": "default"},
+		"spec":     map[string]interface{}{"ports": []interface{}{map[string]interface{}{"port": int64(80)}}},
+	}
+	if got := c.ServiceCheck(svc); got != "OK (200)" {
+		t.Errorf("serviceCheck = %q", got)
+	}
+	if _, ok := hits.Load("/api/v1/namespaces/default/services/healthy-svc:80/proxy/"); !ok {
+		t.Error("services proxy path not requested")
+	}
+}
+
+func TestProbeCheckTimeoutNotCached(t *testing.T) {
+	srv, hits := fakeAPIServer(t)
+	c := NewProbeChecker(nil, &rest.Config{Host: srv.URL})
+	c.Concurrency = 1
+	pod := testPod(httpContainer("app", "/healthy"))
+
+	// The only slot is taken, so the first caller times out waiting for it.
+	c.semaphore() <- struct{}{}
+	expired, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got := c.CheckContext(expired, pod, "readiness"); !strings.HasPrefix(got, "FAIL (context canceled") {
+		t.Fatalf("expired caller = %q", got)
+	}
+	<-c.sem
+
+	// A later caller, with time left, runs the check instead of reusing
+	// the failure.
+	if got := c.CheckContext(context.Background(), pod, "readiness"); got != "OK (200)" {
+		t.Errorf("later caller = %q", got)
+	}
+	count := 0
+	hits.Range(func(_, _ interface{}) bool { count++; return true })
+	if count != 1 {
+		t.Errorf("want the check to run once, got %d paths", count)
+	}
+}
+
+func TestProbeCheckWaiterRetriesAbandonedCheck(t *testing.T) {
+	c := NewProbeChecker(nil, &rest.Config{})
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan string)
+	go func() {
+		first <- c.cached(ctx, "k", func(ctx context.Context) string {
+			close(started)
+			<-ctx.Done()
+			return "FAIL (context canceled)"
+		})
+	}()
+	<-started
+	waiter := make(chan string)
+	go func() {
+		waiter <- c.cached(context.Background(), "k", func(ctx context.Context) string { return "OK (200)" })
+	}()
+	cancel()
+	if got := <-first; got != "FAIL (context canceled)" {
+		t.Errorf("first = %q", got)
+	}
+	if got := <-waiter; got != "OK (200)" {
+		t.Errorf("waiter = %q, want the check run again", got)
+	}
+}
+
+func TestProbeCheckHungCheckFreesSlot(t *testing.T) {
+	c := NewProbeChecker(nil, &rest.Config{})
+	c.Concurrency = 1
+	c.Timeout = 10 * time.Millisecond
+
+	// The caller's context never ends, so only the check's own timeout
+	// frees the single slot for the next check.
+	got := c.cached(context.Background(), "hung", func(ctx context.Context) string {
+		<-ctx.Done()
+		return fmt.Sprintf("FAIL (%s)", ctx.Err())
+	})
+	if got != "FAIL (context deadline exceeded)" {
+		t.Errorf("hung check = %q", got)
+	}
+	if got := c.cached(context.Background(), "next", func(ctx context.Context) string { return "OK (200)" }); got != "OK (200)" {
+		t.Errorf("next check = %q", got)
+	}
+}
+
+func TestProbeCheckPanicReleasesSlot(t *testing.T) {
+	c := NewProbeChecker(nil, &rest.Config{})
+	c.Concurrency = 1
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("want the check to panic")
+			}
+		}()
+		c.cached(context.Background(), "k", func(ctx context.Context) string { panic("boom") })
+	}()
+
+	// The slot is free and the entry is gone, so the key runs again.
+	done := make(chan string)
+	go func() {
+		done <- c.cached(context.Background(), "k", func(ctx context.Context) string { return "OK (200)" })
+	}()
+	select {
+	case got := <-done:
+		if got != "OK (200)" {
+			t.Errorf("retry = %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("retry blocked on the panicked check")
+	}
+}
@@ -0,0 +1,172 @@
+package funcs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+// Formats accepted by the column `format:` option and --time-format.
+const (
+	// TimeFormatAge renders a timestamp like kubectl's AGE column: "3d",
+	// or "in 3d" for a future timestamp.
+	TimeFormatAge = "age"
+	// TimeFormatRelative is TimeFormatAge with an explicit direction:
+	// "3d ago" or "in 3d".
+	TimeFormatRelative = "relative"
+	// TimeFormatRFC3339 renders a timestamp as RFC3339 in UTC.
+	TimeFormatRFC3339 = "rfc3339"
+	// TimeFormatLocal renders a timestamp in the local time zone.
+	TimeFormatLocal = "local"
+	// TimeFormatUnix renders a timestamp as seconds since the epoch.
+	TimeFormatUnix = "unix"
+	// TimeFormatDuration renders a duration ("90s", "1h30m", "2d") in the
+	// same short form as an age.
+	TimeFormatDuration = "duration"
+)
+
+// TimeFormats lists every value accepted by the column `format:` option.
+var TimeFormats = []string{TimeFormatAge, TimeFormatRelative, TimeFormatRFC3339, TimeFormatLocal, TimeFormatUnix, TimeFormatDuration}
+
+// localTimeLayout is used by TimeFormatLocal.
+const localTimeLayout = "2006-01-02 15:04:05 MST"
+
+// IsTimeFormat reports whether format is one of TimeFormats.
+func IsTimeFormat(format string) bool {
+	for _, f := range TimeFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTimestampFormat reports whether format renders a point in time, i.e.
+// any of TimeFormats except TimeFormatDuration. Only these are affected by
+// --time-format.
+func IsTimestampFormat(format string) bool {
+	return format != TimeFormatDuration && IsTimeFormat(format)
+}
+
+// ParseTimestamp parses a timestamp as found in Kubernetes objects: RFC3339
+// (with or without fractional seconds) or a bare date. A bare integer is not
+// a timestamp: replica counts and ports would read as dates in 1970; see
+// ParseUnixTimestamp.
+func ParseTimestamp(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseUnixTimestamp is ParseTimestamp that also accepts seconds since the
+// epoch, for values known to be timestamps: format: unix columns and the
+// operands of filters on time columns.
+func ParseUnixTimestamp(s string) (time.Time, bool) {
+	if t, ok := ParseTimestamp(s); ok {
+		return t, true
+	}
+	if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+		return time.Unix(n, 0).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// ParseDuration parses a Go duration ("1h30m"), a duration with a day
+// component ("2d", "1d12h"), or a plain number of seconds.
+func ParseDuration(s string) (time.Duration, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), true
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, true
+	}
+	neg := strings.HasPrefix(s, "-")
+	rest := strings.TrimPrefix(s, "-")
+	i := strings.Index(rest, "d")
+	if i <= 0 {
+		return 0, false
+	}
+	days, err := strconv.Atoi(rest[:i])
+	if err != nil {
+		return 0, false
+	}
+	d := time.Duration(days) * 24 * time.Hour
+	if tail := rest[i+1:]; tail != "" {
+		extra, err := time.ParseDuration(tail)
+		if err != nil || extra < 0 {
+			return 0, false
+		}
+		d += extra
+	}
+	if neg {
+		d = -d
+	}
+	return d, true
+}
+
+// FormatTimestamp renders t in one of the timestamp formats, measuring
+// ages relative to now.
+func FormatTimestamp(t time.Time, format string, now time.Time) string {
+	switch format {
+	case TimeFormatRFC3339:
+		return t.UTC().Format(time.RFC3339)
+	case TimeFormatLocal:
+		return t.Local().Format(localTimeLayout)
+	case TimeFormatUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case TimeFormatRelative:
+		if t.After(now) {
+			return "in " + duration.HumanDuration(t.Sub(now))
+		}
+		return duration.HumanDuration(now.Sub(t)) + " ago"
+	default:
+		if t.After(now) {
+			return "in " + duration.HumanDuration(t.Sub(now))
+		}
+		return duration.HumanDuration(now.Sub(t))
+	}
+}
+
+// FormatDuration renders d in the short form used for ages ("45s", "5h12m",
+// "3d"). Negative durations get a leading "-".
+func FormatDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + duration.HumanDuration(-d)
+	}
+	return duration.HumanDuration(d)
+}
+
+// ParseAge parses the value an age is compared against in a filter: a
+// duration ("2h", "3d"), or "in <duration>" for a timestamp that far in the
+// future. The result is the signed time elapsed since the timestamp, so
+// future timestamps are negative.
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	future := false
+	if rest, ok := strings.CutPrefix(s, "in "); ok {
+		s, future = rest, true
+	}
+	s = strings.TrimSuffix(s, " ago")
+	d, ok := ParseDuration(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid age %q (expected a duration like 2h or 3d, or \"in 3d\")", s)
+	}
+	if future {
+		d = -d
+	}
+	return d, nil
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// defaultMissingValue is rendered when a JSONPath column matches nothing
+// and no Default is configured.
+const defaultMissingValue = "<none>"
+
+// defaultSeparator joins multiple JSONPath results.
+const defaultSeparator = ","
+
+// ListOptions controls how a cell with several values is rendered. They
+// apply to every JSONPath column, and to template columns whose output is a
+// JSON array (e.g. `{{ ... | toJson }}`) or, when Separator, MaxItems or
+// Unique is set, spans several lines (one item per line).
+type ListOptions struct {
+	// Default replaces a missing value; empty means "<none>" for JSONPath
+	// columns and leaves template output as is.
+	Default string
+	// Separator joins the values; empty means ",". Use "\n" for a
+	// multi-line cell.
+	Separator string
+	// MaxItems, when positive, keeps the first MaxItems values and adds a
+	// "+N more" item for the rest.
+	MaxItems int
+	// Unique drops repeated values, keeping the first occurrence.
+	Unique bool
+}
+
+// Validate reports options that can never render.
+func (o ListOptions) Validate() error {
+	if o.MaxItems < 0 {
+		return fmt.Errorf("maxItems must not be negative, got %d", o.MaxItems)
+	}
+	return nil
+}
+
+// reshapes reports whether the options change how a template's list output
+// is rendered.
+func (o ListOptions) reshapes() bool {
+	return o.Separator != "" || o.MaxItems > 0 || o.Unique
+}
+
+// missing returns the placeholder for a JSONPath column with no results.
+func (o ListOptions) missing() string {
+	if o.Default != "" {
+		return o.Default
+	}
+	return defaultMissingValue
+}
+
+// join applies Unique and MaxItems to items and joins them with Separator.
+func (o ListOptions) join(items []string) string {
+	if o.Unique {
+		seen := make(map[string]bool, len(items))
+		kept := items[:0:0]
+		for _, item := range items {
+			if !seen[item] {
+				seen[item] = true
+				kept = append(kept, item)
+			}
+		}
+		items = kept
+	}
+	if o.MaxItems > 0 && len(items) > o.MaxItems {
+		more := len(items) - o.MaxItems
+		items = append(items[:o.MaxItems:o.MaxItems], fmt.Sprintf("+%d more", more))
+	}
+	sep := o.Separator
+	if sep == "" {
+		sep = defaultSeparator
+	}
+	return strings.Join(items, sep)
+}
+
+// data returns the structured value of a JSONPath column: nil for no
+// results, the value itself for one, and the list (deduplicated if Unique
+// is set, never truncated) for several.
+func (o ListOptions) data(items []interface{}) interface{} {
+	switch len(items) {
+	case 0:
+		return nil
+	case 1:
+		return items[0]
+	}
+	if !o.Unique {
+		return items
+	}
+	seen := make(map[string]bool, len(items))
+	kept := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		key := fmt.Sprint(item)
+		if !seen[key] {
+			seen[key] = true
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// templateList applies the options to a template's output. Output that
+// isn't a list is returned unchanged, except that empty output renders as
+// Default.
+func (o ListOptions) templateList(out string) string {
+	trimmed := strings.TrimSpace(out)
+	if trimmed == "" {
+		if o.Default != "" {
+			return o.Default
+		}
+		return out
+	}
+	if !o.reshapes() {
+		return out
+	}
+	if strings.HasPrefix(trimmed, "[") {
+		var values []interface{}
+		if err := json.Unmarshal([]byte(trimmed), &values); err == nil {
+			if len(values) == 0 && o.Default != "" {
+				return o.Default
+			}
+			items := make([]string, len(values))
+			for i, v := range values {
+				items[i] = jsonItem(v)
+			}
+			return o.join(items)
+		}
+	}
+	if strings.Contains(trimmed, "\n") {
+		return o.join(strings.Split(trimmed, "\n"))
+	}
+	return out
+}
+
+// jsonItem renders one element of a JSON array: strings as is, anything
+// else as compact JSON.
+func jsonItem(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
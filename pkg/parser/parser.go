@@ -1,17 +1,19 @@
 package parser
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
 	"text/template"
+	"text/template/parse"
 	"time"
 
 	"github.com/Masterminds/sprig/v3"
 	"github.com/kubectl-cwide/pkg/parser/funcs"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/duration"
 	"k8s.io/cli-runtime/pkg/printers"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/util/jsonpath"
@@ -21,13 +23,49 @@ type Parser interface {
 	Parse(obj runtime.Object, defaultTable *metav1.Table) (string, error)
 }
 
+// CellParser is implemented by parsers that can also report the typed value
+// behind the rendered text, for sorting and filtering.
+type CellParser interface {
+	ParseCell(obj runtime.Object, defaultTable *metav1.Table) (string, CellValue, error)
+}
+
 type FieldParser struct {
 	*jsonpath.JSONPath
 	*template.Template
+	// IsAGE is equivalent to Format "age"; Format takes precedence.
 	IsAGE                 bool
 	IsDefaultPrinterField bool
-	Header                string
-	Config                *rest.Config
+	// Format, when set, renders the cell as a timestamp or duration; see
+	// funcs.TimeFormats.
+	Format string
+	// List controls missing values and how multiple values are joined.
+	List ListOptions
+	// Type, when set, is the declared type of the cell in structured
+	// output; see ColumnTypes.
+	Type   string
+	Header string
+	Config *rest.Config
+	// Scope, when set, provides the context template functions run under.
+	// Each template cell executes under a child context bounded by Timeout.
+	Scope *Scope
+	// Timeout bounds a single template cell that calls network-touching
+	// functions. Zero means no per-cell limit beyond the Scope's base
+	// context.
+	Timeout time.Duration
+
+	// network records whether Template may call a network-touching
+	// function; see callsNetwork.
+	network *bool
+	// cell is the clone of Template that network-touching cells run,
+	// reused across rows until a cell is abandoned; see executeWithBudget.
+	cell *cellTemplate
+}
+
+// cellTemplate is a clone of a column template whose network-touching
+// functions run under ctx, the context of the cell it is executing.
+type cellTemplate struct {
+	*template.Template
+	ctx context.Context
 }
 
 func NewFieldParser() *FieldParser {
@@ -35,20 +73,53 @@ func NewFieldParser() *FieldParser {
 }
 
 func (p *FieldParser) Parse(obj runtime.Object, defaultTable *metav1.Table) (string, error) {
+	result, _, err := p.ParseCell(obj, defaultTable)
+	return result, err
+}
+
+// ParseCell renders the cell and returns the typed value behind it: the
+// timestamp or duration a formatted column was rendered from, and the
+// structured value used by structured output formats.
+func (p *FieldParser) ParseCell(obj runtime.Object, defaultTable *metav1.Table) (string, CellValue, error) {
+	result, data, err := p.parseRaw(obj, defaultTable)
+	if err != nil {
+		return result, CellValue{}, err
+	}
+	var value CellValue
+	format := p.Format
+	if format == "" && p.IsAGE {
+		format = funcs.TimeFormatAge
+	}
+	if format != "" && !p.IsDefaultPrinterField {
+		result, value = formatCell(result, format, p.Scope.Now())
+		data = result
+	}
+	value.Data = data
+	if p.Type != "" {
+		value.Data = coerce(data, result, value, p.Type)
+	}
+	return result, value, nil
+}
+
+// parseRaw renders the cell before formatting, returning the text and the
+// structured value behind it.
+func (p *FieldParser) parseRaw(obj runtime.Object, defaultTable *metav1.Table) (string, interface{}, error) {
 	var result string
+	var data interface{}
 	// DefaultPrinterResult is used to get the default printer result
 	if p.IsDefaultPrinterField {
 		if defaultTable != nil {
 			for idx, column := range defaultTable.ColumnDefinitions {
 				if strings.ToLower(column.Name) == strings.ToLower(p.Header) {
 					if len(defaultTable.Rows) > 0 {
-						result = fmt.Sprint(defaultTable.Rows[0].Cells[idx])
+						data = defaultTable.Rows[0].Cells[idx]
+						result = fmt.Sprint(data)
 						break
 					}
 				}
 			}
 		}
-		return result, nil
+		return result, data, nil
 	}
 	if p.JSONPath != nil {
 		jpParser := p.JSONPath
@@ -61,20 +132,24 @@ func (p *FieldParser) Parse(obj runtime.Object, defaultTable *metav1.Table) (str
 		}
 
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		valueStrings := []string{}
-		if len(values) == 0 || len(values[0]) == 0 {
-			valueStrings = append(valueStrings, "<none>")
-		}
-
+		var items []interface{}
 		for arrIx := range values {
 			for valIx := range values[arrIx] {
-				valueStrings = append(valueStrings, printers.EscapeTerminal(fmt.Sprint(values[arrIx][valIx].Interface())))
+				item := values[arrIx][valIx].Interface()
+				items = append(items, item)
+				valueStrings = append(valueStrings, printers.EscapeTerminal(fmt.Sprint(item)))
 			}
 		}
+		data = p.List.data(items)
 
-		result = strings.Join(valueStrings, ",")
+		if len(valueStrings) == 0 {
+			result = p.List.missing()
+		} else {
+			result = p.List.join(valueStrings)
+		}
 	}
 
 	if p.Template != nil {
@@ -83,23 +158,106 @@ func (p *FieldParser) Parse(obj runtime.Object, defaultTable *metav1.Table) (str
 
 		unstructured, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
 		if err != nil {
-			return "", err
+			return "", nil, err
+		}
+		if p.Scope == nil && p.Timeout <= 0 {
+			if err := tParser.Execute(&buf, unstructured); err != nil {
+				return "", nil, err
+			}
+			result = buf.String()
+		} else {
+			result, err = p.executeWithBudget(tParser, unstructured)
+			if err != nil {
+				return "", nil, err
+			}
 		}
-		if err := tParser.Execute(&buf, unstructured); err != nil {
+		data = inferTemplateData(result)
+		result = p.List.templateList(result)
+	}
+
+	return result, data, nil
+}
+
+// executeWithBudget runs the template under a per-cell context. If the
+// context ends first the cell is abandoned: ErrCellTimeout is returned for
+// an expired budget, the context error (e.g. Ctrl-C) otherwise.
+//
+// Only templates that call network-touching functions can block, so only
+// those run in a goroutine the cell can abandon; the rest run in place.
+// They run a clone of the template whose network-touching functions are
+// bound to the cell's context. The clone is made once per parser and
+// reused by the following rows, until a cell is abandoned: the abandoned
+// execution keeps its clone, so its later network calls observe its own
+// cancelled context rather than that of whatever cell runs next.
+func (p *FieldParser) executeWithBudget(t *template.Template, data interface{}) (string, error) {
+	ctx, cancel := p.Scope.cellContext(p.Timeout)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return "", budgetError(err)
+	}
+	if !p.callsNetwork(t) {
+		var buf strings.Builder
+		err := t.Execute(&buf, data)
+		return buf.String(), err
+	}
+	if p.cell == nil {
+		cell := &cellTemplate{}
+		clone, err := t.Clone()
+		if err != nil {
 			return "", err
 		}
+		clone.Funcs(p.Scope.cellFuncs(func() context.Context { return cell.ctx }, p.Config))
+		cell.Template = clone
+		p.cell = cell
+	}
+	cell := p.cell
+	cell.ctx = ctx
+
+	type execResult struct {
+		out string
+		err error
+	}
+	done := make(chan execResult, 1)
+	go func() {
+		var buf strings.Builder
+		err := cell.Execute(&buf, data)
+		done <- execResult{out: buf.String(), err: err}
+	}()
 
-		result = buf.String()
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-ctx.Done():
+		p.cell = nil
+		return "", budgetError(ctx.Err())
 	}
+}
 
-	if p.IsAGE {
-		return age(result), nil
+// callsNetwork reports whether t may call a network-touching function,
+// directly or through the templates and custom funcs it invokes.
+func (p *FieldParser) callsNetwork(t *template.Template) bool {
+	if p.network == nil {
+		network := templateCallsNetwork(t)
+		p.network = &network
 	}
+	return *p.network
+}
 
-	return result, nil
+// budgetError maps an expired deadline to ErrCellTimeout and passes any
+// other context error (cancellation) through unchanged.
+func budgetError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrCellTimeout
+	}
+	return err
 }
 
-func GetFuncMap(cfg *rest.Config) template.FuncMap {
+// GetFuncMap returns the template FuncMap used for column templates. The
+// network-touching functions (lookup, lookupByLabel, probeCheck,
+// serviceCheck) run under scope's base context, or, in a cell run by a
+// FieldParser with that scope, under the cell's context; a nil scope means
+// context.Background(). age and now read the scope's clock.
+func GetFuncMap(scope *Scope, cfg *rest.Config) template.FuncMap {
 	m := make(template.FuncMap, len(funcs.DefaultMap))
 	for k, v := range funcs.DefaultMap {
 		m[k] = v
@@ -109,23 +267,110 @@ func GetFuncMap(cfg *rest.Config) template.FuncMap {
 		m[k] = v
 	}
 
-	m["lookup"] = funcs.NewLookupFunction(cfg)
-	m["lookupByLabel"] = funcs.NewLookupByLabelFunction(cfg)
-	m["probeCheck"] = funcs.NewProbeCheckFunction(cfg)
+	m["age"] = funcs.NewAgeFunction(scope.Now)
+	m["now"] = scope.Now
+	network := networkFuncs(scope.probeChecker(cfg), cfg)
+	for k, v := range network(scope.Context) {
+		m[k] = v
+	}
+	scope.setNetwork(network)
 
 	return m
 }
 
-func IsTemplate(template string) bool {
-	return strings.HasPrefix(template, "{{") && strings.HasSuffix(template, "}}")
+// networkFuncs returns a builder of the network-touching template
+// functions, run under the context ctx returns and sharing probes.
+func networkFuncs(probes *funcs.ProbeChecker, cfg *rest.Config) func(ctx funcs.ContextFunc) template.FuncMap {
+	return func(ctx funcs.ContextFunc) template.FuncMap {
+		return template.FuncMap{
+			"lookup":        funcs.NewLookupFunction(ctx, cfg),
+			"lookupByLabel": funcs.NewLookupByLabelFunction(ctx, cfg),
+			"probeCheck": func(obj interface{}, probeType string, container ...string) string {
+				return probes.CheckContext(ctx(), obj, probeType, container...)
+			},
+			"serviceCheck": func(obj interface{}, args ...string) string {
+				return probes.ServiceCheckContext(ctx(), obj, args...)
+			},
+		}
+	}
 }
 
-// age calculates the age of a resource based on its creation time in RFC3339.
-func age(creationTime string) string {
-	t, err := time.Parse(time.RFC3339, creationTime)
-	if err != nil {
-		return ""
+// networkFuncNames are the functions that make a template cell block on
+// the network: those built by networkFuncs, and include and tpl, which run
+// templates chosen at execution time.
+var networkFuncNames = map[string]bool{
+	"lookup":        true,
+	"lookupByLabel": true,
+	"probeCheck":    true,
+	"serviceCheck":  true,
+	"include":       true,
+	"tpl":           true,
+}
+
+// customFuncPrefix names the template behind each custom func of a YAML
+// template, which calling the func executes.
+const customFuncPrefix = "__func_"
+
+// templateCallsNetwork reports whether t, or a template or custom func of
+// its set that it invokes, calls one of networkFuncNames.
+func templateCallsNetwork(t *template.Template) bool {
+	seen := map[string]bool{}
+	var visitTemplate func(name string) bool
+	var visit func(node parse.Node) bool
+	visitTemplate = func(name string) bool {
+		if seen[name] {
+			return false
+		}
+		seen[name] = true
+		tmpl := t.Lookup(name)
+		return tmpl != nil && tmpl.Tree != nil && visit(tmpl.Tree.Root)
+	}
+	visit = func(node parse.Node) bool {
+		switch n := node.(type) {
+		case *parse.IdentifierNode:
+			return networkFuncNames[n.Ident] || visitTemplate(customFuncPrefix+n.Ident)
+		case *parse.TemplateNode:
+			return visitTemplate(n.Name) || visit(n.Pipe)
+		case *parse.ListNode:
+			if n == nil {
+				return false
+			}
+			for _, child := range n.Nodes {
+				if visit(child) {
+					return true
+				}
+			}
+		case *parse.ActionNode:
+			return visit(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return false
+			}
+			for _, cmd := range n.Cmds {
+				if visit(cmd) {
+					return true
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				if visit(arg) {
+					return true
+				}
+			}
+		case *parse.ChainNode:
+			return visit(n.Node)
+		case *parse.IfNode:
+			return visit(n.Pipe) || visit(n.List) || visit(n.ElseList)
+		case *parse.RangeNode:
+			return visit(n.Pipe) || visit(n.List) || visit(n.ElseList)
+		case *parse.WithNode:
+			return visit(n.Pipe) || visit(n.List) || visit(n.ElseList)
+		}
+		return false
 	}
+	return visitTemplate(t.Name())
+}
 
-	return duration.HumanDuration(time.Since(t))
+func IsTemplate(template string) bool {
+	return strings.HasPrefix(template, "{{") && strings.HasSuffix(template, "}}")
 }
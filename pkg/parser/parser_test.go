@@ -0,0 +1,199 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	cwidefuncs "github.com/kubectl-cwide/pkg/parser/funcs"
+)
+
+func TestParseCellTimeout(t *testing.T) {
+	scope := NewScope(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	stubs := template.FuncMap{
+		// This lookup ignores its context entirely, like a call against
+		// an unreachable aggregated API with no client timeout.
+		"lookup": func() string {
+			<-release
+			return "late"
+		},
+	}
+	scope.Override(stubs)
+	tmpl := template.Must(template.New("cell").Funcs(stubs).Parse(`{{ lookup }}`))
+
+	p := NewFieldParser()
+	p.Template = tmpl
+	p.Scope = scope
+	p.Timeout = 20 * time.Millisecond
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"kind": "Pod"}}
+	start := time.Now()
+	_, err := p.Parse(obj, nil)
+	if !errors.Is(err, ErrCellTimeout) {
+		t.Fatalf("want ErrCellTimeout, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("cell was not abandoned promptly")
+	}
+}
+
+func TestParseCancelledScope(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scope := NewScope(ctx)
+
+	p := NewFieldParser()
+	p.Template = template.Must(template.New("cell").Funcs(template.FuncMap{
+		"hang": func() string { return "ran" },
+	}).Parse(`{{ hang }}`))
+	p.Scope = scope
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"kind": "Pod"}}
+	if _, err := p.Parse(obj, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+}
+
+func TestTimedOutCellKeepsItsContext(t *testing.T) {
+	scope := NewScope(context.Background())
+	funcs := GetFuncMap(scope, nil)
+	seen := make(chan error, 1)
+	scope.setNetwork(func(ctx cwidefuncs.ContextFunc) template.FuncMap {
+		return template.FuncMap{
+			"lookup": func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+				seen <- ctx().Err()
+				return map[string]interface{}{}, ctx().Err()
+			},
+		}
+	})
+	release, busy := make(chan struct{}), make(chan struct{})
+	funcs["hang"] = func() string {
+		<-release
+		return ""
+	}
+	funcs["busy"] = func() string {
+		<-busy
+		return ""
+	}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"kind": "Pod"}}
+
+	// The first cell times out, then, abandoned, calls lookup.
+	timedOut := NewFieldParser()
+	timedOut.Template = template.Must(template.New("cell").Funcs(funcs).Parse(`{{ hang }}{{ lookup "v1" "ConfigMap" "" "x" }}`))
+	timedOut.Scope = scope
+	timedOut.Timeout = 20 * time.Millisecond
+	if _, err := timedOut.Parse(obj, nil); !errors.Is(err, ErrCellTimeout) {
+		t.Fatalf("want ErrCellTimeout, got %v", err)
+	}
+
+	// A later cell, with plenty of budget left, is rendering when it does.
+	next := NewFieldParser()
+	next.Template = template.Must(template.New("cell").Funcs(funcs).Parse(`{{ busy }}`))
+	next.Scope = scope
+	next.Timeout = time.Minute
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = next.Parse(obj, nil)
+	}()
+	defer func() {
+		close(busy)
+		<-done
+	}()
+
+	close(release)
+	select {
+	case err := <-seen:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("lookup of the timed-out cell ran under a live context (err %v)", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("lookup of the timed-out cell was not called")
+	}
+}
+
+func TestTemplateCallsNetwork(t *testing.T) {
+	stub := func(...interface{}) string { return "" }
+	funcs := template.FuncMap{"lookup": stub, "probeCheck": stub, "upper": stub, "helper": stub}
+	cases := []struct {
+		text string
+		want bool
+	}{
+		{`{{ .metadata.name }}`, false},
+		{`{{ .metadata.name | upper }}`, false},
+		{`{{ lookup "v1" "Secret" "" "x" }}`, true},
+		{`{{ if .spec }}{{ range .spec.containers }}{{ probeCheck $ "readiness" .name }}{{ end }}{{ end }}`, true},
+		{`{{ with .spec }}{{ . }}{{ else }}{{ (lookup).data }}{{ end }}`, true},
+		{`{{ template "plain" . }}`, false},
+		{`{{ template "probes" . }}`, true},
+		{`{{ helper . }}`, true},
+	}
+	for _, tc := range cases {
+		set := template.New("set").Funcs(funcs)
+		template.Must(set.New("plain").Parse(`{{ .metadata.name }}`))
+		template.Must(set.New("probes").Parse(`{{ probeCheck . "liveness" }}`))
+		template.Must(set.New(customFuncPrefix + "helper").Parse(`{{ template "probes" . }}`))
+		cell := template.Must(set.New("cell").Parse(tc.text))
+		if got := templateCallsNetwork(cell); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestNetworkCellsReuseClone(t *testing.T) {
+	scope := NewScope(context.Background())
+	funcs := GetFuncMap(scope, nil)
+	var contexts []context.Context
+	scope.setNetwork(func(ctx cwidefuncs.ContextFunc) template.FuncMap {
+		return template.FuncMap{
+			"lookup": func() string {
+				contexts = append(contexts, ctx())
+				return "ok"
+			},
+		}
+	})
+	p := NewFieldParser()
+	p.Template = template.Must(template.New("cell").Funcs(funcs).Parse(`{{ lookup }}`))
+	p.Scope = scope
+	p.Timeout = time.Minute
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"kind": "Pod"}}
+
+	var first *cellTemplate
+	for i := 0; i < 3; i++ {
+		if got, err := p.Parse(obj, nil); err != nil || got != "ok" {
+			t.Fatalf("row %d: got %q, %v", i, got, err)
+		}
+		if first == nil {
+			first = p.cell
+		} else if p.cell != first {
+			t.Fatalf("row %d cloned the template again", i)
+		}
+	}
+	// Each row's lookup ran under that row's context, ended with the row.
+	for i, ctx := range contexts {
+		if ctx.Err() == nil {
+			t.Errorf("row %d: lookup ran under a context that outlived its cell", i)
+		}
+	}
+}
+
+func TestFormatCellIntegers(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, format := range []string{"age", "relative", "rfc3339"} {
+		if got, v := formatCell("3", format, now); got != "3" || v.Kind != ValueText {
+			t.Errorf("format %s of a replica count = %q (%v), want it unchanged", format, got, v.Kind)
+		}
+	}
+	if got, _ := formatCell("1704067200", "unix", now); got != "1704067200" {
+		t.Errorf("format unix = %q", got)
+	}
+	if got, v := formatCell("1704067200", "unix", now); v.Kind != ValueTime || !v.Time.Equal(now) {
+		t.Errorf("format unix value = %v (%q)", v, got)
+	}
+}
@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"text/template"
+	"time"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/kubectl-cwide/pkg/parser/funcs"
+)
+
+// ErrCellTimeout is returned by FieldParser.Parse when a template cell does
+// not finish within its budget.
+var ErrCellTimeout = errors.New("cell rendering exceeded its time budget")
+
+// Scope supplies the context that network-touching template functions
+// (lookup, lookupByLabel, probeCheck, serviceCheck) run under, and owns the
+// probe checker those functions share. The base context is the
+// command's context, optionally bounded by --render-timeout; each template
+// cell runs under a child context bounded by --cell-timeout, so a hung call
+// is cancelled together with its cell instead of stalling the table.
+//
+// A nil *Scope is valid and behaves like context.Background().
+type Scope struct {
+	mu     sync.RWMutex
+	base   context.Context
+	probes *funcs.ProbeChecker
+	now    func() time.Time
+	// network builds the network-touching functions of the FuncMap built
+	// from this scope, bound to one context; see cellFuncs.
+	network func(ctx funcs.ContextFunc) template.FuncMap
+	// overridden are functions replaced after the FuncMap was built, which
+	// cells keep as given; see Override.
+	overridden map[string]bool
+}
+
+// NewScope returns a Scope rooted at ctx.
+func NewScope(ctx context.Context) *Scope {
+	return &Scope{base: ctx}
+}
+
+// SetBase replaces the base context, e.g. once cmd.Context() is known.
+func (s *Scope) SetBase(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.base = ctx
+}
+
+// SetClock replaces the clock ages and `now` are computed from, e.g. with
+// a frozen one in template tests.
+func (s *Scope) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// Now returns the current time of the scope's clock; time.Now() unless
+// SetClock was called.
+func (s *Scope) Now() time.Time {
+	if s == nil {
+		return time.Now()
+	}
+	s.mu.RLock()
+	now := s.now
+	s.mu.RUnlock()
+	if now == nil {
+		return time.Now()
+	}
+	return now()
+}
+
+// Context returns the base context. Template cells run under a context of
+// their own; see cellFuncs.
+func (s *Scope) Context() context.Context {
+	if s == nil {
+		return context.Background()
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.base != nil {
+		return s.base
+	}
+	return context.Background()
+}
+
+// Probes returns the checker behind probeCheck and serviceCheck in the
+// FuncMap built from this scope, or nil if GetFuncMap hasn't been called.
+// Callers use it to configure exec probes and concurrency, and to prefetch.
+func (s *Scope) Probes() *funcs.ProbeChecker {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.probes
+}
+
+// probeChecker returns the scope's checker, creating it on first use so
+// that every FuncMap built from one scope shares a single result cache.
+func (s *Scope) probeChecker(cfg *rest.Config) *funcs.ProbeChecker {
+	if s == nil {
+		return funcs.NewProbeChecker(s.Context, cfg)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.probes == nil {
+		s.probes = funcs.NewProbeChecker(s.Context, cfg)
+	}
+	return s.probes
+}
+
+// setNetwork records how to build the network-touching functions of the
+// FuncMap built from this scope.
+func (s *Scope) setNetwork(network func(ctx funcs.ContextFunc) template.FuncMap) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.network = network
+}
+
+// Override records that fns replace functions of the FuncMap built from
+// this scope, e.g. lookup with a stub in template tests: cells keep them
+// as given instead of binding their own.
+func (s *Scope) Override(fns template.FuncMap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.overridden == nil {
+		s.overridden = map[string]bool{}
+	}
+	for name := range fns {
+		s.overridden[name] = true
+	}
+}
+
+// cellFuncs returns the network-touching template functions run under the
+// context ctx returns, that of the cell being executed, sharing the
+// scope's probe checker.
+func (s *Scope) cellFuncs(ctx funcs.ContextFunc, cfg *rest.Config) template.FuncMap {
+	var network func(ctx funcs.ContextFunc) template.FuncMap
+	var overridden map[string]bool
+	if s != nil {
+		s.mu.RLock()
+		network, overridden = s.network, s.overridden
+		s.mu.RUnlock()
+	}
+	if network == nil {
+		network = networkFuncs(funcs.NewProbeChecker(nil, cfg), cfg)
+	}
+	m := network(ctx)
+	for name := range overridden {
+		delete(m, name)
+	}
+	return m
+}
+
+// cellContext derives the context of one cell execution from the base
+// context. A zero timeout means the cell is only bounded by the base
+// context.
+func (s *Scope) cellContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	base := s.Context()
+	if timeout > 0 {
+		return context.WithTimeout(base, timeout)
+	}
+	return context.WithCancel(base)
+}
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubectl-cwide/pkg/parser/funcs"
+)
+
+// Types accepted by the column `type:` option. They decide how a cell is
+// encoded in structured output (template-json, template-yaml); the table is
+// unaffected. The names follow CRD additionalPrinterColumns, plus array and
+// object.
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeDate    = "date"
+	TypeArray   = "array"
+	TypeObject  = "object"
+)
+
+// ColumnTypes lists every value accepted by the column `type:` option.
+var ColumnTypes = []string{TypeString, TypeInteger, TypeNumber, TypeBoolean, TypeDate, TypeArray, TypeObject}
+
+// ValidateType returns an error if typ is not a known column type. The
+// empty string (infer the type) is valid.
+func ValidateType(typ string) error {
+	if typ == "" {
+		return nil
+	}
+	for _, t := range ColumnTypes {
+		if t == typ {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown type %q (expected one of %s)", typ, strings.Join(ColumnTypes, ", "))
+}
+
+// inferTemplateData recovers a typed value from template output: JSON
+// numbers, booleans, arrays and objects (e.g. from `toJson`) are decoded,
+// anything else stays a string.
+func inferTemplateData(out string) interface{} {
+	trimmed := strings.TrimSpace(out)
+	switch {
+	case trimmed == "true":
+		return true
+	case trimmed == "false":
+		return false
+	case strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{"):
+		var v interface{}
+		if err := json.Unmarshal([]byte(trimmed), &v); err == nil {
+			return v
+		}
+	default:
+		if n, ok := parseNumber(trimmed); ok {
+			return n
+		}
+	}
+	return out
+}
+
+// parseNumber parses s as an integer if it is one, else as a float. It
+// rejects forms JSON would reject, like "0x10" or "1_000".
+func parseNumber(s string) (interface{}, bool) {
+	if s == "" || !json.Valid([]byte(s)) {
+		return nil, false
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, true
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, true
+	}
+	return nil, false
+}
+
+// coerce converts a cell's structured value to the declared column type.
+// Values that can't be converted fall back to the rendered text, so that
+// nothing is silently dropped.
+func coerce(data interface{}, text string, value CellValue, typ string) interface{} {
+	switch typ {
+	case TypeString:
+		return text
+	case TypeInteger, TypeNumber:
+		var n interface{}
+		var ok bool
+		switch v := data.(type) {
+		case int64, int32, int, float64:
+			n, ok = v, true
+		default:
+			n, ok = parseNumber(strings.TrimSpace(text))
+		}
+		if !ok {
+			return text
+		}
+		if typ == TypeInteger {
+			if f, isFloat := n.(float64); isFloat {
+				if f != float64(int64(f)) {
+					return text
+				}
+				return int64(f)
+			}
+		}
+		return n
+	case TypeBoolean:
+		if b, ok := data.(bool); ok {
+			return b
+		}
+		if b, err := strconv.ParseBool(strings.TrimSpace(text)); err == nil {
+			return b
+		}
+		return text
+	case TypeDate:
+		if value.Kind == ValueTime || value.Kind == ValueAge {
+			return value.Time.UTC().Format(time.RFC3339)
+		}
+		if s, ok := data.(string); ok {
+			if t, ok := funcs.ParseTimestamp(s); ok {
+				return t.UTC().Format(time.RFC3339)
+			}
+		}
+		return text
+	case TypeArray:
+		switch v := data.(type) {
+		case []interface{}:
+			return v
+		case nil:
+			return []interface{}{}
+		case string:
+			if parsed, ok := inferTemplateData(v).([]interface{}); ok {
+				return parsed
+			}
+		}
+		return []interface{}{data}
+	case TypeObject:
+		if m, ok := data.(map[string]interface{}); ok {
+			return m
+		}
+		if s, ok := data.(string); ok {
+			if parsed, ok := inferTemplateData(s).(map[string]interface{}); ok {
+				return parsed
+			}
+		}
+		return text
+	}
+	return data
+}
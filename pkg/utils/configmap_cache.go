@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+
+	"github.com/kubectl-cwide/pkg/common"
+)
+
+// Template sources in config.yaml's templateSources.
+const (
+	SourceLocal     = "local"
+	SourceConfigMap = "configmap"
+)
+
+// LayerConfigMap names the layers read from ConfigMaps.
+const LayerConfigMap = "configmap"
+
+// ConfigMapSource is a ConfigMap of templates, stored as push writes them:
+// one "<resource-dir>..<template-name>" key per template.
+type ConfigMapSource struct {
+	Namespace string
+	Name      string
+}
+
+func (s ConfigMapSource) String() string {
+	return s.Namespace + "/" + s.Name
+}
+
+// ParseTemplateSource parses a templateSources entry: "local", "configmap"
+// for the default ConfigMap, or "configmap:<namespace>/<name>".
+func ParseTemplateSource(entry string) (local bool, cm ConfigMapSource, err error) {
+	if entry == SourceLocal {
+		return true, ConfigMapSource{}, nil
+	}
+	if entry == SourceConfigMap {
+		return false, ConfigMapSource{Namespace: common.DefaultConfigMapNamespace, Name: common.DefaultConfigMapName}, nil
+	}
+	ref, ok := strings.CutPrefix(entry, SourceConfigMap+":")
+	if !ok {
+		return false, ConfigMapSource{}, fmt.Errorf("unknown template source %q (want local, configmap or configmap:<namespace>/<name>)", entry)
+	}
+	ns, name, ok := strings.Cut(ref, "/")
+	if !ok || ns == "" || name == "" {
+		return false, ConfigMapSource{}, fmt.Errorf("template source %q must name the ConfigMap as configmap:<namespace>/<name>", entry)
+	}
+	return false, ConfigMapSource{Namespace: ns, Name: name}, nil
+}
+
+// ConfigMapReader reads template ConfigMaps.
+type ConfigMapReader interface {
+	// ResourceVersion returns the resourceVersion of the ConfigMap without
+	// reading its data.
+	ResourceVersion(ctx context.Context, src ConfigMapSource) (string, error)
+	// Data returns the resourceVersion and data of the ConfigMap.
+	Data(ctx context.Context, src ConfigMapSource) (string, map[string]string, error)
+}
+
+type clusterConfigMapReader struct {
+	meta   metadata.Interface
+	client kubernetes.Interface
+}
+
+// NewConfigMapReader returns a ConfigMapReader for the cluster of config.
+func NewConfigMapReader(config *rest.Config) (ConfigMapReader, error) {
+	meta, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &clusterConfigMapReader{meta: meta, client: client}, nil
+}
+
+func (r *clusterConfigMapReader) ResourceVersion(ctx context.Context, src ConfigMapSource) (string, error) {
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	m, err := r.meta.Resource(gvr).Namespace(src.Namespace).Get(ctx, src.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return m.ResourceVersion, nil
+}
+
+func (r *clusterConfigMapReader) Data(ctx context.Context, src ConfigMapSource) (string, map[string]string, error) {
+	cm, err := r.client.CoreV1().ConfigMaps(src.Namespace).Get(ctx, src.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	return cm.ResourceVersion, cm.Data, nil
+}
+
+// ConfigMapCacheDir returns where the templates of src on the cluster at
+// host are cached: ~/.kubectl-cwide/cache/configmaps/<host>/<namespace>/<name>.
+func ConfigMapCacheDir(host string, src ConfigMapSource) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	host = strings.NewReplacer(":", "_", "/", "_").Replace(host)
+	return filepath.Join(home, common.CachePath, "configmaps", host, src.Namespace, src.Name), nil
+}
+
+// configMapVersionFile holds the resourceVersion a cache was written from.
+const configMapVersionFile = ".resourceVersion"
+
+// CachedConfigMapLayer returns the layer of the templates of ConfigMap src,
+// cached as a template root in dir. The cache is rewritten when the
+// ConfigMap's resourceVersion differs from the cached one. If the ConfigMap
+// cannot be read, an existing cache is used as is and the error returned
+// alongside it; ok is false when there is nothing to use.
+func CachedConfigMapLayer(ctx context.Context, r ConfigMapReader, dir string, src ConfigMapSource) (layer TemplateLayer, ok bool, err error) {
+	layer = DirLayer(LayerConfigMap, dir)
+	cached, cacheErr := os.ReadFile(filepath.Join(dir, configMapVersionFile))
+	rv, err := r.ResourceVersion(ctx, src)
+	if err != nil {
+		return layer, cacheErr == nil, fmt.Errorf("failed to read ConfigMap %s: %w", src, err)
+	}
+	if cacheErr == nil && string(cached) == rv {
+		return layer, true, nil
+	}
+	rv, data, err := r.Data(ctx, src)
+	if err != nil {
+		return layer, cacheErr == nil, fmt.Errorf("failed to read ConfigMap %s: %w", src, err)
+	}
+	if err := writeConfigMapCache(dir, rv, data); err != nil {
+		return layer, cacheErr == nil, fmt.Errorf("failed to cache ConfigMap %s: %w", src, err)
+	}
+	return layer, true, nil
+}
+
+// writeConfigMapCache replaces the cache in dir with the templates in data.
+// The new cache is written next to dir and renamed into place, so readers
+// never see a partial one.
+func writeConfigMapCache(dir, rv string, data map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	for key, value := range data {
+		resourceDir, name, ok := strings.Cut(key, "..")
+		if !ok || resourceDir == "" || name == "" || strings.ContainsAny(key, `/\`) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(tmp, resourceDir), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(tmp, resourceDir, name+".yaml"), []byte(value), 0644); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(filepath.Join(tmp, configMapVersionFile), []byte(rv), 0644); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
+
+// ConfigMapCacheLayers returns the cached templates of ConfigMap sources on
+// the cluster at host, without contacting it. Sources never cached are left
+// out.
+func ConfigMapCacheLayers(host string) ConfigMapLayerFunc {
+	return func(src ConfigMapSource) (TemplateLayer, bool) {
+		dir, err := ConfigMapCacheDir(host, src)
+		if err != nil || !CheckFileExists(filepath.Join(dir, configMapVersionFile)) {
+			return TemplateLayer{}, false
+		}
+		return DirLayer(LayerConfigMap, dir), true
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+type fakeConfigMapReader struct {
+	rv    string
+	data  map[string]string
+	err   error
+	reads int
+}
+
+func (f *fakeConfigMapReader) ResourceVersion(context.Context, ConfigMapSource) (string, error) {
+	return f.rv, f.err
+}
+
+func (f *fakeConfigMapReader) Data(context.Context, ConfigMapSource) (string, map[string]string, error) {
+	f.reads++
+	return f.rv, f.data, f.err
+}
+
+func TestParseTemplateSource(t *testing.T) {
+	if local, _, err := ParseTemplateSource("local"); !local || err != nil {
+		t.Errorf("local: got (%v, %v)", local, err)
+	}
+	if _, cm, err := ParseTemplateSource("configmap"); err != nil || cm.String() != "kube-system/cwide-templates" {
+		t.Errorf("configmap: got (%v, %v)", cm, err)
+	}
+	if _, cm, err := ParseTemplateSource("configmap:platform/team-templates"); err != nil || cm.String() != "platform/team-templates" {
+		t.Errorf("configmap:ns/name: got (%v, %v)", cm, err)
+	}
+	for _, bad := range []string{"remote", "configmap:", "configmap:only-name"} {
+		if _, _, err := ParseTemplateSource(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestCachedConfigMapLayer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "kube-system", "cwide-templates")
+	src := ConfigMapSource{Namespace: "kube-system", Name: "cwide-templates"}
+	r := &fakeConfigMapReader{rv: "1", data: map[string]string{
+		"pod--v1..team":  "columns: []\n",
+		"__aliases__":    "pd: pods\n",
+		"bad/key..x":     "columns: []\n",
+		"pod--v1..other": "columns: []\n",
+	}}
+
+	layer, ok, err := CachedConfigMapLayer(context.Background(), r, dir, src)
+	if err != nil || !ok {
+		t.Fatalf("first read: got (%v, %v)", ok, err)
+	}
+	path := TemplateSearchPath{layer}
+	if f, ok := path.Find("pod--v1", "team"); !ok || f.Layer.Name != LayerConfigMap {
+		t.Fatalf("team template not cached: %+v", f)
+	}
+
+	// Unchanged resourceVersion: the cache is used without reading the data.
+	if _, _, err := CachedConfigMapLayer(context.Background(), r, dir, src); err != nil || r.reads != 1 {
+		t.Fatalf("unchanged: got %d reads, err %v", r.reads, err)
+	}
+
+	// A new resourceVersion replaces the cache.
+	r.rv, r.data = "2", map[string]string{"pod--v1..renamed": "columns: []\n"}
+	if _, _, err := CachedConfigMapLayer(context.Background(), r, dir, src); err != nil || r.reads != 2 {
+		t.Fatalf("changed: got %d reads, err %v", r.reads, err)
+	}
+	if _, ok := path.Find("pod--v1", "team"); ok {
+		t.Error("stale template survived a refresh")
+	}
+	if _, ok := path.Find("pod--v1", "renamed"); !ok {
+		t.Error("new template missing after a refresh")
+	}
+
+	// An unreachable cluster falls back to the cache.
+	r.err = errors.New("connection refused")
+	if _, ok, err := CachedConfigMapLayer(context.Background(), r, dir, src); !ok || err == nil {
+		t.Errorf("unreachable with cache: got (%v, %v)", ok, err)
+	}
+	if _, ok, _ := CachedConfigMapLayer(context.Background(), r, filepath.Join(t.TempDir(), "none"), src); ok {
+		t.Error("unreachable without cache should leave the source out")
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dir)); len(entries) != 1 {
+		t.Errorf("temporary cache directories left behind: %v", entries)
+	}
+}
+
+func TestResolveTemplateSearchPathSourceOrder(t *testing.T) {
+	user := t.TempDir()
+	cleanup := withTempHome(t, nil)
+	defer cleanup()
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.TemplatePath = user
+	cfg.TemplateSources = []string{"configmap:platform/team", "local", "configmap"}
+	if err := SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var asked []string
+	configMaps := func(src ConfigMapSource) (TemplateLayer, bool) {
+		asked = append(asked, src.String())
+		return TemplateLayer{Name: LayerConfigMap, Root: src.String()}, src.Name == "team"
+	}
+	var got []string
+	for _, l := range ResolveTemplateSearchPath(&cobra.Command{}, configMaps) {
+		got = append(got, l.Name)
+	}
+	if want := []string{LayerConfigMap, LayerUser, LayerBuiltin}; !equalStrings(got, want) {
+		t.Errorf("layers: got %v, want %v", got, want)
+	}
+	if want := []string{"platform/team", "kube-system/cwide-templates"}; !equalStrings(asked, want) {
+		t.Errorf("sources read: got %v, want %v", asked, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/kubectl-cwide/pkg/common"
+)
+
+// historyLogFile lists the versions kept in a template's history directory.
+const historyLogFile = "history.yaml"
+
+// TemplateVersion is one version in the history of a template.
+type TemplateVersion struct {
+	Version int       `yaml:"version"`
+	Time    time.Time `yaml:"time"`
+	// Action is what wrote the version: edit, sync, install, rollback...
+	Action string `yaml:"action"`
+	// Ext is the extension of the template, .yaml or .tpl; its content is
+	// kept as <version><ext>.
+	Ext string `yaml:"ext,omitempty"`
+	// Deleted marks the removal of the template; it has no content.
+	Deleted bool `yaml:"deleted,omitempty"`
+}
+
+// TemplateHistory is the local version history of one template of a
+// template root, kept in Dir.
+type TemplateHistory struct {
+	Dir      string            `yaml:"-"`
+	Versions []TemplateVersion `yaml:"versions"`
+}
+
+// TemplateHistoryDir returns where the history of template name in
+// directory dir of template root root is kept:
+// ~/.kubectl-cwide/history/<root>/<dir>/<name>.
+func TemplateHistoryDir(root, dir, name string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	key := strings.NewReplacer(":", "_", "/", "_", `\`, "_").Replace(root)
+	return filepath.Join(home, common.HistoryPath, key, dir, name), nil
+}
+
+// LoadTemplateHistory reads the history of a template; an empty history if
+// it has none.
+func LoadTemplateHistory(root, dir, name string) (*TemplateHistory, error) {
+	hdir, err := TemplateHistoryDir(root, dir, name)
+	if err != nil {
+		return nil, err
+	}
+	h := &TemplateHistory{Dir: hdir}
+	data, err := os.ReadFile(filepath.Join(hdir, historyLogFile))
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, h); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", filepath.Join(hdir, historyLogFile), err)
+	}
+	return h, nil
+}
+
+// Latest returns the last version, if any.
+func (h *TemplateHistory) Latest() (TemplateVersion, bool) {
+	if len(h.Versions) == 0 {
+		return TemplateVersion{}, false
+	}
+	return h.Versions[len(h.Versions)-1], true
+}
+
+// Find returns version v.
+func (h *TemplateHistory) Find(v int) (TemplateVersion, bool) {
+	for _, version := range h.Versions {
+		if version.Version == v {
+			return version, true
+		}
+	}
+	return TemplateVersion{}, false
+}
+
+// Content returns the content of version v.
+func (h *TemplateHistory) Content(v TemplateVersion) ([]byte, error) {
+	if v.Deleted {
+		return nil, fmt.Errorf("version %d is the removal of the template", v.Version)
+	}
+	return os.ReadFile(filepath.Join(h.Dir, strconv.Itoa(v.Version)+v.Ext))
+}
+
+// Add keeps data as a new version written by action. Content equal to the
+// latest version is not kept again; Add reports whether it was kept.
+func (h *TemplateHistory) Add(data []byte, ext, action string) (bool, error) {
+	if latest, ok := h.Latest(); ok && !latest.Deleted && latest.Ext == ext {
+		if prev, err := h.Content(latest); err == nil && bytes.Equal(prev, data) {
+			return false, nil
+		}
+	}
+	v := TemplateVersion{Version: h.next(), Time: time.Now().UTC().Truncate(time.Second), Action: action, Ext: ext}
+	if err := os.MkdirAll(h.Dir, 0755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(filepath.Join(h.Dir, strconv.Itoa(v.Version)+ext), data, 0644); err != nil {
+		return false, err
+	}
+	h.Versions = append(h.Versions, v)
+	return true, h.save()
+}
+
+// AddDeleted records the removal of the template by action.
+func (h *TemplateHistory) AddDeleted(action string) error {
+	h.Versions = append(h.Versions, TemplateVersion{Version: h.next(), Time: time.Now().UTC().Truncate(time.Second), Action: action, Deleted: true})
+	if err := os.MkdirAll(h.Dir, 0755); err != nil {
+		return err
+	}
+	return h.save()
+}
+
+func (h *TemplateHistory) next() int {
+	if latest, ok := h.Latest(); ok {
+		return latest.Version + 1
+	}
+	return 1
+}
+
+func (h *TemplateHistory) save() error {
+	data, err := yaml.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(h.Dir, historyLogFile), data, 0644)
+}
+
+// RecordTemplateVersion keeps the current content of template name+ext in
+// directory dir of root in its history, as written by action. previous is
+// the content the write replaced, nil if the template is new: it is kept
+// first, unless it is the latest version already, so that a template
+// written before it had a history, or changed by hand since, can be rolled
+// back to. Nothing is kept when the content did not change.
+func RecordTemplateVersion(root, dir, name, ext string, previous []byte, action string) error {
+	data, err := os.ReadFile(filepath.Join(root, dir, name+ext))
+	if err != nil {
+		return err
+	}
+	if previous != nil && bytes.Equal(previous, data) {
+		return nil
+	}
+	h, err := LoadTemplateHistory(root, dir, name)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		if _, err := h.Add(previous, ext, "before "+action); err != nil {
+			return err
+		}
+	}
+	_, err = h.Add(data, ext, action)
+	return err
+}
+
+// WriteTemplateVersioned writes data as template name+ext in directory dir
+// of root, and keeps it, and the content it replaces, in its history.
+// Failing to keep the history does not fail the write: it is returned as
+// historyErr.
+func WriteTemplateVersioned(root, dir, name, ext string, data []byte, action string) (historyErr, err error) {
+	path := filepath.Join(root, dir, name+ext)
+	previous, readErr := os.ReadFile(path)
+	if readErr != nil {
+		previous = nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, err
+	}
+	return RecordTemplateVersion(root, dir, name, ext, previous, action), nil
+}
+
+// MoveTemplateHistory moves the history of template name in directory dir
+// of root to newName in newDir, as a rename of the template does. A
+// template without history has nothing to move.
+func MoveTemplateHistory(root, dir, name, newDir, newName string) error {
+	from, err := TemplateHistoryDir(root, dir, name)
+	if err != nil {
+		return err
+	}
+	to, err := TemplateHistoryDir(root, newDir, newName)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(from); os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(to); err == nil {
+		return fmt.Errorf("%s already has a history in %s", newName, to)
+	}
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
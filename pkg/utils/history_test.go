@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTemplateVersioned(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	root := t.TempDir()
+	path := filepath.Join(root, "pod--v1", "default.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Written before it had a history.
+	if err := os.WriteFile(path, []byte("v0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{"v1", "v1", "v2"} {
+		historyErr, err := WriteTemplateVersioned(root, "pod--v1", "default", ".yaml", []byte(content), "sync")
+		if err != nil || historyErr != nil {
+			t.Fatalf("write %s: %v, %v", content, err, historyErr)
+		}
+	}
+	h, err := LoadTemplateHistory(root, "pod--v1", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ action, content string }{{"before sync", "v0"}, {"sync", "v1"}, {"sync", "v2"}}
+	if len(h.Versions) != len(want) {
+		t.Fatalf("got %d versions, want %d: %+v", len(h.Versions), len(want), h.Versions)
+	}
+	for i, w := range want {
+		v := h.Versions[i]
+		got, err := h.Content(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Version != i+1 || v.Action != w.action || string(got) != w.content {
+			t.Errorf("version %d: got %d %q %q, want %q %q", i+1, v.Version, v.Action, got, w.action, w.content)
+		}
+	}
+
+	if err := h.AddDeleted("rm"); err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveTemplateHistory(root, "pod--v1", "default", "pod--v1", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := LoadTemplateHistory(root, "pod--v1", "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest, ok := moved.Latest(); !ok || latest.Version != 4 || !latest.Deleted {
+		t.Errorf("history not moved: %+v", moved.Versions)
+	}
+	if old, _ := LoadTemplateHistory(root, "pod--v1", "default"); len(old.Versions) != 0 {
+		t.Errorf("history left behind: %+v", old.Versions)
+	}
+}
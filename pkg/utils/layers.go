@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+
+	"github.com/kubectl-cwide/templates"
+)
+
+// Template layer names, from highest to lowest precedence.
+const (
+	LayerProject = "project"
+	LayerUser    = "user"
+	LayerTeam    = "team"
+	LayerBuiltin = "builtin"
+)
+
+// ProjectTemplateDir is the template root a repository can carry, found by
+// walking up from the working directory.
+const ProjectTemplateDir = ".cwide/templates"
+
+// TemplateLayer is one template root of the search path.
+type TemplateLayer struct {
+	Name string
+	// Root is the directory of the layer; "" for the built-in layer.
+	Root string
+	FS   fs.FS
+}
+
+// DirLayer returns the layer named name rooted at directory root.
+func DirLayer(name, root string) TemplateLayer {
+	return TemplateLayer{Name: name, Root: root, FS: os.DirFS(root)}
+}
+
+// BuiltinLayer returns the layer of templates embedded in the binary.
+func BuiltinLayer() TemplateLayer {
+	return TemplateLayer{Name: LayerBuiltin, FS: templates.Native()}
+}
+
+// TemplateFile is a template found in a layer.
+type TemplateFile struct {
+	Layer TemplateLayer
+	// Dir is the <kind-group-version> directory of the template.
+	Dir  string
+	Name string
+	// Ext is ".yaml" or ".tpl".
+	Ext string
+}
+
+// Path returns where the template lives, for display.
+func (f TemplateFile) Path() string {
+	if f.Layer.Root == "" {
+		return f.Layer.Name + ":" + path.Join(f.Dir, f.Name+f.Ext)
+	}
+	return filepath.Join(f.Layer.Root, f.Dir, f.Name+f.Ext)
+}
+
+// Read returns the content of the template.
+func (f TemplateFile) Read() ([]byte, error) {
+	return fs.ReadFile(f.Layer.FS, path.Join(f.Dir, f.Name+f.Ext))
+}
+
+// TemplateSearchPath is the ordered list of template layers. A template in
+// an earlier layer shadows templates of the same name in later layers.
+type TemplateSearchPath []TemplateLayer
+
+// ConfigMapLayerFunc returns the layer of the templates in ConfigMap src,
+// or false to leave the source out.
+type ConfigMapLayerFunc func(src ConfigMapSource) (TemplateLayer, bool)
+
+// ResolveTemplateSearchPath builds the search path: the project templates
+// above the working directory, the user root (--template-path or the
+// configured templatePath) and the configured team roots, then the
+// built-in templates. Layers that do not exist are left out.
+//
+// ConfigMaps listed in templateSources are placed before or after the
+// local layers in the order they are listed, through configMaps; with a
+// nil configMaps they are left out.
+func ResolveTemplateSearchPath(cmd *cobra.Command, configMaps ConfigMapLayerFunc) TemplateSearchPath {
+	var local TemplateSearchPath
+	if wd, err := os.Getwd(); err == nil {
+		if root, ok := FindProjectTemplates(wd); ok {
+			local = append(local, DirLayer(LayerProject, root))
+		}
+	}
+	if root, err := ResolveTemplatePath(cmd); err == nil {
+		local = append(local, DirLayer(LayerUser, root))
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		return append(local, BuiltinLayer())
+	}
+	for _, root := range cfg.TeamTemplatePaths {
+		if abs, err := filepath.Abs(ExpandHome(root)); err == nil {
+			local = append(local, DirLayer(LayerTeam, abs))
+		}
+	}
+
+	var layers TemplateSearchPath
+	placed := false
+	for _, entry := range cfg.TemplateSources {
+		isLocal, src, err := ParseTemplateSource(entry)
+		switch {
+		case err != nil:
+			continue
+		case isLocal:
+			if !placed {
+				layers = append(layers, local...)
+				placed = true
+			}
+		case configMaps != nil:
+			if layer, ok := configMaps(src); ok {
+				layers = append(layers, layer)
+			}
+		}
+	}
+	if !placed {
+		layers = append(layers, local...)
+	}
+	return append(layers, BuiltinLayer())
+}
+
+// FindProjectTemplates walks up from dir to the first directory holding
+// .cwide/templates.
+func FindProjectTemplates(dir string) (string, bool) {
+	for {
+		root := filepath.Join(dir, ProjectTemplateDir)
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			return root, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// ExpandHome replaces a leading ~ in p with the home directory.
+func ExpandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, p[1:])
+}
+
+// Find returns the template named name in directory dir of the first layer
+// that has it, preferring .yaml over .tpl within a layer.
+func (s TemplateSearchPath) Find(dir, name string) (TemplateFile, bool) {
+	for _, layer := range s {
+		for _, ext := range []string{".yaml", ".tpl"} {
+			f := TemplateFile{Layer: layer, Dir: dir, Name: name, Ext: ext}
+			if _, err := fs.Stat(layer.FS, path.Join(dir, name+ext)); err == nil {
+				return f, true
+			}
+		}
+	}
+	return TemplateFile{}, false
+}
+
+// Resolve finds the template named name for kind gvk. A template for the
+// exact version wins, in any layer; then the version-less kind-group
+// directory; then the template of the most stable other version of the
+// kind. Compare the Dir of the result with GenerateDirNameByGVK to tell
+// whether it is for another version.
+func (s TemplateSearchPath) Resolve(gvk schema.GroupVersionKind, name string) (TemplateFile, bool) {
+	if f, ok := s.Find(GenerateDirNameByGVK(gvk), name); ok {
+		return f, true
+	}
+	if f, ok := s.Find(GenerateVersionlessDirName(gvk.GroupKind()), name); ok {
+		return f, true
+	}
+	files, err := s.Glob(GenerateVersionlessDirName(gvk.GroupKind()) + "-*")
+	if err != nil {
+		return TemplateFile{}, false
+	}
+	var best TemplateFile
+	found := false
+	for _, f := range files {
+		dirGVK, ok := ParseTemplateDirName(f.Dir)
+		if !ok || f.Name != name || dirGVK.Version == "" || !strings.EqualFold(dirGVK.Group, gvk.Group) || !strings.EqualFold(dirGVK.Kind, gvk.Kind) {
+			continue
+		}
+		// Copies of one directory come in layer order, so the first is kept.
+		if !found || version.CompareKubeAwareVersionStrings(dirGVK.Version, best.Version()) > 0 {
+			best, found = f, true
+		}
+	}
+	return best, found
+}
+
+// Version returns the API version of the template's directory; "" for a
+// version-less directory.
+func (f TemplateFile) Version() string {
+	gvk, _ := ParseTemplateDirName(f.Dir)
+	return gvk.Version
+}
+
+// Glob returns every template, in every layer, whose directory matches
+// dirPattern (see path.Match). Templates are ordered by directory and name,
+// and copies of one template by layer, so the first copy is the one in
+// effect. Within a layer a .yaml template hides a .tpl of the same name.
+func (s TemplateSearchPath) Glob(dirPattern string) ([]TemplateFile, error) {
+	var files []TemplateFile
+	for _, layer := range s {
+		seen := map[string]bool{}
+		for _, ext := range []string{".yaml", ".tpl"} {
+			matches, err := fs.Glob(layer.FS, dirPattern+"/*"+ext)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range matches {
+				dir, file := path.Split(m)
+				f := TemplateFile{Layer: layer, Dir: path.Clean(dir), Name: strings.TrimSuffix(file, ext), Ext: ext}
+				if seen[f.Dir+"/"+f.Name] {
+					continue
+				}
+				seen[f.Dir+"/"+f.Name] = true
+				files = append(files, f)
+			}
+		}
+	}
+	// files are in layer order; a stable sort keeps copies in that order.
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Dir != files[j].Dir {
+			return files[i].Dir < files[j].Dir
+		}
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// SharedHelpers concatenates the _shared/*.tpl helper files of every layer.
+// A helper file in an earlier layer shadows the file of the same name in
+// later layers.
+func (s TemplateSearchPath) SharedHelpers() string {
+	files := map[string][]byte{}
+	var names []string
+	for _, layer := range s {
+		matches, err := fs.Glob(layer.FS, "_shared/*.tpl")
+		if err != nil {
+			continue
+		}
+		for _, m := range matches {
+			name := path.Base(m)
+			if _, ok := files[name]; ok {
+				continue
+			}
+			data, err := fs.ReadFile(layer.FS, m)
+			if err != nil {
+				continue
+			}
+			files[name] = data
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.Write(files[name])
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func writeTemplate(t *testing.T, root, rel, body string) {
+	t.Helper()
+	p := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindProjectTemplates(t *testing.T) {
+	repo := t.TempDir()
+	writeTemplate(t, repo, ".cwide/templates/pod--v1/default.yaml", "columns: []\n")
+	deep := filepath.Join(repo, "src", "app")
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	root, ok := FindProjectTemplates(deep)
+	if !ok || root != filepath.Join(repo, ProjectTemplateDir) {
+		t.Fatalf("got (%q, %v), want %q", root, ok, filepath.Join(repo, ProjectTemplateDir))
+	}
+	if _, ok := FindProjectTemplates(t.TempDir()); ok {
+		t.Error("found project templates outside a project")
+	}
+}
+
+func TestTemplateSearchPathShadowing(t *testing.T) {
+	project, user := t.TempDir(), t.TempDir()
+	writeTemplate(t, project, "pod--v1/default.tpl", "NAME\n{{ .metadata.name }}\n")
+	writeTemplate(t, user, "pod--v1/default.yaml", "columns: []\n")
+	writeTemplate(t, user, "pod--v1/mine.yaml", "columns: []\n")
+	writeTemplate(t, project, "_shared/fmt.tpl", `{{ define "a" }}project{{ end }}`)
+	writeTemplate(t, user, "_shared/fmt.tpl", `{{ define "a" }}user{{ end }}`)
+	writeTemplate(t, user, "_shared/more.tpl", `{{ define "b" }}user{{ end }}`)
+	layers := TemplateSearchPath{DirLayer(LayerProject, project), DirLayer(LayerUser, user), BuiltinLayer()}
+
+	f, ok := layers.Find("pod--v1", "default")
+	if !ok || f.Layer.Name != LayerProject || f.Ext != ".tpl" {
+		t.Errorf("default: got %+v, want the project .tpl", f)
+	}
+	if f, ok := layers.Find("pod--v1", "images"); !ok || f.Layer.Name != LayerBuiltin {
+		t.Errorf("images: got %+v, want the built-in copy", f)
+	}
+	if _, ok := layers.Find("pod--v1", "missing"); ok {
+		t.Error("found a template no layer has")
+	}
+
+	files, err := layers.Glob("pod-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		if f.Name == "default" || f.Name == "mine" {
+			got = append(got, f.Name+"@"+f.Layer.Name)
+		}
+	}
+	want := "default@project default@user default@builtin mine@user"
+	if strings.Join(got, " ") != want {
+		t.Errorf("glob: got %v, want %s", got, want)
+	}
+
+	if got, want := layers.SharedHelpers(), `{{ define "a" }}project{{ end }}`+"\n"+`{{ define "b" }}user{{ end }}`+"\n"; got != want {
+		t.Errorf("shared helpers: got %q, want %q", got, want)
+	}
+}
+
+func TestParseTemplateDirName(t *testing.T) {
+	cases := map[string]schema.GroupVersionKind{
+		"pod--v1":         {Kind: "pod", Version: "v1"},
+		"pod-":            {Kind: "pod"},
+		"deployment-apps": {Kind: "deployment", Group: "apps"},
+		"horizontalpodautoscaler-autoscaling-v2beta2": {Kind: "horizontalpodautoscaler", Group: "autoscaling", Version: "v2beta2"},
+		"certificate-cert-manager.io-v1":              {Kind: "certificate", Group: "cert-manager.io", Version: "v1"},
+		"certificate-cert-manager.io":                 {Kind: "certificate", Group: "cert-manager.io"},
+	}
+	for dir, want := range cases {
+		got, ok := ParseTemplateDirName(dir)
+		if !ok || got != want {
+			t.Errorf("%s: got (%v, %v), want %v", dir, got, ok, want)
+		}
+	}
+	if _, ok := ParseTemplateDirName("_shared"); ok {
+		t.Error("_shared is not a template directory")
+	}
+}
+
+func TestTemplateSearchPathResolveVersions(t *testing.T) {
+	user := t.TempDir()
+	writeTemplate(t, user, "widget-example.com-v1alpha1/default.yaml", "columns: []\n")
+	writeTemplate(t, user, "widget-example.com-v1beta1/default.yaml", "columns: []\n")
+	writeTemplate(t, user, "widget-example.com-v1beta1/wide.yaml", "columns: []\n")
+	writeTemplate(t, user, "widget-example.com/wide.yaml", "columns: []\n")
+	writeTemplate(t, user, "widget-example.com-v2/compact.yaml", "columns: []\n")
+	layers := TemplateSearchPath{DirLayer(LayerUser, user)}
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v2", Kind: "Widget"}
+
+	cases := map[string]string{
+		"compact": "widget-example.com-v2",
+		"wide":    "widget-example.com",
+		"default": "widget-example.com-v1beta1",
+	}
+	for name, dir := range cases {
+		f, ok := layers.Resolve(gvk, name)
+		if !ok || f.Dir != dir {
+			t.Errorf("%s: got (%q, %v), want %q", name, f.Dir, ok, dir)
+		}
+	}
+	if _, ok := layers.Resolve(gvk, "missing"); ok {
+		t.Error("resolved a template no version has")
+	}
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// Same layout as printers.GetNewTabWriter.
+const (
+	tabwriterMinWidth = 6
+	tabwriterPadding  = 3
+)
+
+// TabWriter aligns tab-separated cells the way printers.GetNewTabWriter
+// does (minimum width 6, padding 3, widths remembered across Flush), but
+// measures each cell by its display width: ANSI escape sequences such as
+// those emitted by colorIf take no space, and East Asian wide characters
+// (CJK, most emoji) take two columns. The liggitt tabwriter counts runes,
+// so one colored or wide cell shifts every column after it.
+//
+// Cells are separated by '\t' and rows by '\n'; the last cell of a row is
+// written as is. Output is buffered until Flush.
+type TabWriter struct {
+	out    io.Writer
+	buf    bytes.Buffer
+	widths []int
+}
+
+// NewTabWriter returns a TabWriter writing to out.
+func NewTabWriter(out io.Writer) *TabWriter {
+	return &TabWriter{out: out}
+}
+
+// Write buffers p until the next Flush.
+func (w *TabWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// Flush aligns and writes everything buffered since the last Flush. Column
+// widths never shrink between flushes, so rows printed later (e.g. watch
+// events) stay aligned with earlier ones.
+func (w *TabWriter) Flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	data := w.buf.String()
+	w.buf.Reset()
+
+	lines := strings.Split(data, "\n")
+	rows := make([][]string, len(lines))
+	for i, line := range lines {
+		rows[i] = strings.Split(line, "\t")
+		for col, cell := range rows[i][:len(rows[i])-1] {
+			width := DisplayWidth(cell) + tabwriterPadding
+			if width < tabwriterMinWidth {
+				width = tabwriterMinWidth
+			}
+			if col == len(w.widths) {
+				w.widths = append(w.widths, width)
+			} else if width > w.widths[col] {
+				w.widths[col] = width
+			}
+		}
+	}
+
+	var out strings.Builder
+	for i, cells := range rows {
+		for col, cell := range cells {
+			out.WriteString(cell)
+			if col < len(cells)-1 {
+				out.WriteString(strings.Repeat(" ", w.widths[col]-DisplayWidth(cell)))
+			}
+		}
+		if i < len(rows)-1 {
+			out.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w.out, out.String())
+	return err
+}
+
+// DisplayWidth returns the number of terminal columns s occupies, ignoring
+// ANSI escape sequences and counting East Asian wide characters as two.
+func DisplayWidth(s string) int {
+	return text.StringWidthWithoutEscSequences(s)
+}
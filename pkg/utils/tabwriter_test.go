@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kubectl-cwide/pkg/utils/golden"
+)
+
+const (
+	green = "\x1b[32m"
+	red   = "\x1b[31m"
+	reset = "\x1b[0m"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	cases := map[string]int{
+		"Running":                 7,
+		green + "Running" + reset: 7,
+		"数据库":                     6,
+		"✅ ok":                    5,
+		red + "失败" + reset:        4,
+	}
+	for in, want := range cases {
+		if got := DisplayWidth(in); got != want {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestTabWriterColoredAndWide(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewTabWriter(&buf)
+	fmt.Fprintln(w, "NAME\tSTATUS\tOWNER\tAGE")
+	fmt.Fprintln(w, "web\t"+green+"Running"+reset+"\tplatform\t3d")
+	fmt.Fprintln(w, "数据库-0\t"+red+"CrashLoopBackOff"+reset+"\t数据团队\t5m")
+	fmt.Fprintln(w, "cache\t✅ Ready\tsre\t20h")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, "tabwriter_colored_wide", buf.Bytes())
+
+	// Every row's AGE column must start at the same display offset.
+	var offset int
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		idx := strings.LastIndex(line, " ") + 1
+		if o := DisplayWidth(line[:idx]); i == 0 {
+			offset = o
+		} else if o != offset {
+			t.Errorf("row %d: AGE starts at %d, header at %d", i, o, offset)
+		}
+	}
+}
+
+func TestTabWriterRemembersWidths(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewTabWriter(&buf)
+	fmt.Fprintln(w, "NAME\tSTATUS")
+	fmt.Fprintln(w, "a-much-longer-name\tRunning")
+	w.Flush()
+	fmt.Fprintln(w, "b\t"+red+"Failed"+reset)
+	w.Flush()
+	golden.Assert(t, "tabwriter_remember_widths", buf.Bytes())
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/kubectl-cwide/pkg/common"
@@ -192,6 +193,30 @@ func GenerateDirNameByGVK(gvk schema.GroupVersionKind) string {
 	return strings.ToLower(fmt.Sprintf("%s-%s-%s", gvk.Kind, gvk.Group, gvk.Version))
 }
 
+// GenerateVersionlessDirName returns the template directory name shared by
+// every version of a kind: lower("kind-group").
+func GenerateVersionlessDirName(gk schema.GroupKind) string {
+	return strings.ToLower(fmt.Sprintf("%s-%s", gk.Kind, gk.Group))
+}
+
+// kubeVersion matches API versions such as v1, v2beta2 or v1alpha1.
+var kubeVersion = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// ParseTemplateDirName splits a template directory name into its lowercase
+// kind, group and version. The version is empty for a version-less
+// directory (see GenerateVersionlessDirName).
+func ParseTemplateDirName(dir string) (schema.GroupVersionKind, bool) {
+	kind, rest, ok := strings.Cut(dir, "-")
+	if !ok || kind == "" {
+		return schema.GroupVersionKind{}, false
+	}
+	gvk := schema.GroupVersionKind{Kind: kind, Group: rest}
+	if i := strings.LastIndex(rest, "-"); i >= 0 && kubeVersion.MatchString(rest[i+1:]) {
+		gvk.Group, gvk.Version = rest[:i], rest[i+1:]
+	}
+	return gvk, true
+}
+
 // BuildYAMLColumnTemplate generates a YAML template from CRD AdditionalPrinterColumns.
 func BuildYAMLColumnTemplate(columns []v1.CustomResourceColumnDefinition) ([]byte, error) {
 	tmpl := models.YAMLTemplate{
@@ -202,6 +227,15 @@ func BuildYAMLColumnTemplate(columns []v1.CustomResourceColumnDefinition) ([]byt
 			Header:    strings.ToUpper(col.Name),
 			FieldSpec: col.JSONPath,
 		}
+		// kubectl renders "date" printer columns as an age.
+		if col.Type == "date" {
+			tmpl.Columns[i].Format = "age"
+		}
+		// Keep the declared type for structured output; strings are the
+		// default anyway.
+		if col.Type != "string" {
+			tmpl.Columns[i].Type = col.Type
+		}
 	}
 	return yaml.Marshal(&tmpl)
 }
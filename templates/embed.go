@@ -0,0 +1,21 @@
+// Package templates embeds the curated native templates, which form the
+// built-in layer of the template search path.
+package templates
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed native/*/*.yaml
+var native embed.FS
+
+// Native returns the built-in templates, laid out like a template root
+// (<kind-group-version>/<name>.yaml).
+func Native() fs.FS {
+	sub, err := fs.Sub(native, "native")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}